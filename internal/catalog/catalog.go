@@ -0,0 +1,208 @@
+// Package catalog describes database schemas at the level of tables, columns,
+// indexes and constraints. A catalog is built either from the Storm IR, as the
+// DDL generator would create it, or by introspecting a live database, so the
+// two can be compared.
+package catalog
+
+import (
+	"github.com/pixperk/storm/internal/generator/ddl"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// Catalog is the set of tables of a database
+type Catalog struct {
+	Tables []*Table
+	// Defaults is set when the columns carry their DEFAULT expressions.
+	// Introspected catalogs leave them out, as databases rewrite them.
+	Defaults bool
+}
+
+// Table is a table with its columns and constraints
+type Table struct {
+	Name        string
+	Columns     []*Column
+	PrimaryKey  []string
+	Indexes     []*Index
+	ForeignKeys []*ForeignKey
+}
+
+// Column is a table column. Type is normalized with NormalizeType.
+type Column struct {
+	Name          string
+	Type          string
+	Nullable      bool
+	Unique        bool // Single-column unique constraint
+	AutoIncrement bool
+	Default       string // DEFAULT expression, when the catalog has Defaults
+	OnUpdate      bool   // Set to CURRENT_TIMESTAMP on each update, by MySQL ON UPDATE
+}
+
+// Index is a secondary index; single-column unique indexes are Column.Unique
+type Index struct {
+	Name       string
+	Columns    []string
+	Unique     bool
+	Definition string // CREATE INDEX statement with all options, when built from the IR
+}
+
+// ForeignKey is a FOREIGN KEY constraint
+type ForeignKey struct {
+	Name       string // Constraint name, when introspected
+	Columns    []string
+	References string // Referenced table
+	Referenced []string
+	OnDelete   string // ON DELETE action, empty for the default
+}
+
+// Table looks up a table by name
+func (c *Catalog) Table(name string) (*Table, bool) {
+	for _, t := range c.Tables {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Column looks up a column by name
+func (t *Table) Column(name string) (*Column, bool) {
+	for _, col := range t.Columns {
+		if col.Name == name {
+			return col, true
+		}
+	}
+	return nil, false
+}
+
+// Index looks up an index by name
+func (t *Table) Index(name string) (*Index, bool) {
+	for _, idx := range t.Indexes {
+		if idx.Name == name {
+			return idx, true
+		}
+	}
+	return nil, false
+}
+
+// FromIR returns the catalog the DDL generator creates for the IR in dialect d
+func FromIR(irData *ir.IR, d dialect.Dialect) *Catalog {
+	c := &Catalog{Defaults: true}
+	for _, model := range irData.Models {
+		c.Tables = append(c.Tables, modelTable(irData, model, d))
+	}
+
+	seen := make(map[string]bool)
+	for _, model := range irData.Models {
+		for _, f := range model.Fields {
+			jt, ok := irData.JoinTable(model, f)
+			if !ok || jt.Through || seen[jt.Name] {
+				continue
+			}
+			seen[jt.Name] = true
+			c.Tables = append(c.Tables, joinTable(jt, d))
+		}
+	}
+	return c
+}
+
+// modelTable describes the table of a model
+func modelTable(irData *ir.IR, model ir.IRModel, d dialect.Dialect) *Table {
+	t := &Table{Name: model.Name}
+	for _, f := range model.Fields {
+		if irData.IsRelation(f) {
+			continue
+		}
+		col := fieldColumn(f, d)
+		t.Columns = append(t.Columns, col)
+		if f.Type.HasDirective(directive.DirID) {
+			t.PrimaryKey = append(t.PrimaryKey, col.Name)
+		}
+		if spec, ok := f.Index(); ok {
+			t.Indexes = append(t.Indexes, &Index{
+				Name:       spec.IndexName(model.Name),
+				Columns:    []string{spec.Column},
+				Definition: ddl.CreateIndex(model, spec, d),
+			})
+		}
+	}
+
+	for _, f := range model.Fields {
+		if !f.Type.HasDirective(directive.DirBelongsTo) || !irData.IsRelation(f) {
+			continue
+		}
+		target, _ := irData.FindModel(f.Type.ModelName)
+		fk := &ForeignKey{Columns: []string{model.ForeignKeyColumn(f)}, References: target.Name, Referenced: []string{"id"}}
+
+		keyType := *fld.NewFieldType(fld.KindInt, "", nil)
+		referenced, ok := target.PrimaryKey()
+		if ref := ir.ForeignKeyReference(f); ref != "" {
+			referenced, ok = target.FindField(ref)
+		}
+		if ok {
+			fk.Referenced = []string{referenced.ColumnName()}
+			keyType = *fld.NewFieldType(referenced.Type.Kind, "", nil)
+		}
+
+		if _, declared := model.FindField(ir.ForeignKeyName(f)); !declared {
+			// Implicit key column
+			t.Columns = append(t.Columns, &Column{
+				Name:     fk.Columns[0],
+				Type:     NormalizeType(d, keyType.SQLType(d)),
+				Nullable: f.IsNullable(),
+			})
+		}
+		t.ForeignKeys = append(t.ForeignKeys, fk)
+	}
+	return t
+}
+
+// fieldColumn describes the column of a scalar field
+func fieldColumn(f ir.IRField, d dialect.Dialect) *Column {
+	isID := f.Type.HasDirective(directive.DirID)
+	col := &Column{
+		Name:          f.ColumnName(),
+		Nullable:      f.IsNullable() && !isID,
+		Unique:        f.Type.HasDirective(directive.DirUnique) && !isID,
+		AutoIncrement: isID && f.Type.HasDirective(directive.DirAuto),
+	}
+	if !col.AutoIncrement {
+		col.Default = ddl.DefaultClause(f, d)
+	}
+	col.OnUpdate = d == dialect.MySQL && f.Type.HasDirective(directive.DirUpdatedAt)
+
+	switch {
+	case col.AutoIncrement && d == dialect.Postgres && f.Type.Kind == fld.KindBigInt:
+		col.Type = "BIGINT"
+	case col.AutoIncrement && (d == dialect.Postgres || d == dialect.SQLite):
+		col.Type = "INTEGER"
+	case f.IsArray && d == dialect.Postgres:
+		col.Type = f.Type.PostgresType() + "[]"
+	case f.IsArray && d == dialect.MySQL:
+		col.Type = "JSON"
+	case f.IsArray:
+		col.Type = "TEXT"
+	default:
+		col.Type = f.Type.SQLType(d)
+	}
+	col.Type = NormalizeType(d, col.Type)
+	return col
+}
+
+// joinTable describes an implicit many-to-many join table
+func joinTable(jt *ir.JoinTable, d dialect.Dialect) *Table {
+	cols := []ir.JoinColumn{jt.Source, jt.Target}
+	if cols[0].Name > cols[1].Name {
+		cols[0], cols[1] = cols[1], cols[0]
+	}
+
+	t := &Table{Name: jt.Name}
+	for _, col := range cols {
+		t.Columns = append(t.Columns, &Column{Name: col.Name, Type: NormalizeType(d, col.Type.SQLType(d))})
+		t.PrimaryKey = append(t.PrimaryKey, col.Name)
+		t.ForeignKeys = append(t.ForeignKeys, &ForeignKey{Columns: []string{col.Name}, References: col.Table, Referenced: []string{col.References}, OnDelete: "CASCADE"})
+	}
+	return t
+}
@@ -0,0 +1,243 @@
+package catalog
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ChangeKind identifies what a Change does
+type ChangeKind int
+
+const (
+	AddTable ChangeKind = iota
+	DropTable
+	AddColumn
+	DropColumn
+	AlterColumn
+	AlterPrimaryKey
+	AddIndex
+	DropIndex
+	AddForeignKey
+	DropForeignKey
+	RenameTable
+	RenameColumn
+)
+
+// Change is one step turning a catalog into another
+type Change struct {
+	Kind        ChangeKind
+	Table       *Table      // Table changed; the old definition for DropTable
+	Column      *Column     // Column added, dropped or altered (new definition)
+	Previous    *Column     // Definition of an altered column before the change
+	PreviousKey []string    // Primary key before AlterPrimaryKey
+	Index       *Index      // Index added or dropped
+	ForeignKey  *ForeignKey // Foreign key added or dropped
+	OldName     string      // Name of a renamed table or column before RenameTable or RenameColumn
+}
+
+// Diff returns the changes turning catalog from into catalog to. Tables and
+// columns are matched by name and column order is not compared, nor are
+// defaults unless both catalogs have them.
+func Diff(from, to *Catalog) []Change {
+	defaults := from.Defaults && to.Defaults
+	var changes []Change
+
+	for _, t := range to.Tables {
+		old, ok := from.Table(t.Name)
+		if !ok {
+			changes = append(changes, Change{Kind: AddTable, Table: t})
+			continue
+		}
+		changes = append(changes, diffTable(old, t, defaults)...)
+	}
+	for _, t := range from.Tables {
+		if _, ok := to.Table(t.Name); !ok {
+			changes = append(changes, Change{Kind: DropTable, Table: t})
+		}
+	}
+	return changes
+}
+
+// diffTable returns the changes turning table from into table to
+func diffTable(from, to *Table, defaults bool) []Change {
+	var changes []Change
+
+	for _, col := range to.Columns {
+		old, ok := from.Column(col.Name)
+		switch {
+		case !ok:
+			changes = append(changes, Change{Kind: AddColumn, Table: to, Column: col})
+		case !old.equal(col, defaults):
+			previous := *old
+			if !defaults {
+				previous.Default = col.Default
+			}
+			changes = append(changes, Change{Kind: AlterColumn, Table: to, Column: col, Previous: &previous})
+		}
+	}
+	for _, col := range from.Columns {
+		if _, ok := to.Column(col.Name); !ok {
+			changes = append(changes, Change{Kind: DropColumn, Table: to, Column: col})
+		}
+	}
+
+	if !slices.Equal(from.PrimaryKey, to.PrimaryKey) {
+		changes = append(changes, Change{Kind: AlterPrimaryKey, Table: to, PreviousKey: from.PrimaryKey})
+	}
+
+	for _, idx := range from.Indexes {
+		if other, ok := to.Index(idx.Name); !ok || !idx.equal(other) {
+			changes = append(changes, Change{Kind: DropIndex, Table: to, Index: idx})
+		}
+	}
+	for _, idx := range to.Indexes {
+		if other, ok := from.Index(idx.Name); !ok || !idx.equal(other) {
+			changes = append(changes, Change{Kind: AddIndex, Table: to, Index: idx})
+		}
+	}
+
+	for _, fk := range from.ForeignKeys {
+		if !containsForeignKey(to.ForeignKeys, fk) {
+			changes = append(changes, Change{Kind: DropForeignKey, Table: to, ForeignKey: fk})
+		}
+	}
+	for _, fk := range to.ForeignKeys {
+		if !containsForeignKey(from.ForeignKeys, fk) {
+			changes = append(changes, Change{Kind: AddForeignKey, Table: to, ForeignKey: fk})
+		}
+	}
+	return changes
+}
+
+func (c *Column) equal(other *Column, defaults bool) bool {
+	a, b := *c, *other
+	if !defaults {
+		a.Default, b.Default = "", ""
+	}
+	return a == b
+}
+
+func (i *Index) equal(other *Index) bool {
+	return i.Unique == other.Unique && slices.Equal(i.Columns, other.Columns)
+}
+
+func (fk *ForeignKey) equal(other *ForeignKey) bool {
+	return fk.References == other.References &&
+		slices.Equal(fk.Columns, other.Columns) &&
+		slices.Equal(fk.Referenced, other.Referenced)
+}
+
+func containsForeignKey(fks []*ForeignKey, fk *ForeignKey) bool {
+	for _, other := range fks {
+		if fk.equal(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the change, e.g. "add column User.age INTEGER NOT NULL"
+func (c Change) String() string {
+	switch c.Kind {
+	case AddTable:
+		return "add table " + c.Table.Name
+	case DropTable:
+		return "drop table " + c.Table.Name
+	case AddColumn:
+		return fmt.Sprintf("add column %s.%s %s", c.Table.Name, c.Column.Name, c.Column)
+	case DropColumn:
+		return fmt.Sprintf("drop column %s.%s", c.Table.Name, c.Column.Name)
+	case AlterColumn:
+		return fmt.Sprintf("alter column %s.%s: %s", c.Table.Name, c.Column.Name, strings.Join(columnChanges(c.Previous, c.Column), ", "))
+	case AlterPrimaryKey:
+		return fmt.Sprintf("change primary key of %s from (%s) to (%s)", c.Table.Name, strings.Join(c.PreviousKey, ", "), strings.Join(c.Table.PrimaryKey, ", "))
+	case AddIndex:
+		return fmt.Sprintf("add %s %s on %s (%s)", c.Index.kind(), c.Index.Name, c.Table.Name, strings.Join(c.Index.Columns, ", "))
+	case DropIndex:
+		return fmt.Sprintf("drop %s %s on %s", c.Index.kind(), c.Index.Name, c.Table.Name)
+	case AddForeignKey:
+		return fmt.Sprintf("add foreign key %s", c.ForeignKey.describe(c.Table.Name))
+	case DropForeignKey:
+		return fmt.Sprintf("drop foreign key %s", c.ForeignKey.describe(c.Table.Name))
+	case RenameTable, RenameColumn:
+		return c.describeRename()
+	}
+	return "unknown change"
+}
+
+// String renders the type and constraints of a column, e.g. "VARCHAR(255) NOT NULL UNIQUE"
+func (c *Column) String() string {
+	parts := []string{c.Type}
+	if !c.Nullable {
+		parts = append(parts, "NOT NULL")
+	}
+	if c.Unique {
+		parts = append(parts, "UNIQUE")
+	}
+	if c.AutoIncrement {
+		parts = append(parts, "AUTOINCREMENT")
+	}
+	if c.Default != "" {
+		parts = append(parts, "DEFAULT "+c.Default)
+	}
+	return strings.Join(parts, " ")
+}
+
+// columnChanges lists what differs between two definitions of a column
+func columnChanges(from, to *Column) []string {
+	var changes []string
+	if from.Type != to.Type {
+		changes = append(changes, fmt.Sprintf("type %s -> %s", from.Type, to.Type))
+	}
+	if from.Nullable != to.Nullable {
+		if to.Nullable {
+			changes = append(changes, "drop NOT NULL")
+		} else {
+			changes = append(changes, "set NOT NULL")
+		}
+	}
+	if from.Unique != to.Unique {
+		if to.Unique {
+			changes = append(changes, "add UNIQUE")
+		} else {
+			changes = append(changes, "drop UNIQUE")
+		}
+	}
+	if from.Default != to.Default {
+		switch {
+		case to.Default == "":
+			changes = append(changes, "drop DEFAULT")
+		case from.Default == "":
+			changes = append(changes, "set DEFAULT "+to.Default)
+		default:
+			changes = append(changes, fmt.Sprintf("DEFAULT %s -> %s", from.Default, to.Default))
+		}
+	}
+	if from.AutoIncrement != to.AutoIncrement {
+		if to.AutoIncrement {
+			changes = append(changes, "add AUTOINCREMENT")
+		} else {
+			changes = append(changes, "drop AUTOINCREMENT")
+		}
+	}
+	if from.OnUpdate != to.OnUpdate {
+		if to.OnUpdate {
+			changes = append(changes, "add ON UPDATE CURRENT_TIMESTAMP")
+		} else {
+			changes = append(changes, "drop ON UPDATE CURRENT_TIMESTAMP")
+		}
+	}
+	return changes
+}
+
+func (i *Index) kind() string {
+	if i.Unique {
+		return "unique index"
+	}
+	return "index"
+}
+
+func (fk *ForeignKey) describe(table string) string {
+	return fmt.Sprintf("%s (%s) -> %s (%s)", table, strings.Join(fk.Columns, ", "), fk.References, strings.Join(fk.Referenced, ", "))
+}
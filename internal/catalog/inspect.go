@@ -0,0 +1,191 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// Inspect reads the catalog of the database db speaks dialect d
+func Inspect(ctx context.Context, db *sql.DB, d dialect.Dialect) (*Catalog, error) {
+	var inspect func(context.Context, *sql.DB) (*Catalog, error)
+	switch d {
+	case dialect.SQLite:
+		inspect = inspectSQLite
+	case dialect.Postgres:
+		inspect = inspectPostgres
+	case dialect.MySQL:
+		inspect = inspectMySQL
+	default:
+		return nil, fmt.Errorf("unsupported database dialect: %s", d)
+	}
+	return inspect(ctx, db)
+}
+
+// addIndex records an index on the table. Single-column unique indexes are
+// unique columns, as the DDL generator writes them.
+func (t *Table) addIndex(name string, columns []string, unique bool) {
+	if unique && len(columns) == 1 {
+		if col, ok := t.Column(columns[0]); ok {
+			col.Unique = true
+			return
+		}
+	}
+	t.Indexes = append(t.Indexes, &Index{Name: name, Columns: columns, Unique: unique})
+}
+
+// queryStrings runs a query returning a single text column
+func queryStrings(ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+func inspectSQLite(ctx context.Context, db *sql.DB) (*Catalog, error) {
+	names, err := queryStrings(ctx, db,
+		`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite\_%' ESCAPE '\' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{}
+	for _, name := range names {
+		t, err := inspectSQLiteTable(ctx, db, name)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", name, err)
+		}
+		c.Tables = append(c.Tables, t)
+	}
+	return c, nil
+}
+
+func inspectSQLiteTable(ctx context.Context, db *sql.DB, name string) (*Table, error) {
+	t := &Table{Name: name}
+
+	var create string
+	if err := db.QueryRowContext(ctx, `SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&create); err != nil {
+		return nil, err
+	}
+	// Only INTEGER PRIMARY KEY columns can be AUTOINCREMENT, so the keyword marks the key
+	autoIncrement := strings.Contains(strings.ToUpper(create), "AUTOINCREMENT")
+
+	rows, err := db.QueryContext(ctx, `SELECT name, type, "notnull", pk FROM pragma_table_info(?) ORDER BY cid`, name)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[int]string)
+	for rows.Next() {
+		var col Column
+		var notNull bool
+		var pk int
+		if err := rows.Scan(&col.Name, &col.Type, &notNull, &pk); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		col.Type = NormalizeType(dialect.SQLite, col.Type)
+		col.Nullable = !notNull && pk == 0
+		col.AutoIncrement = pk > 0 && autoIncrement
+		if pk > 0 {
+			keys[pk] = col.Name
+		}
+		t.Columns = append(t.Columns, &col)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := 1; i <= len(keys); i++ {
+		t.PrimaryKey = append(t.PrimaryKey, keys[i])
+	}
+
+	// Indexes backing the primary key belong to it
+	indexes, err := db.QueryContext(ctx, `SELECT name, "unique" FROM pragma_index_list(?) WHERE origin <> 'pk' ORDER BY name`, name)
+	if err != nil {
+		return nil, err
+	}
+	type sqliteIndex struct {
+		name   string
+		unique bool
+	}
+	var list []sqliteIndex
+	for indexes.Next() {
+		var idx sqliteIndex
+		if err := indexes.Scan(&idx.name, &idx.unique); err != nil {
+			indexes.Close()
+			return nil, err
+		}
+		list = append(list, idx)
+	}
+	indexes.Close()
+	if err := indexes.Err(); err != nil {
+		return nil, err
+	}
+	for _, idx := range list {
+		columns, err := queryStrings(ctx, db, `SELECT name FROM pragma_index_info(?) ORDER BY seqno`, idx.name)
+		if err != nil {
+			return nil, err
+		}
+		t.addIndex(idx.name, columns, idx.unique)
+	}
+
+	fks, err := db.QueryContext(ctx, `SELECT id, "table", "from", "to" FROM pragma_foreign_key_list(?) ORDER BY id, seq`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer fks.Close()
+	byID := make(map[int]*ForeignKey)
+	for fks.Next() {
+		var id int
+		var table, from string
+		var to sql.NullString
+		if err := fks.Scan(&id, &table, &from, &to); err != nil {
+			return nil, err
+		}
+		fk, ok := byID[id]
+		if !ok {
+			fk = &ForeignKey{References: table}
+			byID[id] = fk
+			t.ForeignKeys = append(t.ForeignKeys, fk)
+		}
+		fk.Columns = append(fk.Columns, from)
+		// A missing target column means the referenced table's primary key
+		fk.Referenced = append(fk.Referenced, to.String)
+	}
+	if err := fks.Err(); err != nil {
+		return nil, err
+	}
+	for _, fk := range t.ForeignKeys {
+		if allEmpty(fk.Referenced) {
+			fk.Referenced, err = queryStrings(ctx, db, `SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk`, fk.References)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t, nil
+}
+
+// allEmpty reports whether every element of the slice is empty
+func allEmpty(values []string) bool {
+	for _, v := range values {
+		if v != "" {
+			return false
+		}
+	}
+	return true
+}
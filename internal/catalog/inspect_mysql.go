@@ -0,0 +1,136 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+func inspectMySQL(ctx context.Context, db *sql.DB) (*Catalog, error) {
+	names, err := queryStrings(ctx, db, `SELECT TABLE_NAME FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME`)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{}
+	for _, name := range names {
+		t, err := inspectMySQLTable(ctx, db, name)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", name, err)
+		}
+		c.Tables = append(c.Tables, t)
+	}
+	return c, nil
+}
+
+func inspectMySQLTable(ctx context.Context, db *sql.DB, name string) (*Table, error) {
+	t := &Table{Name: name}
+
+	rows, err := db.QueryContext(ctx, `SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE = 'YES', EXTRA LIKE '%auto_increment%',
+		EXTRA LIKE '%on update%'
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION`, name)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var col Column
+		if err := rows.Scan(&col.Name, &col.Type, &col.Nullable, &col.AutoIncrement, &col.OnUpdate); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		col.Type = NormalizeType(dialect.MySQL, col.Type)
+		t.Columns = append(t.Columns, &col)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	fks, err := db.QueryContext(ctx, `SELECT CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY CONSTRAINT_NAME, ORDINAL_POSITION`, name)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*ForeignKey)
+	for fks.Next() {
+		var constraint, column, table, referenced string
+		if err := fks.Scan(&constraint, &column, &table, &referenced); err != nil {
+			fks.Close()
+			return nil, err
+		}
+		fk, ok := byName[constraint]
+		if !ok {
+			fk = &ForeignKey{Name: constraint, References: table}
+			byName[constraint] = fk
+			t.ForeignKeys = append(t.ForeignKeys, fk)
+		}
+		fk.Columns = append(fk.Columns, column)
+		fk.Referenced = append(fk.Referenced, referenced)
+	}
+	fks.Close()
+	if err := fks.Err(); err != nil {
+		return nil, err
+	}
+
+	stats, err := db.QueryContext(ctx, `SELECT INDEX_NAME, NON_UNIQUE = 0, COLUMN_NAME
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME IS NOT NULL
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer stats.Close()
+
+	var order []string
+	indexes := make(map[string]*Index)
+	for stats.Next() {
+		var index, column string
+		var unique bool
+		if err := stats.Scan(&index, &unique, &column); err != nil {
+			return nil, err
+		}
+		idx, ok := indexes[index]
+		if !ok {
+			idx = &Index{Name: index, Unique: unique}
+			indexes[index] = idx
+			order = append(order, index)
+		}
+		idx.Columns = append(idx.Columns, column)
+	}
+	if err := stats.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, index := range order {
+		idx := indexes[index]
+		switch {
+		case index == "PRIMARY":
+			t.PrimaryKey = idx.Columns
+		case !idx.Unique && backsForeignKey(t, idx):
+			// MySQL indexes foreign key columns on its own; the index is part of the constraint
+		default:
+			t.addIndex(idx.Name, idx.Columns, idx.Unique)
+		}
+	}
+	return t, nil
+}
+
+// backsForeignKey reports whether the index is the one MySQL created for a
+// foreign key, named after the constraint or its first column
+func backsForeignKey(t *Table, idx *Index) bool {
+	for _, fk := range t.ForeignKeys {
+		if slices.Equal(fk.Columns, idx.Columns) && (strings.EqualFold(idx.Name, fk.Columns[0]) || strings.Contains(idx.Name, "_ibfk_")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// columnList aggregates the names of the columns numbered in an int2vector or
+// int2[] column, in key order
+const columnList = `(SELECT string_agg(a.attname, ',' ORDER BY k.ord)
+	FROM unnest(%s) WITH ORDINALITY AS k(attnum, ord)
+	JOIN pg_attribute a ON a.attrelid = %s AND a.attnum = k.attnum)`
+
+func inspectPostgres(ctx context.Context, db *sql.DB) (*Catalog, error) {
+	names, err := queryStrings(ctx, db, `SELECT table_name FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name`)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{}
+	for _, name := range names {
+		t, err := inspectPostgresTable(ctx, db, name)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", name, err)
+		}
+		c.Tables = append(c.Tables, t)
+	}
+	return c, nil
+}
+
+func inspectPostgresTable(ctx context.Context, db *sql.DB, name string) (*Table, error) {
+	t := &Table{Name: name}
+	rel := dialect.Postgres.QuoteIdent(name)
+
+	rows, err := db.QueryContext(ctx, `SELECT a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull,
+			COALESCE(pg_get_expr(d.adbin, d.adrelid), '') LIKE 'nextval(%' OR a.attidentity <> ''
+		FROM pg_attribute a
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = $1::text::regclass AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`, rel)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var col Column
+		if err := rows.Scan(&col.Name, &col.Type, &col.Nullable, &col.AutoIncrement); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		col.Type = NormalizeType(dialect.Postgres, col.Type)
+		t.Columns = append(t.Columns, &col)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	constraints, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT c.contype, c.conname, %s,
+			COALESCE((SELECT relname FROM pg_class WHERE oid = c.confrelid), ''),
+			COALESCE(%s, '')
+		FROM pg_constraint c
+		WHERE c.conrelid = $1::text::regclass AND c.contype IN ('p', 'u', 'f')
+		ORDER BY c.conname`,
+		fmt.Sprintf(columnList, "c.conkey", "c.conrelid"), fmt.Sprintf(columnList, "c.confkey", "c.confrelid")), rel)
+	if err != nil {
+		return nil, err
+	}
+	for constraints.Next() {
+		var kind, conname, columns, references, referenced string
+		if err := constraints.Scan(&kind, &conname, &columns, &references, &referenced); err != nil {
+			constraints.Close()
+			return nil, err
+		}
+		switch kind {
+		case "p":
+			t.PrimaryKey = strings.Split(columns, ",")
+		case "u":
+			t.addIndex(conname, strings.Split(columns, ","), true)
+		case "f":
+			t.ForeignKeys = append(t.ForeignKeys, &ForeignKey{
+				Name:       conname,
+				Columns:    strings.Split(columns, ","),
+				References: references,
+				Referenced: strings.Split(referenced, ","),
+			})
+		}
+	}
+	constraints.Close()
+	if err := constraints.Err(); err != nil {
+		return nil, err
+	}
+
+	// Indexes created on their own, not backing a constraint. Expression
+	// indexes have no column names and are left out.
+	indexes, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT i.relname, x.indisunique, %s
+		FROM pg_index x
+		JOIN pg_class i ON i.oid = x.indexrelid
+		WHERE x.indrelid = $1::text::regclass
+			AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = x.indexrelid AND c.contype IN ('p', 'u', 'x'))
+			AND NOT 0 = ANY (x.indkey::int2[])
+		ORDER BY i.relname`, fmt.Sprintf(columnList, "x.indkey::int2[]", "x.indrelid")), rel)
+	if err != nil {
+		return nil, err
+	}
+	defer indexes.Close()
+	for indexes.Next() {
+		var name, columns string
+		var unique bool
+		if err := indexes.Scan(&name, &unique, &columns); err != nil {
+			return nil, err
+		}
+		t.addIndex(name, strings.Split(columns, ","), unique)
+	}
+	return t, indexes.Err()
+}
@@ -0,0 +1,79 @@
+package catalog
+
+import (
+	"strings"
+
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// typeAliases maps the spellings databases report to the ones the DDL generator writes
+var typeAliases = map[dialect.Dialect]map[string]string{
+	dialect.Postgres: {
+		"CHARACTER VARYING":           "VARCHAR",
+		"CHARACTER":                   "CHAR",
+		"BPCHAR":                      "CHAR",
+		"INT":                         "INTEGER",
+		"INT4":                        "INTEGER",
+		"SERIAL":                      "INTEGER",
+		"SERIAL4":                     "INTEGER",
+		"INT8":                        "BIGINT",
+		"BIGSERIAL":                   "BIGINT",
+		"SERIAL8":                     "BIGINT",
+		"INT2":                        "SMALLINT",
+		"FLOAT8":                      "DOUBLE PRECISION",
+		"FLOAT4":                      "REAL",
+		"BOOL":                        "BOOLEAN",
+		"DECIMAL":                     "NUMERIC",
+		"TIMESTAMP WITHOUT TIME ZONE": "TIMESTAMP",
+		"TIMESTAMP WITH TIME ZONE":    "TIMESTAMPTZ",
+		"TIME WITHOUT TIME ZONE":      "TIME",
+		"TIME WITH TIME ZONE":         "TIMETZ",
+	},
+	dialect.MySQL: {
+		"INTEGER": "INT",
+		"BOOLEAN": "TINYINT(1)",
+		"BOOL":    "TINYINT(1)",
+		"NUMERIC": "DECIMAL",
+		"REAL":    "DOUBLE",
+	},
+}
+
+// NormalizeType returns the canonical spelling of a column type in dialect d:
+// upper case, single spaces, no display widths on MySQL integers and the
+// names the DDL generator uses for aliases
+func NormalizeType(d dialect.Dialect, typ string) string {
+	typ = strings.ToUpper(strings.Join(strings.Fields(typ), " "))
+	typ = strings.ReplaceAll(typ, ", ", ",")
+
+	array := ""
+	if base, ok := strings.CutSuffix(typ, "[]"); ok {
+		typ, array = base, "[]"
+	}
+
+	base, args := typ, ""
+	if i := strings.Index(typ, "("); i >= 0 {
+		base, args = strings.TrimSpace(typ[:i]), typ[i:]
+		// Keep modifiers written after the arguments, as in TIMESTAMP(3) WITHOUT TIME ZONE
+		if j := strings.Index(args, ")"); j >= 0 && j+1 < len(args) {
+			base += args[j+1:]
+			args = args[:j+1]
+		}
+	}
+
+	if alias, ok := typeAliases[d][base+args]; ok {
+		return alias + array
+	}
+	if alias, ok := typeAliases[d][base]; ok {
+		base = alias
+	}
+
+	if d == dialect.MySQL {
+		base = strings.TrimSuffix(base, " UNSIGNED")
+		switch base {
+		case "INT", "BIGINT", "SMALLINT", "MEDIUMINT":
+			// Display widths such as INT(11) do not change the type
+			args = ""
+		}
+	}
+	return base + args + array
+}
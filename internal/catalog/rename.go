@@ -0,0 +1,156 @@
+package catalog
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Renames maps new table and column names to the names they had before
+type Renames struct {
+	Tables  map[string]string            // New table name -> old table name
+	Columns map[string]map[string]string // New table name -> new column name -> old column name
+}
+
+// RenameColumn records that column from of table was renamed to to; table is
+// the new table name
+func (r *Renames) RenameColumn(table, from, to string) {
+	if r.Columns == nil {
+		r.Columns = make(map[string]map[string]string)
+	}
+	if r.Columns[table] == nil {
+		r.Columns[table] = make(map[string]string)
+	}
+	r.Columns[table][to] = from
+}
+
+// RenameTable records that table from was renamed to to
+func (r *Renames) RenameTable(from, to string) {
+	if r.Tables == nil {
+		r.Tables = make(map[string]string)
+	}
+	r.Tables[to] = from
+}
+
+// DiffRenamed is Diff with the given renames applied first. Each rename whose
+// old name exists in from and whose new name does not becomes a RenameTable
+// or RenameColumn change, listed before the other changes; the others are
+// ignored, so a schema keeping its rename directives diffs cleanly once the
+// rename has happened. It also returns from with the renames applied, which
+// the other changes start from.
+func DiffRenamed(from, to *Catalog, renames Renames) ([]Change, *Catalog) {
+	renamed := from.clone()
+	var changes []Change
+
+	for _, t := range to.Tables {
+		old, ok := renames.Tables[t.Name]
+		if !ok || old == t.Name {
+			continue
+		}
+		prev, ok := renamed.Table(old)
+		if !ok {
+			continue
+		}
+		if _, exists := renamed.Table(t.Name); exists {
+			continue
+		}
+		renamed.renameTable(prev, t.Name)
+		changes = append(changes, Change{Kind: RenameTable, Table: prev, OldName: old})
+	}
+
+	for _, t := range to.Tables {
+		table, ok := renamed.Table(t.Name)
+		if !ok {
+			continue
+		}
+		for _, col := range t.Columns {
+			old, ok := renames.Columns[t.Name][col.Name]
+			if !ok || old == col.Name {
+				continue
+			}
+			prev, ok := table.Column(old)
+			if !ok {
+				continue
+			}
+			if _, exists := table.Column(col.Name); exists {
+				continue
+			}
+			renamed.renameColumn(table, prev, col.Name)
+			changes = append(changes, Change{Kind: RenameColumn, Table: table, Column: prev, OldName: old})
+		}
+	}
+
+	return append(changes, Diff(renamed, to)...), renamed
+}
+
+// clone returns a deep copy of the catalog
+func (c *Catalog) clone() *Catalog {
+	out := &Catalog{Defaults: c.Defaults}
+	for _, t := range c.Tables {
+		table := &Table{Name: t.Name, PrimaryKey: slices.Clone(t.PrimaryKey)}
+		for _, col := range t.Columns {
+			copied := *col
+			table.Columns = append(table.Columns, &copied)
+		}
+		for _, idx := range t.Indexes {
+			copied := *idx
+			copied.Columns = slices.Clone(idx.Columns)
+			table.Indexes = append(table.Indexes, &copied)
+		}
+		for _, fk := range t.ForeignKeys {
+			copied := *fk
+			copied.Columns = slices.Clone(fk.Columns)
+			copied.Referenced = slices.Clone(fk.Referenced)
+			table.ForeignKeys = append(table.ForeignKeys, &copied)
+		}
+		out.Tables = append(out.Tables, table)
+	}
+	return out
+}
+
+// renameTable renames t and the foreign keys referencing it, as the
+// database does
+func (c *Catalog) renameTable(t *Table, name string) {
+	for _, other := range c.Tables {
+		for _, fk := range other.ForeignKeys {
+			if fk.References == t.Name {
+				fk.References = name
+			}
+		}
+	}
+	t.Name = name
+}
+
+// renameColumn renames col of t with the keys, indexes and foreign keys
+// using it, as the database does
+func (c *Catalog) renameColumn(t *Table, col *Column, name string) {
+	replace := func(names []string) {
+		for i, n := range names {
+			if n == col.Name {
+				names[i] = name
+			}
+		}
+	}
+	replace(t.PrimaryKey)
+	for _, idx := range t.Indexes {
+		replace(idx.Columns)
+	}
+	for _, fk := range t.ForeignKeys {
+		replace(fk.Columns)
+	}
+	for _, other := range c.Tables {
+		for _, fk := range other.ForeignKeys {
+			if fk.References == t.Name {
+				replace(fk.Referenced)
+			}
+		}
+	}
+	col.Name = name
+}
+
+// describeRename renders a RenameTable or RenameColumn change
+func (c Change) describeRename() string {
+	if c.Kind == RenameTable {
+		return fmt.Sprintf("rename table %s to %s", c.OldName, c.Table.Name)
+	}
+	return fmt.Sprintf("rename column %s.%s to %s", c.Table.Name, c.OldName, c.Column.Name)
+}
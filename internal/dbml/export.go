@@ -0,0 +1,236 @@
+package dbml
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	"github.com/pixperk/storm/internal/types/directive"
+	"github.com/pixperk/storm/internal/types/field"
+)
+
+var plainName = regexp.MustCompile(`^[a-zA-Z_]\w*$`)
+
+// databaseTypes are the DBML database_type names of the dialects
+var databaseTypes = map[dialect.Dialect]string{
+	dialect.Postgres: "PostgreSQL",
+	dialect.MySQL:    "MySQL",
+	dialect.SQLite:   "SQLite",
+}
+
+// Export renders the IR as DBML. Column types are those of the schema's
+// database, or PostgreSQL when it has none.
+func Export(irData *ir.IR) string {
+	d := dialect.Parse(irData.DatabaseDriver)
+	if d == dialect.Unknown {
+		d = dialect.Postgres
+	}
+	relations := irData.Relations()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Project storm {\n  database_type: '%s'\n}\n", databaseTypes[d])
+
+	var enums []string
+	for _, model := range irData.Models {
+		b.WriteString("\n")
+		enums = append(enums, writeTable(&b, irData, model, relations, d)...)
+	}
+	for _, enum := range enums {
+		b.WriteString("\n")
+		b.WriteString(enum)
+	}
+
+	if len(relations) > 0 {
+		b.WriteString("\n")
+	}
+	for _, r := range relations {
+		writeRef(&b, irData, r)
+	}
+
+	return b.String()
+}
+
+// writeTable writes the table of a model and returns the enums its columns use
+func writeTable(b *strings.Builder, irData *ir.IR, model ir.IRModel, relations []ir.Relation, d dialect.Dialect) []string {
+	var enums []string
+	fmt.Fprintf(b, "Table %s {\n", quoteName(model.Name))
+
+	declared := make(map[string]bool)
+	for _, f := range model.Fields {
+		if irData.IsRelation(f) {
+			continue
+		}
+		declared[f.ColumnName()] = true
+
+		typ := f.Type.SQLType(d)
+		if values := f.Type.GetDirective(directive.DirEnum); len(values) > 0 {
+			typ = model.Name + "_" + f.ColumnName()
+			enums = append(enums, enum(typ, values))
+		}
+		if f.IsArray {
+			typ += "[]"
+		}
+		fmt.Fprintf(b, "  %s %s%s\n", quoteName(f.ColumnName()), quoteType(typ), settings(columnSettings(f)))
+	}
+
+	// Implicit foreign key columns of @belongsTo relations
+	for _, r := range relations {
+		fk := r.ForeignKey
+		if fk == nil || fk.Model != model.Name || declared[fk.Column] {
+			continue
+		}
+		declared[fk.Column] = true
+
+		typ := field.NewFieldType(field.KindInt, "", nil).SQLType(d)
+		if target, ok := irData.FindModel(fk.References); ok {
+			if pk, ok := target.PrimaryKey(); ok {
+				typ = field.NewFieldType(pk.Type.Kind, "", nil).SQLType(d)
+			}
+		}
+		var opts []string
+		if child, ok := model.FindField(r.To.Field); !ok || !child.IsNullable() {
+			opts = append(opts, "not null")
+		}
+		fmt.Fprintf(b, "  %s %s%s\n", quoteName(fk.Column), quoteType(typ), settings(opts))
+	}
+
+	var indexes []string
+	for _, f := range model.Fields {
+		spec, ok := f.Index()
+		if !ok {
+			continue
+		}
+		var opts []string
+		if spec.Name != "" {
+			opts = append(opts, "name: "+quote(spec.Name))
+		}
+		if spec.Desc {
+			opts = append(opts, "sort: desc")
+		}
+		// DBML only knows btree and hash indexes
+		if method := strings.ToLower(spec.Method); method == "btree" || method == "hash" {
+			opts = append(opts, "type: "+method)
+		}
+		indexes = append(indexes, fmt.Sprintf("    %s%s\n", quoteName(f.ColumnName()), settings(opts)))
+	}
+	if len(indexes) > 0 {
+		b.WriteString("\n  indexes {\n")
+		for _, idx := range indexes {
+			b.WriteString(idx)
+		}
+		b.WriteString("  }\n")
+	}
+
+	if model.Doc != "" {
+		fmt.Fprintf(b, "\n  Note: %s\n", quote(model.Doc))
+	}
+	b.WriteString("}\n")
+	return enums
+}
+
+// columnSettings returns the [...] settings of a column
+func columnSettings(f ir.IRField) []string {
+	var opts []string
+	if f.Type.HasDirective(directive.DirID) {
+		opts = append(opts, "pk")
+	}
+	if f.Type.HasDirective(directive.DirAuto) {
+		opts = append(opts, "increment")
+	}
+	if f.Type.HasDirective(directive.DirUnique) {
+		opts = append(opts, "unique")
+	}
+	if !f.IsNullable() && !f.Type.HasDirective(directive.DirID) {
+		opts = append(opts, "not null")
+	}
+	if d, ok := f.Type.Directive(directive.DirDefault); ok && len(d.Positional()) > 0 {
+		opts = append(opts, "default: "+defaultValue(d.Positional()[0]))
+	} else if f.Type.HasDirective(directive.DirDefaultNow) || f.Type.HasDirective(directive.DirCreatedAt) ||
+		f.Type.HasDirective(directive.DirUpdatedAt) {
+		opts = append(opts, "default: `now()`")
+	}
+	if f.Doc != "" {
+		opts = append(opts, "note: "+quote(f.Doc))
+	}
+	return opts
+}
+
+// defaultValue renders a @default value as a DBML default
+func defaultValue(v directive.Value) string {
+	switch v.Kind {
+	case directive.ValueInt, directive.ValueFloat, directive.ValueBool:
+		return v.String()
+	case directive.ValueCall:
+		return "`" + v.String() + "`"
+	default:
+		return quote(v.String())
+	}
+}
+
+// enum renders an Enum block
+func enum(name string, values []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Enum %s {\n", quoteName(name))
+	for _, v := range values {
+		fmt.Fprintf(&b, "  %s\n", quoteName(v))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// writeRef writes a relation as a Ref
+func writeRef(b *strings.Builder, irData *ir.IR, r ir.Relation) {
+	name := ""
+	if r.Name != "" {
+		name = " " + quoteName(r.Name)
+	}
+
+	switch {
+	case r.ForeignKey != nil:
+		op := ">"
+		if r.Kind == ir.OneToOne {
+			op = "-"
+		}
+		fmt.Fprintf(b, "Ref%s: %s.%s %s %s.%s\n", name,
+			quoteName(r.ForeignKey.Model), quoteName(r.ForeignKey.Column), op,
+			quoteName(r.ForeignKey.References), quoteName(r.ForeignKey.Referenced))
+	case r.Kind == ir.ManyToMany && r.JoinTable != nil && !r.JoinTable.Through:
+		fmt.Fprintf(b, "Ref%s: %s.%s <> %s.%s\n", name,
+			quoteName(r.JoinTable.Source.Table), quoteName(r.JoinTable.Source.References),
+			quoteName(r.JoinTable.Target.Table), quoteName(r.JoinTable.Target.References))
+	}
+}
+
+func settings(opts []string) string {
+	if len(opts) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(opts, ", ") + "]"
+}
+
+// quote renders a DBML string, using triple quotes for multi-line text
+func quote(s string) string {
+	if strings.Contains(s, "\n") {
+		return "'''" + s + "'''"
+	}
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "'", `\'`) + "'"
+}
+
+// quoteName quotes names that are not plain identifiers
+func quoteName(name string) string {
+	if plainName.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// quoteType quotes column types with spaces, e.g. "double precision"
+func quoteType(typ string) string {
+	if strings.Contains(typ, " ") {
+		return strconv.Quote(typ)
+	}
+	return typ
+}
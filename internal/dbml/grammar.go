@@ -0,0 +1,218 @@
+// Package dbml converts between the Storm IR and DBML, the schema language of dbdiagram.io.
+package dbml
+
+import (
+	"io"
+	"strings"
+
+	"github.com/alecthomas/participle/v2"
+	participleLexer "github.com/alecthomas/participle/v2/lexer"
+)
+
+// File is a parsed DBML document
+type File struct {
+	Elements []*Element `parser:"@@*"`
+}
+
+type Element struct {
+	Project *Project    `parser:"  @@"`
+	Table   *Table      `parser:"| @@"`
+	Enum    *Enum       `parser:"| @@"`
+	Ref     *Ref        `parser:"| @@"`
+	Group   *TableGroup `parser:"| @@"`
+}
+
+type Project struct {
+	Name  *string        `parser:"\"Project\" @(Ident | String)?"`
+	Items []*ProjectItem `parser:"\"{\" @@* \"}\""`
+}
+
+type ProjectItem struct {
+	Note  *Note         `parser:"  @@"`
+	Key   string        `parser:"| @Ident \":\""`
+	Value *SettingValue `parser:"  @@"`
+}
+
+type Table struct {
+	Pos      participleLexer.Position
+	Name     *Name        `parser:"\"Table\" @@"`
+	Alias    *string      `parser:"( \"as\" @Ident )?"`
+	Settings []*Setting   `parser:"( \"[\" @@ ( \",\" @@ )* \"]\" )?"`
+	Items    []*TableItem `parser:"\"{\" @@* \"}\""`
+}
+
+type TableItem struct {
+	Note    *Note    `parser:"  @@"`
+	Indexes []*Index `parser:"| \"indexes\" \"{\" @@* \"}\""`
+	Column  *Column  `parser:"| @@"`
+}
+
+type Column struct {
+	Pos      participleLexer.Position
+	Name     string      `parser:"@(Ident | String)"`
+	Type     *ColumnType `parser:"@@"`
+	Settings []*Setting  `parser:"( \"[\" @@ ( \",\" @@ )* \"]\" )?"`
+}
+
+type ColumnType struct {
+	Name    *Name    `parser:"@@"`
+	Args    []string `parser:"( \"(\" @(Number | Ident) ( \",\" @(Number | Ident) )* \")\" )?"`
+	IsArray bool     `parser:"(@\"[\" @\"]\")?"`
+}
+
+// Name is a possibly schema-qualified name, e.g. public.users
+type Name struct {
+	Parts []string `parser:"@(Ident | String) ( \".\" @(Ident | String) )*"`
+}
+
+// Setting is an entry of a [...] settings list, e.g. pk, not null or default: 0
+type Setting struct {
+	Key   []string      `parser:"@Ident+"`
+	Value *SettingValue `parser:"( \":\" @@ )?"`
+}
+
+type SettingValue struct {
+	Ref    *InlineRef `parser:"  @@"`
+	String *string    `parser:"| @(String | TripleString)"`
+	Expr   *string    `parser:"| @Expr"`
+	Number *string    `parser:"| @Number"`
+	Color  *string    `parser:"| @Color"`
+	Ident  *Name      `parser:"| @@"`
+}
+
+// InlineRef is the value of a column's ref setting, e.g. > users.id
+type InlineRef struct {
+	Op     string    `parser:"@Op"`
+	Target *Endpoint `parser:"@@"`
+}
+
+type Index struct {
+	Columns  []*IndexColumn `parser:"( \"(\" @@ ( \",\" @@ )* \")\" | @@ )"`
+	Settings []*Setting     `parser:"( \"[\" @@ ( \",\" @@ )* \"]\" )?"`
+}
+
+type IndexColumn struct {
+	Name *string `parser:"  @(Ident | String)"`
+	Expr *string `parser:"| @Expr"`
+}
+
+type Enum struct {
+	Name   *Name        `parser:"\"Enum\" @@"`
+	Values []*EnumValue `parser:"\"{\" @@* \"}\""`
+}
+
+type EnumValue struct {
+	Name     string     `parser:"@(Ident | String)"`
+	Settings []*Setting `parser:"( \"[\" @@ ( \",\" @@ )* \"]\" )?"`
+}
+
+type Ref struct {
+	Name *string  `parser:"\"Ref\" @(Ident | String)?"`
+	Body *RefBody `parser:"( \":\" @@ | \"{\" @@ \"}\" )"`
+}
+
+type RefBody struct {
+	From     *Endpoint  `parser:"@@"`
+	Op       string     `parser:"@Op"`
+	To       *Endpoint  `parser:"@@"`
+	Settings []*Setting `parser:"( \"[\" @@ ( \",\" @@ )* \"]\" )?"`
+}
+
+// Endpoint is a column reference, table.column or table.(a, b)
+type Endpoint struct {
+	Parts     []string `parser:"@(Ident | String) ( \".\" @(Ident | String) )*"`
+	Composite []string `parser:"( \".\" \"(\" @(Ident | String) ( \",\" @(Ident | String) )* \")\" )?"`
+}
+
+type TableGroup struct {
+	Name   string  `parser:"\"TableGroup\" @(Ident | String)"`
+	Tables []*Name `parser:"\"{\" @@* \"}\""`
+}
+
+type Note struct {
+	Text string `parser:"\"Note\" ( \":\" @(String | TripleString) | \"{\" @(String | TripleString) \"}\" )"`
+}
+
+var lexerRules = []participleLexer.SimpleRule{
+	{Name: "Comment", Pattern: `//.*|/\*(.|\n)*?\*/`},
+	{Name: "Whitespace", Pattern: `\s+`},
+	{Name: "TripleString", Pattern: `'''(.|\n)*?'''`},
+	{Name: "String", Pattern: `'(\\.|[^'\\])*'|"(\\.|[^"\\])*"`},
+	{Name: "Expr", Pattern: "`[^`]*`"},
+	{Name: "Color", Pattern: `#[0-9a-fA-F]+`},
+	{Name: "Number", Pattern: `[-+]?\d+(\.\d+)?`},
+	{Name: "Ident", Pattern: `[a-zA-Z_]\w*`},
+	{Name: "Op", Pattern: `<>|[<>-]`},
+	{Name: "Punct", Pattern: `[{}\[\](),:.]`},
+}
+
+var dbmlLexer = participleLexer.MustSimple(lexerRules)
+
+var parser = participle.MustBuild[File](
+	participle.Lexer(dbmlLexer),
+	participle.Elide("Comment", "Whitespace"),
+	// DBML keywords such as Table and Ref are case-insensitive
+	participle.CaseInsensitive("Ident"),
+	// Column names may collide with keywords such as note and indexes
+	participle.UseLookahead(3),
+)
+
+// Parse parses a DBML document
+func Parse(name string, r io.Reader) (*File, error) {
+	return parser.Parse(name, r)
+}
+
+// Last returns the unqualified part of the name
+func (n *Name) Last() string {
+	return unquote(n.Parts[len(n.Parts)-1])
+}
+
+// Text returns the setting key in lower case, e.g. "not null"
+func (s *Setting) Text() string {
+	return strings.ToLower(strings.Join(s.Key, " "))
+}
+
+// unquote strips the quotes of a DBML string and resolves its escapes
+func unquote(s string) string {
+	switch {
+	case strings.HasPrefix(s, "'''"):
+		return dedent(strings.TrimSuffix(strings.TrimPrefix(s, "'''"), "'''"))
+	case strings.HasPrefix(s, "'") || strings.HasPrefix(s, "\""):
+		quote := s[:1]
+		s = s[1 : len(s)-1]
+		s = strings.ReplaceAll(s, "\\"+quote, quote)
+		return strings.ReplaceAll(s, "\\\\", "\\")
+	case strings.HasPrefix(s, "`"):
+		return strings.Trim(s, "`")
+	default:
+		return s
+	}
+}
+
+// dedent removes the indentation shared by the lines of a multi-line string
+func dedent(s string) string {
+	lines := strings.Split(s, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		}
+		lines[i] = strings.TrimRight(lines[i], " \t")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,589 @@
+package dbml
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/directive"
+	"github.com/pixperk/storm/internal/types/field"
+)
+
+var identifierRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
+
+// reservedNames cannot be used as field names in schemas
+var reservedNames = map[string]bool{"type": true, "model": true, "select": true, "package": true}
+
+// ref is a relationship between two columns, normalized so that From holds
+// the foreign key: ">" is many-to-one, "-" one-to-one and "<>" many-to-many
+type ref struct {
+	Name     string
+	From, To endpoint
+	Op       string
+}
+
+type endpoint struct {
+	Table, Column string
+}
+
+// importer holds the state of a DBML import
+type importer struct {
+	ir       *ir.IR
+	enums    map[string][]string
+	aliases  map[string]string
+	columns  map[endpoint]string // DBML column -> field name
+	nullable map[endpoint]bool
+	refs     []ref
+	warnings []string
+}
+
+// placeholderHost is the host of the database URL Import writes when it is
+// given none, as DBML does not record where the database runs
+const placeholderHost = "TODO-set-database-url"
+
+// Import converts a DBML document into the IR, with url as the database URL.
+// Constructs that schemas cannot express, such as composite keys, are skipped
+// and reported as warnings, as is a placeholder URL written for an empty url.
+func Import(file *File, url string) (*ir.IR, []string, error) {
+	imp := &importer{
+		ir:       &ir.IR{DatabaseDriver: "postgres"},
+		enums:    make(map[string][]string),
+		aliases:  make(map[string]string),
+		columns:  make(map[endpoint]string),
+		nullable: make(map[endpoint]bool),
+	}
+
+	for _, el := range file.Elements {
+		switch {
+		case el.Project != nil:
+			imp.project(el.Project)
+		case el.Enum != nil:
+			var values []string
+			for _, v := range el.Enum.Values {
+				values = append(values, unquote(v.Name))
+			}
+			imp.enums[el.Enum.Name.Last()] = values
+		case el.Table != nil && el.Table.Alias != nil:
+			imp.aliases[*el.Table.Alias] = el.Table.Name.Last()
+		}
+	}
+	imp.ir.DatabaseURL = url
+	if url == "" {
+		imp.ir.DatabaseURL = fmt.Sprintf("%s://%s", imp.ir.DatabaseDriver, placeholderHost)
+		imp.warn("the database url %s is a placeholder; pass -url or set it in the schema", imp.ir.DatabaseURL)
+	}
+
+	for _, el := range file.Elements {
+		switch {
+		case el.Table != nil:
+			if err := imp.table(el.Table); err != nil {
+				return nil, nil, err
+			}
+		case el.Ref != nil:
+			name := ""
+			if el.Ref.Name != nil {
+				name = unquote(*el.Ref.Name)
+			}
+			imp.addRef(name, el.Ref.Body.From, el.Ref.Body.Op, el.Ref.Body.To)
+		}
+	}
+
+	if err := imp.relations(); err != nil {
+		return nil, nil, err
+	}
+	return imp.ir, imp.warnings, nil
+}
+
+func (imp *importer) warn(format string, args ...interface{}) {
+	imp.warnings = append(imp.warnings, fmt.Sprintf(format, args...))
+}
+
+// project reads the database type of the Project block
+func (imp *importer) project(p *Project) {
+	for _, item := range p.Items {
+		if item.Key != "database_type" || item.Value.String == nil {
+			continue
+		}
+		dbType := strings.ToLower(unquote(*item.Value.String))
+		switch {
+		case strings.Contains(dbType, "postgres"):
+			imp.ir.DatabaseDriver = "postgres"
+		case strings.Contains(dbType, "mysql"):
+			imp.ir.DatabaseDriver = "mysql"
+		case strings.Contains(dbType, "sqlite"):
+			imp.ir.DatabaseDriver = "sqlite"
+		default:
+			imp.warn("database_type %s is not supported; using postgres", dbType)
+		}
+	}
+}
+
+// table converts a table into a model
+func (imp *importer) table(t *Table) error {
+	name := t.Name.Last()
+	if !identifierRegex.MatchString(name) {
+		return fmt.Errorf("%s: table name %q is not a valid model name", t.Pos, name)
+	}
+	if _, exists := imp.ir.FindModel(name); exists {
+		return fmt.Errorf("%s: duplicate table %s", t.Pos, name)
+	}
+
+	model := ir.IRModel{Name: name}
+	for _, item := range t.Items {
+		switch {
+		case item.Note != nil:
+			model.Doc = unquote(item.Note.Text)
+		case item.Column != nil:
+			model.Fields = append(model.Fields, imp.column(name, item.Column))
+		}
+	}
+	for _, item := range t.Items {
+		for _, idx := range item.Indexes {
+			imp.index(&model, idx)
+		}
+	}
+
+	imp.ir.Models = append(imp.ir.Models, model)
+	return nil
+}
+
+// column converts a column into a scalar field
+func (imp *importer) column(table string, c *Column) ir.IRField {
+	column := unquote(c.Name)
+	f := ir.IRField{Name: fieldName(column), IsArray: c.Type.IsArray}
+	imp.columns[endpoint{table, column}] = f.Name
+
+	kind, directives := imp.columnType(table, column, c.Type)
+	if f.Name != column {
+		directives = append(directives, *directive.NewDirective(directive.DirMap, []directive.Arg{{Value: stringValue(column)}}))
+	}
+
+	notNull, primary := false, false
+	for _, s := range c.Settings {
+		switch s.Text() {
+		case "pk", "primary key":
+			primary = true
+			if kind == field.KindInt {
+				directives = append([]directive.Directive{{Kind: directive.DirID}}, directives...)
+			} else {
+				imp.warn("%s.%s: primary keys must be Int; imported as @unique", table, column)
+				directives = append(directives, directive.Directive{Kind: directive.DirUnique})
+			}
+		case "increment":
+			if !hasDirective(directives, directive.DirAuto) {
+				directives = append(directives, directive.Directive{Kind: directive.DirAuto})
+			}
+		case "unique":
+			directives = append(directives, directive.Directive{Kind: directive.DirUnique})
+		case "not null":
+			notNull = true
+		case "null":
+			notNull = false
+		case "note":
+			if s.Value != nil && s.Value.String != nil {
+				f.Doc = unquote(*s.Value.String)
+			}
+		case "default":
+			if d, ok := imp.defaultValue(table, column, s.Value); ok {
+				directives = append(directives, d)
+			}
+		case "ref":
+			if s.Value != nil && s.Value.Ref != nil {
+				imp.addRef("", &Endpoint{Parts: []string{table, column}}, s.Value.Ref.Op, s.Value.Ref.Target)
+			}
+		}
+	}
+
+	// DBML columns are nullable unless declared not null
+	if !notNull && !primary {
+		directives = append(directives, directive.Directive{Kind: directive.DirNullable})
+		imp.nullable[endpoint{table, column}] = true
+	}
+
+	f.Type = *field.NewFieldType(kind, "", directives)
+	return f
+}
+
+// columnType maps a DBML column type to a field kind and the directives it implies
+func (imp *importer) columnType(table, column string, t *ColumnType) (field.FieldKind, []directive.Directive) {
+	typeName := t.Name.Last()
+	if values, ok := imp.enums[typeName]; ok {
+		args := make([]directive.Arg, 0, len(values))
+		for _, v := range values {
+			args = append(args, directive.Arg{Value: stringValue(v)})
+		}
+		return field.KindString, []directive.Directive{*directive.NewDirective(directive.DirEnum, args)}
+	}
+
+	var intArgs []int64
+	for _, arg := range t.Args {
+		if n, err := strconv.ParseInt(arg, 10, 64); err == nil {
+			intArgs = append(intArgs, n)
+		}
+	}
+
+	switch strings.ToLower(typeName) {
+	case "serial", "serial4", "smallserial", "serial2":
+		return field.KindInt, []directive.Directive{{Kind: directive.DirAuto}}
+	case "bigserial", "serial8":
+		return field.KindBigInt, []directive.Directive{{Kind: directive.DirAuto}}
+	case "int", "int2", "int4", "smallint", "tinyint", "mediumint":
+		return field.KindInt, nil
+	case "int8":
+		return field.KindBigInt, nil
+	case "float4", "float8", "double precision":
+		return field.KindFloat, nil
+	case "money":
+		return field.KindDecimal, nil
+	case "decimal", "numeric":
+		if len(intArgs) == 2 {
+			return field.KindDecimal, []directive.Directive{*directive.NewDirective(directive.DirPrecision,
+				[]directive.Arg{{Value: intValue(intArgs[0])}, {Value: intValue(intArgs[1])}})}
+		}
+		return field.KindDecimal, nil
+	case "varchar", "character varying", "nvarchar":
+		if len(intArgs) == 1 {
+			return field.KindString, []directive.Directive{*directive.NewDirective(directive.DirLength,
+				[]directive.Arg{{Value: intValue(intArgs[0])}})}
+		}
+		return field.KindString, nil
+	case "character", "nchar", "bpchar":
+		return field.KindChar, nil
+	case "tinytext", "mediumtext", "longtext", "citext":
+		return field.KindText, nil
+	case "timestamptz", "timestamp with time zone":
+		return field.KindTimestamp, nil
+	case "timetz":
+		return field.KindTime, nil
+	case "jsonb":
+		return field.KindJSON, nil
+	case "bytea", "varbinary", "longblob":
+		return field.KindBinary, nil
+	}
+
+	kind := ir.MapFieldType(typeName)
+	if kind == field.KindCustom {
+		imp.warn("%s.%s: unknown type %s imported as String", table, column, typeName)
+		kind = field.KindString
+	}
+	return kind, nil
+}
+
+// defaultValue converts a default: setting into a @default directive
+func (imp *importer) defaultValue(table, column string, v *SettingValue) (directive.Directive, bool) {
+	var value directive.Value
+	switch {
+	case v == nil:
+		return directive.Directive{}, false
+	case v.String != nil:
+		value = stringValue(unquote(*v.String))
+	case v.Number != nil:
+		if n, err := strconv.ParseInt(*v.Number, 10, 64); err == nil {
+			value = intValue(n)
+		} else {
+			f, _ := strconv.ParseFloat(*v.Number, 64)
+			value = directive.Value{Kind: directive.ValueFloat, Float: f}
+		}
+	case v.Ident != nil && (v.Ident.Last() == "true" || v.Ident.Last() == "false"):
+		value = directive.Value{Kind: directive.ValueBool, Bool: v.Ident.Last() == "true"}
+	case v.Expr != nil:
+		switch expr := strings.ToLower(strings.TrimSpace(unquote(*v.Expr))); expr {
+		case "now()", "current_timestamp", "current_timestamp()", "localtimestamp":
+			// Export writes @createdAt and @updatedAt as this default, so the
+			// columns named after them get their directive back
+			switch strings.ToLower(strings.ReplaceAll(column, "_", "")) {
+			case "createdat":
+				return directive.Directive{Kind: directive.DirCreatedAt}, true
+			case "updatedat":
+				return directive.Directive{Kind: directive.DirUpdatedAt}, true
+			}
+			value = directive.Value{Kind: directive.ValueCall, Call: &directive.Call{Name: "now"}}
+		case "gen_random_uuid()", "uuid_generate_v4()", "uuid()":
+			value = directive.Value{Kind: directive.ValueCall, Call: &directive.Call{Name: "uuid"}}
+		default:
+			imp.warn("%s.%s: default expression %s is not supported", table, column, expr)
+			return directive.Directive{}, false
+		}
+	default:
+		// default: null is the column default anyway
+		return directive.Directive{}, false
+	}
+	return *directive.NewDirective(directive.DirDefault, []directive.Arg{{Value: value}}), true
+}
+
+// index applies a single-column index to its field
+func (imp *importer) index(model *ir.IRModel, idx *Index) {
+	if len(idx.Columns) != 1 || idx.Columns[0].Name == nil {
+		imp.warn("%s: composite and expression indexes are not supported", model.Name)
+		return
+	}
+	column := unquote(*idx.Columns[0].Name)
+	f := findColumn(model, column)
+	if f == nil {
+		imp.warn("%s: index on unknown column %s", model.Name, column)
+		return
+	}
+
+	var args []directive.Arg
+	unique := false
+	for _, s := range idx.Settings {
+		switch s.Text() {
+		case "unique":
+			unique = true
+		case "pk":
+			imp.warn("%s.%s: primary keys declared in indexes are not supported", model.Name, column)
+			return
+		case "name":
+			if s.Value != nil && s.Value.String != nil {
+				args = append(args, directive.Arg{Name: "name", Value: stringValue(unquote(*s.Value.String))})
+			}
+		case "type":
+			if s.Value != nil && s.Value.Ident != nil {
+				args = append(args, directive.Arg{Name: "type", Value: directive.Value{Kind: directive.ValueIdent, Text: s.Value.Ident.Last()}})
+			}
+		case "sort":
+			if s.Value != nil && s.Value.Ident != nil && strings.EqualFold(s.Value.Ident.Last(), "desc") {
+				args = append(args, directive.Arg{Name: "sort", Value: directive.Value{Kind: directive.ValueIdent, Text: "Desc"}})
+			}
+		}
+	}
+
+	if unique {
+		if !f.Type.HasDirective(directive.DirUnique) {
+			f.Type.Directives = append(f.Type.Directives, directive.Directive{Kind: directive.DirUnique})
+		}
+		return
+	}
+	f.Type.Directives = append(f.Type.Directives, *directive.NewDirective(directive.DirIndex, args))
+}
+
+// addRef records a relationship, normalizing its direction
+func (imp *importer) addRef(name string, from *Endpoint, op string, to *Endpoint) {
+	if len(from.Composite) > 0 || len(to.Composite) > 0 || len(from.Parts) < 2 || len(to.Parts) < 2 {
+		imp.warn("ref %s: composite references are not supported", name)
+		return
+	}
+	r := ref{Name: name, From: imp.endpoint(from), To: imp.endpoint(to), Op: op}
+	if r.Op == "<" {
+		r.From, r.To, r.Op = r.To, r.From, ">"
+	}
+	imp.refs = append(imp.refs, r)
+}
+
+func (imp *importer) endpoint(e *Endpoint) endpoint {
+	table := unquote(e.Parts[len(e.Parts)-2])
+	if real, ok := imp.aliases[table]; ok {
+		table = real
+	}
+	return endpoint{Table: table, Column: unquote(e.Parts[len(e.Parts)-1])}
+}
+
+// relations adds a pair of relation fields for every ref
+func (imp *importer) relations() error {
+	// Refs sharing a pair of tables must be told apart by name
+	pairs := make(map[[2]string]int)
+	for _, r := range imp.refs {
+		pairs[tablePair(r)]++
+	}
+
+	for _, r := range imp.refs {
+		if r.Op == "-" && imp.isPrimaryKey(r.From) && !imp.isPrimaryKey(r.To) {
+			// The key of a one-to-one ref is on the side that is not the primary key
+			r.From, r.To = r.To, r.From
+		}
+
+		from, ok := imp.ir.FindModel(r.From.Table)
+		if !ok {
+			return fmt.Errorf("ref %s: unknown table %s", r.Name, r.From.Table)
+		}
+		to, ok := imp.ir.FindModel(r.To.Table)
+		if !ok {
+			return fmt.Errorf("ref %s: unknown table %s", r.Name, r.To.Table)
+		}
+
+		name := ""
+		if pairs[tablePair(r)] > 1 || r.From.Table == r.To.Table {
+			name = r.Name
+			if name == "" {
+				name = r.From.Table + "_" + r.From.Column
+			}
+		}
+		var relationArgs []directive.Arg
+		if name != "" {
+			relationArgs = append(relationArgs, directive.Arg{Value: stringValue(name)})
+		}
+
+		// Fields of named relations are named after the relation, the others after the related model
+		plural := func(model string) string {
+			if name != "" {
+				return camelCase(name)
+			}
+			return pluralize(lowerFirst(model))
+		}
+
+		if r.Op == "<>" {
+			imp.addRelationField(from, imp.uniqueName(from, plural(to.Name)), to.Name, true, directive.DirHasMany, relationArgs)
+			imp.addRelationField(to, imp.uniqueName(to, plural(from.Name)), from.Name, true, directive.DirHasMany, relationArgs)
+			continue
+		}
+
+		// The side holding the foreign key belongs to the referenced side
+		belongsName := imp.uniqueName(from, relationFieldName(r.From.Column))
+		belongsArgs := relationArgs
+		if fkField := imp.columns[r.From]; fkField != "" && fkField != belongsName+"Id" {
+			belongsArgs = append(belongsArgs, directive.Arg{Name: "fields", Value: arrayValue(fkField)})
+		}
+		if referenced := imp.columns[r.To]; referenced != "" {
+			if pk, ok := to.PrimaryKey(); !ok || pk.Name != referenced {
+				belongsArgs = append(belongsArgs, directive.Arg{Name: "references", Value: arrayValue(referenced)})
+			}
+		}
+		belongsTo := imp.addRelationField(from, belongsName, to.Name, false, directive.DirBelongsTo, belongsArgs)
+		if imp.nullable[r.From] {
+			belongsTo.Type.Directives = append(belongsTo.Type.Directives, directive.Directive{Kind: directive.DirNullable})
+		}
+
+		if r.Op == "-" {
+			imp.addRelationField(to, imp.uniqueName(to, lowerFirst(from.Name)), from.Name, false, directive.DirHasOne, relationArgs)
+		} else {
+			imp.addRelationField(to, imp.uniqueName(to, plural(from.Name)), from.Name, true, directive.DirHasMany, relationArgs)
+		}
+	}
+	return nil
+}
+
+// uniqueName returns a field name derived from hint that is not yet used in the model
+func (imp *importer) uniqueName(model *ir.IRModel, hint string) string {
+	base := fieldName(hint)
+	if base == "" {
+		base = "related"
+	}
+	name := base
+	for i := 2; findField(model, name) != nil; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	return name
+}
+
+// addRelationField appends a relation field to the model
+func (imp *importer) addRelationField(model *ir.IRModel, name, target string, array bool, kind directive.DirectiveKind, relationArgs []directive.Arg) *ir.IRField {
+	directives := []directive.Directive{{Kind: kind}}
+	if len(relationArgs) > 0 {
+		directives = append(directives, *directive.NewDirective(directive.DirRelation, relationArgs))
+	}
+	model.Fields = append(model.Fields, ir.IRField{
+		Name:    name,
+		Type:    *field.NewFieldType(field.KindCustom, target, directives),
+		IsArray: array,
+	})
+	return &model.Fields[len(model.Fields)-1]
+}
+
+// isPrimaryKey reports whether the column is the primary key of its table
+func (imp *importer) isPrimaryKey(e endpoint) bool {
+	model, ok := imp.ir.FindModel(e.Table)
+	if !ok {
+		return false
+	}
+	pk, ok := model.PrimaryKey()
+	return ok && pk.Name == imp.columns[e]
+}
+
+func hasDirective(directives []directive.Directive, kind directive.DirectiveKind) bool {
+	for _, d := range directives {
+		if d.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func tablePair(r ref) [2]string {
+	if r.From.Table < r.To.Table {
+		return [2]string{r.From.Table, r.To.Table}
+	}
+	return [2]string{r.To.Table, r.From.Table}
+}
+
+// relationFieldName derives the name of a relation field from its key column, e.g. author_id -> author
+func relationFieldName(column string) string {
+	for _, suffix := range []string{"_id", "Id", "ID"} {
+		if name, ok := strings.CutSuffix(column, suffix); ok && name != "" {
+			return name
+		}
+	}
+	return column
+}
+
+// fieldName turns a column name into a valid field name
+func fieldName(column string) string {
+	name := regexp.MustCompile(`\W+`).ReplaceAllString(column, "_")
+	if name != "" && !identifierRegex.MatchString(name) {
+		name = "f_" + name
+	}
+	if reservedNames[name] {
+		name += "_"
+	}
+	return name
+}
+
+func findField(model *ir.IRModel, name string) *ir.IRField {
+	for i := range model.Fields {
+		if model.Fields[i].Name == name {
+			return &model.Fields[i]
+		}
+	}
+	return nil
+}
+
+// findColumn returns the field stored in the given column
+func findColumn(model *ir.IRModel, column string) *ir.IRField {
+	for i := range model.Fields {
+		if model.Fields[i].ColumnName() == column {
+			return &model.Fields[i]
+		}
+	}
+	return nil
+}
+
+// pluralize returns a plural form of an English noun
+func pluralize(name string) string {
+	switch {
+	case strings.HasSuffix(name, "s"):
+		return name
+	case strings.HasSuffix(name, "y") && !strings.HasSuffix(name, "ey"):
+		return strings.TrimSuffix(name, "y") + "ies"
+	default:
+		return name + "s"
+	}
+}
+
+// camelCase turns a snake_case name into camelCase
+func camelCase(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == ' ' || r == '-' })
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return lowerFirst(strings.Join(parts, ""))
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func stringValue(s string) directive.Value {
+	return directive.Value{Kind: directive.ValueString, Text: s}
+}
+
+func intValue(n int64) directive.Value {
+	return directive.Value{Kind: directive.ValueInt, Int: n}
+}
+
+func arrayValue(ident string) directive.Value {
+	return directive.Value{Kind: directive.ValueArray, Items: []directive.Value{{Kind: directive.ValueIdent, Text: ident}}}
+}
@@ -0,0 +1,354 @@
+// Package fake generates synthetic rows for the models of the Storm IR, for
+// loading production-sized tables into a test database.
+//
+// Values follow the field kinds and the @length, @min, @max, @precision,
+// @enum, @unique and @nullable directives, and every @belongsTo key points at
+// a generated row of the related model. Implicit many-to-many join tables
+// link the rows generated for both sides. @id @auto keys continue from
+// Options.KeysAfter and unique values from Options.RowsBefore, @updatedAt
+// follows @createdAt and @deletedAt is left NULL, so no row is soft deleted.
+// The same seed always yields the same rows.
+package fake
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pixperk/storm/internal/seed"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// Options configures the generated data
+type Options struct {
+	Rows     map[string]int // Number of rows by model; models left out get none
+	Seed     int64          // Seed of the random source
+	NullRate float64        // Share of nullable values left null, 0.1 when zero
+	// KeysAfter holds the largest @id @auto key each model's table already
+	// has; the generated keys follow it
+	KeysAfter map[string]int64
+	// RowsBefore holds the number of rows each model's table already has;
+	// unique values follow those generated for them
+	RowsBefore map[string]int
+}
+
+// Table holds the generated rows of one model
+type Table struct {
+	Model   string
+	Columns []Column
+	Rows    [][]interface{} // Values in column order
+}
+
+// Column is a column of a generated table
+type Column struct {
+	Name  string     // Column name
+	Field ir.IRField // Field describing the column's type; synthesized for implicit keys
+}
+
+// ParseRows parses row counts written as "User=10000,Post=50000"
+func ParseRows(s string) (map[string]int, error) {
+	rows := make(map[string]int)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		model, count, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid row count %q, want Model=N", part)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid row count %q, want Model=N", part)
+		}
+		rows[strings.TrimSpace(model)] = n
+	}
+	return rows, nil
+}
+
+// Generate returns a table per model with rows, in dependency order
+func Generate(irData *ir.IR, opts Options) ([]Table, error) {
+	if opts.NullRate == 0 {
+		opts.NullRate = 0.1
+	}
+	for name := range opts.Rows {
+		if _, ok := irData.FindModel(name); !ok {
+			return nil, fmt.Errorf("unknown model %s", name)
+		}
+	}
+
+	order, err := seed.Order(irData)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{rng: rand.New(rand.NewSource(opts.Seed)), nullRate: opts.NullRate}
+	tables := make(map[string]*Table)
+	var result []Table
+
+	for _, name := range order {
+		n := opts.Rows[name]
+		if n == 0 {
+			continue
+		}
+		model, _ := irData.FindModel(name)
+		table, err := g.table(irData, *model, n, opts.KeysAfter[name], opts.RowsBefore[name], tables)
+		if err != nil {
+			return nil, fmt.Errorf("model %s: %w", name, err)
+		}
+		tables[name] = table
+		result = append(result, *table)
+	}
+
+	for _, jt := range joinTables(irData) {
+		if table := g.links(jt, tables); table != nil {
+			result = append(result, *table)
+		}
+	}
+	return result, nil
+}
+
+// joinTables returns the implicit join tables of the many-to-many relations,
+// once per relation; join tables declared with through: are models
+func joinTables(irData *ir.IR) []*ir.JoinTable {
+	var joins []*ir.JoinTable
+	seen := make(map[string]bool)
+	for _, model := range irData.Models {
+		for _, f := range model.Fields {
+			jt, ok := irData.JoinTable(model, f)
+			if !ok || jt.Through || seen[jt.Name] {
+				continue
+			}
+			seen[jt.Name] = true
+			joins = append(joins, jt)
+		}
+	}
+	return joins
+}
+
+// links fills a join table, linking each generated row of the source side
+// to up to three distinct rows of the target side. It returns nil when
+// either side has no generated rows.
+func (g *generator) links(jt *ir.JoinTable, tables map[string]*Table) *Table {
+	source, target := tables[jt.Source.Table], tables[jt.Target.Table]
+	if source == nil || target == nil {
+		return nil
+	}
+	sourceKey, targetKey := columnIndex(source, jt.Source.References), columnIndex(target, jt.Target.References)
+	if sourceKey < 0 || targetKey < 0 {
+		return nil
+	}
+
+	table := &Table{Model: jt.Name, Columns: []Column{
+		{Name: jt.Source.Name, Field: ir.IRField{Name: jt.Source.Name, Type: jt.Source.Type}},
+		{Name: jt.Target.Name, Field: ir.IRField{Name: jt.Target.Name, Type: jt.Target.Type}},
+	}}
+	for _, row := range source.Rows {
+		n := min(g.rng.Intn(4), len(target.Rows))
+		for _, j := range g.rng.Perm(len(target.Rows))[:n] {
+			table.Rows = append(table.Rows, []interface{}{row[sourceKey], target.Rows[j][targetKey]})
+		}
+	}
+	return table
+}
+
+// columnIndex returns the index of the named column of a table, -1 when it has none
+func columnIndex(t *Table, name string) int {
+	for i, col := range t.Columns {
+		if col.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// reference is a @belongsTo relation whose key column the generator fills
+type reference struct {
+	Relation ir.IRField
+	Column   int  // Index of the key column
+	Unique   bool // One-to-one relations and unique keys use each target row once
+}
+
+// table generates the rows of a model, numbering @id @auto keys from
+// keysAfter+1 and unique values from the rowsBefore rows already there
+func (g *generator) table(irData *ir.IR, model ir.IRModel, n int, keysAfter int64, rowsBefore int, tables map[string]*Table) (*Table, error) {
+	table := &Table{Model: model.Name}
+	columns := make(map[string]int)
+	for _, f := range model.Fields {
+		if irData.IsRelation(f) {
+			continue
+		}
+		columns[f.Name] = len(table.Columns)
+		table.Columns = append(table.Columns, Column{Name: f.ColumnName(), Field: f})
+	}
+
+	oneToOne := make(map[string]bool)
+	for _, r := range irData.Relations() {
+		if r.Kind == ir.OneToOne && r.ForeignKey != nil && r.To.Model == model.Name {
+			oneToOne[r.To.Field] = true
+		}
+	}
+
+	var refs []reference
+	for _, f := range model.Fields {
+		if !f.Type.HasDirective(directive.DirBelongsTo) || !irData.IsRelation(f) {
+			continue
+		}
+		key := ir.ForeignKeyName(f)
+		i, declared := columns[key]
+		if !declared {
+			i = len(table.Columns)
+			table.Columns = append(table.Columns, Column{Name: model.ForeignKeyColumn(f), Field: keyField(irData, f)})
+		}
+		unique := oneToOne[f.Name] || declared && table.Columns[i].Field.Type.HasDirective(directive.DirUnique)
+		refs = append(refs, reference{Relation: f, Column: i, Unique: unique})
+	}
+
+	// Generated keys are written explicitly so references can point at them
+	keyed := make(map[int]bool)
+	for _, ref := range refs {
+		keyed[ref.Column] = true
+	}
+
+	created, updated := -1, -1
+	for c, col := range table.Columns {
+		switch t := col.Field.Type; {
+		case t.HasDirective(directive.DirCreatedAt):
+			created = c
+		case t.HasDirective(directive.DirUpdatedAt):
+			updated = c
+		}
+	}
+
+	table.Rows = make([][]interface{}, n)
+	for i := range table.Rows {
+		row := make([]interface{}, len(table.Columns))
+		for c, col := range table.Columns {
+			t := col.Field.Type
+			switch {
+			case keyed[c]:
+				continue
+			case t.HasDirective(directive.DirDeletedAt):
+				// Left NULL, as soft deleted rows are hidden from queries
+				continue
+			case t.HasDirective(directive.DirID) && t.HasDirective(directive.DirAuto):
+				row[c] = keysAfter + int64(i+1)
+				continue
+			}
+			v, err := g.value(col.Field, rowsBefore+i)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", col.Field.Name, err)
+			}
+			row[c] = v
+		}
+		if created >= 0 && updated >= 0 {
+			// Rows are updated after they were created, and before the epoch
+			if at, ok := row[created].(time.Time); ok && row[updated] != nil {
+				row[updated] = at.Add(time.Duration(g.rng.Int63n(int64(epoch.Sub(at)/time.Second)+1)) * time.Second)
+			}
+		}
+		table.Rows[i] = row
+	}
+
+	for _, ref := range refs {
+		if err := g.fillReference(irData, table, ref, tables); err != nil {
+			return nil, fmt.Errorf("field %s: %w", ref.Relation.Name, err)
+		}
+	}
+	return table, nil
+}
+
+// fillReference sets the key column of a @belongsTo relation in every row
+// to the key of a random row of the related model
+func (g *generator) fillReference(irData *ir.IR, table *Table, ref reference, tables map[string]*Table) error {
+	f := ref.Relation
+	target := tables[f.Type.ModelName]
+	self := f.Type.ModelName == table.Model
+	if self {
+		target = table
+	}
+
+	var keys []interface{}
+	if target != nil {
+		column := referencedColumn(irData, f, target)
+		if column < 0 {
+			return fmt.Errorf("no column of %s to reference", f.Type.ModelName)
+		}
+		for _, row := range target.Rows {
+			keys = append(keys, row[column])
+		}
+	}
+
+	if len(keys) == 0 {
+		if f.IsNullable() {
+			return nil
+		}
+		return fmt.Errorf("needs rows of %s; give it a row count", f.Type.ModelName)
+	}
+
+	var order []int
+	if ref.Unique {
+		order = g.rng.Perm(len(keys))
+	}
+	for i, row := range table.Rows {
+		switch {
+		case f.IsNullable() && g.rng.Float64() < g.nullRate:
+			row[ref.Column] = nil
+		case ref.Unique && i < len(order):
+			row[ref.Column] = keys[order[i]]
+		case ref.Unique && f.IsNullable():
+			row[ref.Column] = nil
+		case ref.Unique:
+			return fmt.Errorf("%d rows need as many rows of %s, which has %d", len(table.Rows), f.Type.ModelName, len(keys))
+		case self:
+			// Point at an earlier row so rows can be inserted in order; the first points at itself
+			row[ref.Column] = keys[g.rng.Intn(i+1)]
+		default:
+			row[ref.Column] = keys[g.rng.Intn(len(keys))]
+		}
+	}
+	return nil
+}
+
+// referencedColumn returns the index of the column of target a @belongsTo key points at
+func referencedColumn(irData *ir.IR, f ir.IRField, target *Table) int {
+	model, ok := irData.FindModel(f.Type.ModelName)
+	if !ok {
+		return -1
+	}
+	referenced, ok := model.PrimaryKey()
+	if ref := ir.ForeignKeyReference(f); ref != "" {
+		referenced, ok = model.FindField(ref)
+	}
+	if !ok {
+		return -1
+	}
+	for i, col := range target.Columns {
+		if col.Field.Name == referenced.Name {
+			return i
+		}
+	}
+	return -1
+}
+
+// keyField returns a field typed like the key a @belongsTo relation references
+func keyField(irData *ir.IR, f ir.IRField) ir.IRField {
+	key := ir.IRField{Name: ir.ForeignKeyName(f), Type: *fld.NewFieldType(fld.KindInt, "", nil)}
+	if target, ok := irData.FindModel(f.Type.ModelName); ok {
+		referenced, ok := target.PrimaryKey()
+		if ref := ir.ForeignKeyReference(f); ref != "" {
+			referenced, ok = target.FindField(ref)
+		}
+		if ok {
+			key.Type = *fld.NewFieldType(referenced.Type.Kind, "", nil)
+		}
+	}
+	if f.IsNullable() {
+		key.Type.Directives = []directive.Directive{{Kind: directive.DirNullable}}
+	}
+	return key
+}
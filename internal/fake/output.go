@@ -0,0 +1,294 @@
+package fake
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pixperk/storm/internal/database"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	"github.com/pixperk/storm/internal/types/directive"
+)
+
+// batchSize is the number of rows per INSERT statement
+const batchSize = 500
+
+// maxParams bounds the bind parameters of one statement, below the limits of all dialects
+const maxParams = 30000
+
+// WriteSQL writes the tables as batched INSERT statements inside a transaction
+func WriteSQL(w io.Writer, d dialect.Dialect, tables []Table) error {
+	if _, err := io.WriteString(w, "BEGIN;\n"); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		for start := 0; start < len(t.Rows); start += batchSize {
+			end := min(start+batchSize, len(t.Rows))
+			rows := make([]string, 0, end-start)
+			for _, row := range t.Rows[start:end] {
+				values := make([]string, len(row))
+				for i, v := range row {
+					values[i] = literal(d, v)
+				}
+				rows = append(rows, "("+strings.Join(values, ", ")+")")
+			}
+			if _, err := fmt.Fprintf(w, "INSERT INTO %s (%s) VALUES\n  %s;\n",
+				d.QuoteIdent(t.Model), columnList(d, t), strings.Join(rows, ",\n  ")); err != nil {
+				return err
+			}
+		}
+		if stmt := resetSequence(d, t); stmt != "" {
+			if _, err := fmt.Fprintf(w, "%s;\n", stmt); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := io.WriteString(w, "COMMIT;\n")
+	return err
+}
+
+// WriteCSV writes each table to <dir>/<Model>.csv with a header row. NULL is
+// an empty field, as COPY ... WITH (FORMAT csv) reads it.
+func WriteCSV(dir string, d dialect.Dialect, tables []Table) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if err := writeCSVFile(filepath.Join(dir, t.Model+".csv"), d, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSVFile(path string, d dialect.Dialect, t Table) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	header := make([]string, len(t.Columns))
+	for i, col := range t.Columns {
+		header[i] = col.Name
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, row := range t.Rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = text(d, v)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Insert writes the tables into the database inside one transaction
+func Insert(ctx context.Context, db *sql.DB, d dialect.Dialect, tables []Table) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, t := range tables {
+		size := batchSize
+		if len(t.Columns) > 0 {
+			size = max(1, min(batchSize, maxParams/len(t.Columns)))
+		}
+		for start := 0; start < len(t.Rows); start += size {
+			end := min(start+size, len(t.Rows))
+			var groups []string
+			var args []interface{}
+			for _, row := range t.Rows[start:end] {
+				placeholders := make([]string, len(row))
+				for i, v := range row {
+					args = append(args, argument(d, v))
+					placeholders[i] = database.Placeholder(d, len(args))
+				}
+				groups = append(groups, "("+strings.Join(placeholders, ", ")+")")
+			}
+			stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", d.QuoteIdent(t.Model), columnList(d, t), strings.Join(groups, ", "))
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return fmt.Errorf("model %s: %w", t.Model, err)
+			}
+		}
+		if stmt := resetSequence(d, t); stmt != "" {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("model %s: %w", t.Model, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// RowsBefore returns the number of rows the tables of the models already
+// hold, for Options.RowsBefore
+func RowsBefore(ctx context.Context, db *sql.DB, d dialect.Dialect, irData *ir.IR) (map[string]int, error) {
+	rows := make(map[string]int)
+	for _, model := range irData.Models {
+		var n int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+d.QuoteIdent(model.Name)).Scan(&n); err != nil {
+			return nil, fmt.Errorf("model %s: %w", model.Name, err)
+		}
+		rows[model.Name] = n
+	}
+	return rows, nil
+}
+
+// KeysAfter returns the largest @id @auto key the tables of the models
+// already hold, for Options.KeysAfter
+func KeysAfter(ctx context.Context, db *sql.DB, d dialect.Dialect, irData *ir.IR) (map[string]int64, error) {
+	keys := make(map[string]int64)
+	for _, model := range irData.Models {
+		pk, ok := model.PrimaryKey()
+		if !ok || !pk.Type.HasDirective(directive.DirAuto) {
+			continue
+		}
+		var last sql.NullInt64
+		err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT MAX(%s) FROM %s", d.QuoteIdent(pk.ColumnName()), d.QuoteIdent(model.Name))).Scan(&last)
+		if err != nil {
+			return nil, fmt.Errorf("model %s: %w", model.Name, err)
+		}
+		keys[model.Name] = last.Int64
+	}
+	return keys, nil
+}
+
+func columnList(d dialect.Dialect, t Table) string {
+	names := make([]string, len(t.Columns))
+	for i, col := range t.Columns {
+		names[i] = d.QuoteIdent(col.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// resetSequence moves a PostgreSQL serial key past the explicitly written
+// keys, so later inserts do not collide with them. Other dialects advance
+// their counters on their own.
+func resetSequence(d dialect.Dialect, t Table) string {
+	if d != dialect.Postgres {
+		return ""
+	}
+	for _, col := range t.Columns {
+		if col.Field.Type.HasDirective(directive.DirID) && col.Field.Type.HasDirective(directive.DirAuto) {
+			return fmt.Sprintf("SELECT setval(pg_get_serial_sequence(%s, %s), (SELECT MAX(%s) FROM %s))",
+				d.QuoteString(d.QuoteIdent(t.Model)), d.QuoteString(col.Name), d.QuoteIdent(col.Name), d.QuoteIdent(t.Model))
+		}
+	}
+	return ""
+}
+
+// literal renders a generated value as a SQL literal
+func literal(d dialect.Dialect, v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case Decimal:
+		return string(v)
+	case bool:
+		if d == dialect.Postgres {
+			return strings.ToUpper(strconv.FormatBool(v))
+		}
+		if v {
+			return "1"
+		}
+		return "0"
+	case []byte:
+		if d == dialect.Postgres {
+			return `'\x` + hex.EncodeToString(v) + `'`
+		}
+		return "X'" + hex.EncodeToString(v) + "'"
+	default:
+		return d.QuoteString(text(d, v))
+	}
+}
+
+// text renders a generated value as CSV text, or the content of a string literal
+func text(d dialect.Dialect, v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.DateTime)
+	case []byte:
+		if d == dialect.Postgres {
+			return `\x` + hex.EncodeToString(v)
+		}
+		return hex.EncodeToString(v)
+	case []interface{}:
+		return list(d, v)
+	case bool:
+		if d == dialect.Postgres {
+			return strconv.FormatBool(v)
+		}
+		if v {
+			return "1"
+		}
+		return "0"
+	default:
+		return literal(d, v)
+	}
+}
+
+// argument converts a generated value into a query argument
+func argument(d dialect.Dialect, v interface{}) interface{} {
+	switch v := v.(type) {
+	case Decimal:
+		return string(v)
+	case []interface{}:
+		return list(d, v)
+	default:
+		return v
+	}
+}
+
+// list renders a scalar list as the column stores it: a PostgreSQL array, or JSON elsewhere
+func list(d dialect.Dialect, items []interface{}) string {
+	if d == dialect.Postgres {
+		elems := make([]interface{}, len(items))
+		for i, item := range items {
+			elems[i] = text(d, item)
+		}
+		return database.ArrayLiteral(elems)
+	}
+
+	elems := make([]interface{}, len(items))
+	for i, item := range items {
+		switch item := item.(type) {
+		case Decimal:
+			elems[i] = json.Number(item)
+		case time.Time:
+			elems[i] = item.Format(time.RFC3339)
+		default:
+			elems[i] = item
+		}
+	}
+	data, _ := json.Marshal(elems)
+	return string(data)
+}
@@ -0,0 +1,333 @@
+package fake
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// Decimal is a generated DECIMAL value, kept as text to preserve its scale
+type Decimal string
+
+// epoch anchors generated timestamps so runs do not depend on the clock
+var epoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	firstNames = []string{"Ada", "Alan", "Grace", "Linus", "Margaret", "Ken", "Barbara", "Dennis", "Frances", "John", "Radia", "Edsger", "Hedy", "Donald", "Karen", "Tim"}
+	lastNames  = []string{"Lovelace", "Turing", "Hopper", "Torvalds", "Hamilton", "Thompson", "Liskov", "Ritchie", "Allen", "McCarthy", "Perlman", "Dijkstra", "Lamarr", "Knuth", "Jones", "Berners-Lee"}
+	cities     = []string{"Lisbon", "Nairobi", "Osaka", "Toronto", "Berlin", "Lima", "Melbourne", "Pune", "Oslo", "Austin"}
+	countries  = []string{"Portugal", "Kenya", "Japan", "Canada", "Germany", "Peru", "Australia", "India", "Norway", "United States"}
+	words      = []string{"storm", "cloud", "river", "stone", "signal", "harbor", "lantern", "meadow", "copper", "orbit", "pixel", "ember", "canyon", "delta", "forest", "quartz", "summit", "tide", "violet", "willow"}
+)
+
+// generator produces the values of the generated rows
+type generator struct {
+	rng      *rand.Rand
+	nullRate float64
+}
+
+// value generates the value of a field in row i
+func (g *generator) value(f ir.IRField, i int) (interface{}, error) {
+	t := f.Type
+	unique := t.HasDirective(directive.DirUnique) || t.HasDirective(directive.DirID)
+	if f.IsNullable() && !unique && g.rng.Float64() < g.nullRate {
+		return nil, nil
+	}
+
+	if f.IsArray {
+		items := make([]interface{}, g.rng.Intn(4))
+		for j := range items {
+			v, err := g.scalar(f, i, false)
+			if err != nil {
+				return nil, err
+			}
+			items[j] = v
+		}
+		return items, nil
+	}
+	return g.scalar(f, i, unique)
+}
+
+// scalar generates a single value of the field's kind; unique values are
+// derived from the row index so no two rows share one, and indexes counting
+// on from the rows a table already holds keep them apart from those too
+func (g *generator) scalar(f ir.IRField, i int, unique bool) (interface{}, error) {
+	t := f.Type
+
+	if d, ok := t.Directive(directive.DirEnum); ok {
+		values := d.StringArgs()
+		if unique {
+			if i >= len(values) {
+				return nil, fmt.Errorf("cannot generate more than %d unique values", len(values))
+			}
+			return values[i], nil
+		}
+		return values[g.rng.Intn(len(values))], nil
+	}
+
+	switch t.Kind {
+	case fld.KindInt, fld.KindBigInt:
+		lo, hi := bounds(t, 0, 1000)
+		if t.Kind == fld.KindInt {
+			lo, hi = math.Max(lo, math.MinInt32), math.Min(hi, math.MaxInt32)
+		}
+		min, max := int64(math.Ceil(lo)), int64(math.Floor(hi))
+		if unique {
+			if min+int64(i) > max {
+				return nil, fmt.Errorf("cannot generate more than %d unique values between %d and %d", max-min+1, min, max)
+			}
+			return min + int64(i), nil
+		}
+		return min + g.rng.Int63n(max-min+1), nil
+	case fld.KindFloat:
+		lo, hi := bounds(t, 0, 1000)
+		if unique {
+			return lo + (hi-lo)*float64(i)/float64(i+1), nil
+		}
+		return math.Round((lo+g.rng.Float64()*(hi-lo))*100) / 100, nil
+	case fld.KindDecimal:
+		return g.decimal(t, i, unique)
+	case fld.KindBoolean:
+		if unique {
+			if i >= 2 {
+				return nil, errors.New("cannot generate more than 2 unique values")
+			}
+			return i == 1, nil
+		}
+		return g.rng.Intn(2) == 1, nil
+	case fld.KindDateTime, fld.KindTimestamp:
+		if unique {
+			return epoch.Add(-time.Duration(i) * time.Minute), nil
+		}
+		return epoch.Add(-time.Duration(g.rng.Int63n(2*365*24*3600)) * time.Second), nil
+	case fld.KindDate:
+		days := g.rng.Intn(2 * 365)
+		if unique {
+			days = i
+		}
+		return epoch.AddDate(0, 0, -days).Format(time.DateOnly), nil
+	case fld.KindTime:
+		seconds := g.rng.Intn(24 * 3600)
+		if unique {
+			if i >= 24*3600 {
+				return nil, errors.New("cannot generate more unique times than seconds in a day")
+			}
+			seconds = i
+		}
+		return time.Time{}.Add(time.Duration(seconds) * time.Second).Format(time.TimeOnly), nil
+	case fld.KindUUID:
+		return g.uuid(i, unique), nil
+	case fld.KindCUID:
+		return g.cuid(i, unique), nil
+	case fld.KindBinary:
+		b := make([]byte, 16)
+		g.rng.Read(b)
+		return b, nil
+	case fld.KindJSON:
+		return fmt.Sprintf(`{"%s":%d}`, words[g.rng.Intn(len(words))], g.rng.Intn(1000)), nil
+	case fld.KindPoint:
+		return fmt.Sprintf("(%.4f,%.4f)", g.rng.Float64()*360-180, g.rng.Float64()*180-90), nil
+	default:
+		return g.text(f, i, unique)
+	}
+}
+
+// bounds returns the range of a numeric field from @min and @max, defaulting
+// to [lo, hi] shifted to fit the given end
+func bounds(t fld.FieldType, lo, hi float64) (float64, float64) {
+	min, hasMin := directiveArg(t, directive.DirMin)
+	max, hasMax := directiveArg(t, directive.DirMax)
+	switch {
+	case hasMin && hasMax:
+		return min, max
+	case hasMin:
+		return min, min + (hi - lo)
+	case hasMax:
+		return math.Min(lo, max-(hi-lo)), max
+	}
+	return lo, hi
+}
+
+func directiveArg(t fld.FieldType, kind directive.DirectiveKind) (float64, bool) {
+	d, ok := t.Directive(kind)
+	if !ok || len(d.Positional()) == 0 {
+		return 0, false
+	}
+	v := d.Positional()[0]
+	if v.Kind == directive.ValueInt {
+		return float64(v.Int), true
+	}
+	return v.Float, true
+}
+
+// decimal generates a value fitting the @precision of a DECIMAL field
+func (g *generator) decimal(t fld.FieldType, i int, unique bool) (interface{}, error) {
+	precision, scale := 10, 2
+	if p, s := t.GetPrecisionScale(); p != "" {
+		precision, _ = strconv.Atoi(p)
+		scale, _ = strconv.Atoi(s)
+	}
+
+	limit := math.Pow(10, float64(precision-scale)) - 1
+	lo, hi := bounds(t, 0, math.Min(limit, 1000))
+	hi = math.Min(hi, limit)
+
+	v := lo + g.rng.Float64()*(hi-lo)
+	if unique {
+		v = lo + float64(i)/math.Pow(10, float64(scale))
+		if v > hi {
+			return nil, fmt.Errorf("cannot generate %d unique values with precision %d and scale %d", i+1, precision, scale)
+		}
+	}
+	return Decimal(strconv.FormatFloat(v, 'f', scale, 64)), nil
+}
+
+// text generates a string, picking realistic content from the field name
+func (g *generator) text(f ir.IRField, i int, unique bool) (interface{}, error) {
+	name := strings.ToLower(f.Name)
+	first, last := firstNames[g.rng.Intn(len(firstNames))], lastNames[g.rng.Intn(len(lastNames))]
+
+	var s string
+	switch {
+	case strings.Contains(name, "email"):
+		n := g.rng.Intn(1000)
+		if unique {
+			n = i
+		}
+		return email(strings.ToLower(first+"."+last), n, f)
+	case strings.Contains(name, "firstname"):
+		s = first
+	case strings.Contains(name, "lastname"), strings.Contains(name, "surname"):
+		s = last
+	case strings.Contains(name, "username"), strings.Contains(name, "login"), strings.Contains(name, "handle"):
+		s = fmt.Sprintf("%s%d", strings.ToLower(first), g.rng.Intn(1000))
+	case strings.Contains(name, "name"):
+		s = first + " " + last
+	case strings.Contains(name, "url"), strings.Contains(name, "website"), strings.Contains(name, "link"):
+		s = fmt.Sprintf("https://example.com/%s/%s", g.word(), g.word())
+	case strings.Contains(name, "phone"):
+		s = fmt.Sprintf("+1-555-%03d-%04d", g.rng.Intn(1000), g.rng.Intn(10000))
+	case strings.Contains(name, "city"):
+		s = cities[g.rng.Intn(len(cities))]
+	case strings.Contains(name, "country"):
+		s = countries[g.rng.Intn(len(countries))]
+	case strings.Contains(name, "slug"):
+		s = g.word() + "-" + g.word()
+	case strings.Contains(name, "title"), strings.Contains(name, "subject"):
+		s = g.sentence(3 + g.rng.Intn(4))
+	case f.Type.Kind == fld.KindText, strings.Contains(name, "description"), strings.Contains(name, "body"),
+		strings.Contains(name, "content"), strings.Contains(name, "bio"):
+		var sentences []string
+		for n := 1 + g.rng.Intn(3); n > 0; n-- {
+			sentences = append(sentences, g.sentence(6+g.rng.Intn(8))+".")
+		}
+		s = strings.Join(sentences, " ")
+	case f.Type.Kind == fld.KindChar:
+		s = strings.ToUpper(g.word())
+	default:
+		s = g.word()
+		for n := g.rng.Intn(3); n > 0; n-- {
+			s += " " + g.word()
+		}
+	}
+	return fit(s, f, i, unique)
+}
+
+// email builds an address from a local part and a number, shortening the
+// local part and then the domain to fit @length while keeping the number
+func email(local string, n int, f ir.IRField) (string, error) {
+	limit := -1
+	if d, ok := f.Type.Directive(directive.DirLength); ok && len(d.Positional()) > 0 {
+		limit = int(d.Positional()[0].Int)
+	}
+
+	number := strconv.Itoa(n)
+	for _, domain := range []string{"@example.com", "@x.io"} {
+		room := limit - len(number) - len(domain)
+		switch {
+		case limit < 0:
+			return local + number + domain, nil
+		case room >= len(local):
+			return local + number + domain, nil
+		case room >= 1:
+			return strings.TrimRight(local[:room], ".") + number + domain, nil
+		}
+	}
+	return "", fmt.Errorf("@length(%d) is too short for email addresses", limit)
+}
+
+// fit truncates a string to the @length of the field, keeping the row index
+// suffix of unique values
+func fit(s string, f ir.IRField, i int, unique bool) (string, error) {
+	suffix := ""
+	if unique {
+		suffix = "-" + strconv.Itoa(i)
+	}
+
+	limit := -1
+	if d, ok := f.Type.Directive(directive.DirLength); ok && len(d.Positional()) > 0 {
+		limit = int(d.Positional()[0].Int)
+	}
+	if limit < 0 {
+		return s + suffix, nil
+	}
+
+	room := limit - utf8.RuneCountInString(suffix)
+	if room < 0 {
+		return "", fmt.Errorf("@length(%d) leaves no room for unique values", limit)
+	}
+	if runes := []rune(s); len(runes) > room {
+		s = strings.TrimRight(string(runes[:room]), " ")
+	}
+	return s + suffix, nil
+}
+
+func (g *generator) word() string {
+	return words[g.rng.Intn(len(words))]
+}
+
+func (g *generator) sentence(n int) string {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = g.word()
+	}
+	s := strings.Join(parts, " ")
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// uuid returns a random version 4 UUID drawn from the seeded source
+func (g *generator) uuid(i int, unique bool) string {
+	b := make([]byte, 16)
+	g.rng.Read(b)
+	if unique {
+		// The last bytes hold the row index, as the same seed draws the same bytes
+		binary.BigEndian.PutUint32(b[12:], uint32(i))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// cuid returns a CUID-shaped identifier drawn from the seeded source
+func (g *generator) cuid(i int, unique bool) string {
+	const alphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+	b := []byte{'c'}
+	for len(b) < 25 {
+		b = append(b, alphabet[g.rng.Intn(len(alphabet))])
+	}
+	if unique {
+		// The last characters hold the row index, as the same seed draws the same ones
+		copy(b[17:], fmt.Sprintf("%08s", strconv.FormatInt(int64(i), 36)))
+	}
+	return string(b)
+}
@@ -0,0 +1,44 @@
+package ddl
+
+import (
+	"fmt"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// commentStatements renders the COMMENT ON statements documenting a model in Postgres
+func commentStatements(irData *ir.IR, model ir.IRModel, d dialect.Dialect) []string {
+	if d != dialect.Postgres {
+		return nil
+	}
+
+	var stmts []string
+	if model.Doc != "" {
+		stmts = append(stmts, fmt.Sprintf("COMMENT ON TABLE %s IS %s", d.QuoteIdent(model.Name), d.QuoteString(model.Doc)))
+	}
+	for _, f := range model.Fields {
+		if f.Doc == "" || irData.IsRelation(f) {
+			continue
+		}
+		stmts = append(stmts, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s",
+			d.QuoteIdent(model.Name), d.QuoteIdent(f.ColumnName()), d.QuoteString(f.Doc)))
+	}
+	return stmts
+}
+
+// columnComment returns the inline COMMENT clause of a MySQL column, or ""
+func columnComment(f ir.IRField, d dialect.Dialect) string {
+	if d != dialect.MySQL || f.Doc == "" {
+		return ""
+	}
+	return " COMMENT " + d.QuoteString(f.Doc)
+}
+
+// tableComment returns the COMMENT table option of a MySQL table, or ""
+func tableComment(model ir.IRModel, d dialect.Dialect) string {
+	if d != dialect.MySQL || model.Doc == "" {
+		return ""
+	}
+	return " COMMENT=" + d.QuoteString(model.Doc)
+}
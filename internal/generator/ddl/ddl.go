@@ -0,0 +1,246 @@
+// Package ddl renders the Storm IR as CREATE TABLE and CREATE INDEX statements.
+package ddl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// Generate returns the DDL statements creating every model of the IR.
+// Tables are created after the tables they reference; keys of self and
+// circular references are added once every table exists, except on SQLite,
+// which cannot add them later but does not check them at creation either.
+func Generate(irData *ir.IR, d dialect.Dialect) []string {
+	var tables, keys, indexes, comments []string
+
+	created := make(map[string]bool)
+	for _, model := range dependencyOrder(irData) {
+		table, deferred := createTable(irData, model, created, d)
+		created[model.Name] = true
+		tables = append(tables, table)
+		keys = append(keys, deferred...)
+		indexes = append(indexes, createIndexes(model, d)...)
+		comments = append(comments, commentStatements(irData, model, d)...)
+	}
+	for _, jt := range joinTables(irData) {
+		tables = append(tables, createJoinTable(jt, d))
+	}
+
+	stmts := append(tables, keys...)
+	return append(append(stmts, indexes...), comments...)
+}
+
+// dependencyOrder orders the models so that referenced models come before
+// the models referencing them, keeping the declared order otherwise
+func dependencyOrder(irData *ir.IR) []ir.IRModel {
+	pending := make(map[string]bool)
+	for _, model := range irData.Models {
+		pending[model.Name] = true
+	}
+
+	var order []ir.IRModel
+	for len(pending) > 0 {
+		progress := false
+		for _, model := range irData.Models {
+			if !pending[model.Name] || !ready(irData, model, pending) {
+				continue
+			}
+			order = append(order, model)
+			delete(pending, model.Name)
+			progress = true
+		}
+		if !progress {
+			// Models referencing each other in a cycle keep their order
+			for _, model := range irData.Models {
+				if pending[model.Name] {
+					order = append(order, model)
+					delete(pending, model.Name)
+				}
+			}
+		}
+	}
+	return order
+}
+
+// ready reports whether every model the model references, other than
+// itself, is placed
+func ready(irData *ir.IR, model ir.IRModel, pending map[string]bool) bool {
+	for _, fk := range foreignKeys(irData, model) {
+		if fk.Table != model.Name && pending[fk.Table] {
+			return false
+		}
+	}
+	return true
+}
+
+// Render joins statements into a single SQL script
+func Render(stmts []string) string {
+	var b strings.Builder
+	for i, stmt := range stmts {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(stmt)
+		b.WriteString(";\n")
+	}
+	return b.String()
+}
+
+// createTable renders the CREATE TABLE statement of a model, along with the
+// statements adding the keys that reference itself or tables not yet created
+func createTable(irData *ir.IR, model ir.IRModel, created map[string]bool, d dialect.Dialect) (string, []string) {
+	var lines []string
+
+	for _, f := range model.Fields {
+		if irData.IsRelation(f) {
+			continue
+		}
+		lines = append(lines, columnDefinition(f, d))
+	}
+
+	// Implicit key columns go with the other columns; SQLite wants every
+	// column defined before the table constraints
+	fks := foreignKeys(irData, model)
+	for _, fk := range fks {
+		if fk.Column != nil {
+			lines = append(lines, columnDefinition(*fk.Column, d))
+		}
+	}
+	var deferred []string
+	for _, fk := range fks {
+		clause := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+			d.QuoteIdent(fk.Name), d.QuoteIdent(fk.Table), d.QuoteIdent(fk.References))
+		if d != dialect.SQLite && !created[fk.Table] {
+			deferred = append(deferred, fmt.Sprintf("ALTER TABLE %s ADD %s", d.QuoteIdent(model.Name), clause))
+			continue
+		}
+		lines = append(lines, clause)
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n  %s\n)%s", d.QuoteIdent(model.Name), strings.Join(lines, ",\n  "), tableComment(model, d)), deferred
+}
+
+// columnDefinition renders a single column of a CREATE TABLE statement
+func columnDefinition(f ir.IRField, d dialect.Dialect) string {
+	return columnSpec(f, d) + columnComment(f, d)
+}
+
+// columnSpec renders the name, type and constraints of a column
+func columnSpec(f ir.IRField, d dialect.Dialect) string {
+	parts := []string{d.QuoteIdent(f.ColumnName())}
+
+	isID := f.Type.HasDirective(directive.DirID)
+	isAuto := isID && f.Type.HasDirective(directive.DirAuto)
+
+	switch {
+	case isAuto && d == dialect.Postgres:
+		if f.Type.Kind == fld.KindBigInt {
+			parts = append(parts, "BIGSERIAL PRIMARY KEY")
+		} else {
+			parts = append(parts, "SERIAL PRIMARY KEY")
+		}
+		return strings.Join(parts, " ")
+	case isAuto && d == dialect.MySQL:
+		parts = append(parts, f.Type.MySQLType(), "NOT NULL AUTO_INCREMENT PRIMARY KEY")
+		return strings.Join(parts, " ")
+	case isAuto && d == dialect.SQLite:
+		parts = append(parts, "INTEGER PRIMARY KEY AUTOINCREMENT")
+		return strings.Join(parts, " ")
+	}
+
+	parts = append(parts, columnType(f, d))
+
+	if isID {
+		parts = append(parts, "PRIMARY KEY")
+	} else if !f.IsNullable() {
+		parts = append(parts, "NOT NULL")
+	}
+	if f.Type.HasDirective(directive.DirUnique) {
+		parts = append(parts, "UNIQUE")
+	}
+	if def := DefaultClause(f, d); def != "" {
+		parts = append(parts, "DEFAULT "+def)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// columnType returns the SQL type of a column, including scalar lists
+func columnType(f ir.IRField, d dialect.Dialect) string {
+	if !f.IsArray {
+		return f.Type.SQLType(d)
+	}
+
+	switch d {
+	case dialect.Postgres:
+		return f.Type.PostgresType() + "[]"
+	case dialect.MySQL:
+		return "JSON"
+	default:
+		return "TEXT"
+	}
+}
+
+// DefaultClause renders the DEFAULT expression of a column, or "" when it has
+// none. On MySQL, @updatedAt columns are also kept current by ON UPDATE.
+// Tables created before Storm wrote ON UPDATE lack it, and `storm drift` and
+// `storm migrate verify` report those columns; a migration running ALTER
+// TABLE ... MODIFY COLUMN with the column as `storm ddl` prints it adds it.
+func DefaultClause(f ir.IRField, d dialect.Dialect) string {
+	switch {
+	case f.Type.HasDirective(directive.DirUpdatedAt) && d == dialect.MySQL:
+		return "CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"
+	case f.Type.HasDirective(directive.DirDefaultNow) || f.Type.HasDirective(directive.DirCreatedAt) ||
+		f.Type.HasDirective(directive.DirUpdatedAt):
+		return "CURRENT_TIMESTAMP"
+	}
+
+	dir, ok := f.Type.Directive(directive.DirDefault)
+	if !ok || len(dir.Positional()) == 0 {
+		return ""
+	}
+	value := dir.Positional()[0]
+
+	if value.Kind == directive.ValueCall {
+		return defaultFunction(value.Call.Name, d)
+	}
+
+	switch f.Type.Kind {
+	case fld.KindBoolean:
+		truthy := value.Kind == directive.ValueBool && value.Bool
+		if d == dialect.Postgres {
+			return strings.ToUpper(strconv.FormatBool(truthy))
+		}
+		if truthy {
+			return "1"
+		}
+		return "0"
+	case fld.KindInt, fld.KindBigInt, fld.KindFloat, fld.KindDecimal:
+		return value.String()
+	default:
+		return "'" + strings.ReplaceAll(value.String(), "'", "''") + "'"
+	}
+}
+
+// defaultFunction renders a @default function call such as now() for the
+// dialect; the validator reports the calls a dialect has no function for
+func defaultFunction(name string, d dialect.Dialect) string {
+	switch name {
+	case "now":
+		return "CURRENT_TIMESTAMP"
+	case "uuid":
+		switch d {
+		case dialect.Postgres:
+			return "gen_random_uuid()"
+		case dialect.MySQL:
+			return "(UUID())"
+		}
+	}
+	return ""
+}
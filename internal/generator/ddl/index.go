@@ -0,0 +1,64 @@
+package ddl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// createIndexes renders the CREATE INDEX statements of a model's @index fields
+func createIndexes(model ir.IRModel, d dialect.Dialect) []string {
+	var stmts []string
+	for _, f := range model.Fields {
+		if spec, ok := f.Index(); ok {
+			stmts = append(stmts, CreateIndex(model, spec, d))
+		}
+	}
+	return stmts
+}
+
+// CreateIndex renders the CREATE INDEX statement of an @index field. Options
+// the dialect cannot honour are dropped here; the validator reports them
+// before generation.
+func CreateIndex(model ir.IRModel, spec *ir.IndexSpec, d dialect.Dialect) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "CREATE INDEX %s", d.QuoteIdent(spec.IndexName(model.Name)))
+	if method := strings.ToLower(spec.Method); d == dialect.MySQL && (method == "btree" || method == "hash") {
+		fmt.Fprintf(&b, " USING %s", strings.ToUpper(spec.Method))
+	}
+	fmt.Fprintf(&b, " ON %s", d.QuoteIdent(model.Name))
+	if d == dialect.Postgres && spec.Method != "" {
+		fmt.Fprintf(&b, " USING %s", strings.ToUpper(spec.Method))
+	}
+
+	key := d.QuoteIdent(spec.Column)
+	if d == dialect.MySQL && spec.Length > 0 {
+		key += "(" + strconv.Itoa(spec.Length) + ")"
+	}
+	if spec.Desc {
+		key += " DESC"
+	}
+	fmt.Fprintf(&b, " (%s)", key)
+
+	if d == dialect.Postgres && len(spec.Include) > 0 {
+		cols := make([]string, 0, len(spec.Include))
+		for _, name := range spec.Include {
+			col := name
+			if f, ok := model.FindField(name); ok {
+				col = f.ColumnName()
+			}
+			cols = append(cols, d.QuoteIdent(col))
+		}
+		fmt.Fprintf(&b, " INCLUDE (%s)", strings.Join(cols, ", "))
+	}
+
+	if spec.Where != "" && d != dialect.MySQL {
+		fmt.Fprintf(&b, " WHERE %s", spec.Where)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package ddl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// foreignKey is a FOREIGN KEY constraint derived from a @belongsTo field
+type foreignKey struct {
+	Name       string      // Column holding the key
+	Table      string      // Referenced table
+	References string      // Referenced column
+	Column     *ir.IRField // Implicit column to add, nil when the model declares it
+}
+
+// foreignKeys returns the foreign keys of a model's @belongsTo relations
+func foreignKeys(irData *ir.IR, model ir.IRModel) []foreignKey {
+	var fks []foreignKey
+
+	for _, f := range model.Fields {
+		if !f.Type.HasDirective(directive.DirBelongsTo) || !irData.IsRelation(f) {
+			continue
+		}
+		target, _ := irData.FindModel(f.Type.ModelName)
+
+		fk := foreignKey{
+			Name:       ir.ForeignKeyName(f),
+			Table:      target.Name,
+			References: "id",
+		}
+
+		keyType := *fld.NewFieldType(fld.KindInt, "", nil)
+		referenced, ok := target.PrimaryKey()
+		if ref := ir.ForeignKeyReference(f); ref != "" {
+			referenced, ok = target.FindField(ref)
+		}
+		if ok {
+			fk.References = referenced.ColumnName()
+			keyType = *fld.NewFieldType(referenced.Type.Kind, "", nil)
+		}
+
+		if _, ok := model.FindField(fk.Name); !ok {
+			if f.IsNullable() {
+				keyType.Directives = []directive.Directive{{Kind: directive.DirNullable}}
+			}
+			fk.Column = &ir.IRField{Name: fk.Name, Type: keyType}
+		}
+		fk.Name = model.ForeignKeyColumn(f)
+
+		fks = append(fks, fk)
+	}
+
+	return fks
+}
+
+// joinTables returns the implicit join tables of the IR's many-to-many relations,
+// once per relation. Join tables declared with through: are ordinary models.
+func joinTables(irData *ir.IR) []*ir.JoinTable {
+	var tables []*ir.JoinTable
+	seen := make(map[string]bool)
+
+	for _, model := range irData.Models {
+		for _, f := range model.Fields {
+			jt, ok := irData.JoinTable(model, f)
+			if !ok || jt.Through || seen[jt.Name] {
+				continue
+			}
+			seen[jt.Name] = true
+			tables = append(tables, jt)
+		}
+	}
+	return tables
+}
+
+// createJoinTable renders the CREATE TABLE statement of an implicit join table
+func createJoinTable(jt *ir.JoinTable, d dialect.Dialect) string {
+	cols := []ir.JoinColumn{jt.Source, jt.Target}
+	// Render the columns in a stable order whichever side discovered the table
+	if cols[0].Name > cols[1].Name {
+		cols[0], cols[1] = cols[1], cols[0]
+	}
+
+	var lines []string
+	for _, col := range cols {
+		lines = append(lines, fmt.Sprintf("%s %s NOT NULL", d.QuoteIdent(col.Name), col.Type.SQLType(d)))
+	}
+	lines = append(lines, fmt.Sprintf("PRIMARY KEY (%s, %s)", d.QuoteIdent(cols[0].Name), d.QuoteIdent(cols[1].Name)))
+	for _, col := range cols {
+		lines = append(lines, fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s) ON DELETE CASCADE",
+			d.QuoteIdent(col.Name), d.QuoteIdent(col.Table), d.QuoteIdent(col.References)))
+	}
+
+	return fmt.Sprintf("CREATE TABLE %s (\n  %s\n)", d.QuoteIdent(jt.Name), strings.Join(lines, ",\n  "))
+}
@@ -0,0 +1,39 @@
+package ddl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	"github.com/pixperk/storm/internal/types/directive"
+)
+
+// UpdatedAtTriggers returns the PostgreSQL functions and triggers setting the
+// @updatedAt columns of every model on each update, so raw SQL updates keep
+// them current too. MySQL columns have ON UPDATE instead, and other dialects
+// get none.
+func UpdatedAtTriggers(irData *ir.IR, d dialect.Dialect) []string {
+	if d != dialect.Postgres {
+		return nil
+	}
+
+	var stmts []string
+	for _, model := range irData.Models {
+		var sets []string
+		for _, f := range model.Fields {
+			if f.Type.HasDirective(directive.DirUpdatedAt) {
+				sets = append(sets, fmt.Sprintf("  NEW.%s := CURRENT_TIMESTAMP;\n", d.QuoteIdent(f.ColumnName())))
+			}
+		}
+		if len(sets) == 0 {
+			continue
+		}
+
+		name := d.QuoteIdent(model.Name + "_set_updated_at")
+		stmts = append(stmts,
+			fmt.Sprintf("CREATE OR REPLACE FUNCTION %s() RETURNS trigger LANGUAGE plpgsql AS $$\nBEGIN\n%s  RETURN NEW;\nEND\n$$", name, strings.Join(sets, "")),
+			fmt.Sprintf("CREATE TRIGGER %s BEFORE UPDATE ON %s FOR EACH ROW EXECUTE FUNCTION %s()", name, d.QuoteIdent(model.Name), name))
+	}
+	return stmts
+}
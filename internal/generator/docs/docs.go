@@ -0,0 +1,186 @@
+// Package docs renders the Storm IR as a data dictionary in Markdown or HTML.
+package docs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/directive"
+	"github.com/pixperk/storm/internal/types/field"
+)
+
+// dictionary is the content shared by the Markdown and HTML renderings
+type dictionary struct {
+	Driver string
+	Models []modelDoc
+}
+
+type modelDoc struct {
+	Name      string
+	Doc       string
+	Columns   []columnDoc
+	Relations []relationDoc
+}
+
+type columnDoc struct {
+	Field       string
+	Column      string
+	Type        string
+	MySQL       string
+	Postgres    string
+	SQLite      string
+	Constraints []string
+	Default     string
+	Doc         string
+}
+
+type relationDoc struct {
+	Field   string // Field of the documented model, empty when only the other side declares one
+	Model   string // Related model
+	Kind    string
+	Details string // Foreign key or join table implementing the relation
+	Doc     string
+}
+
+// build collects the data dictionary of the IR
+func build(irData *ir.IR) dictionary {
+	dict := dictionary{Driver: irData.DatabaseDriver}
+	relations := irData.Relations()
+
+	for _, model := range irData.Models {
+		doc := modelDoc{Name: model.Name, Doc: model.Doc}
+		columns := make(map[string]int)
+		for _, f := range model.Fields {
+			if irData.IsRelation(f) {
+				continue
+			}
+			columns[f.ColumnName()] = len(doc.Columns)
+			doc.Columns = append(doc.Columns, column(f))
+		}
+
+		for _, r := range relations {
+			if fk := r.ForeignKey; fk != nil && fk.Model == model.Name {
+				i, ok := columns[fk.Column]
+				if !ok {
+					// Implicit key column of a @belongsTo relation
+					columns[fk.Column] = len(doc.Columns)
+					doc.Columns = append(doc.Columns, keyColumn(irData, model, r))
+					i = len(doc.Columns) - 1
+				}
+				doc.Columns[i].Constraints = append(doc.Columns[i].Constraints,
+					fmt.Sprintf("references %s.%s", fk.References, fk.Referenced))
+			}
+		}
+
+		for _, r := range relations {
+			if r.From.Model == model.Name {
+				doc.Relations = append(doc.Relations, relation(model, r, r.From, r.To))
+			}
+			if r.To.Model == model.Name && (r.From.Model != model.Name || r.From.Field != r.To.Field) {
+				doc.Relations = append(doc.Relations, relation(model, r, r.To, r.From))
+			}
+		}
+		dict.Models = append(dict.Models, doc)
+	}
+	return dict
+}
+
+// column describes a scalar field
+func column(f ir.IRField) columnDoc {
+	col := columnDoc{
+		Field:    f.Name,
+		Column:   f.ColumnName(),
+		Type:     f.Type.String(),
+		MySQL:    f.Type.MySQLType(),
+		Postgres: f.Type.PostgresType(),
+		SQLite:   f.Type.SQLiteType(),
+		Doc:      f.Doc,
+	}
+	if f.IsArray {
+		col.Type += "[]"
+		col.MySQL, col.Postgres, col.SQLite = "JSON", col.Postgres+"[]", "TEXT"
+	}
+
+	t := f.Type
+	if t.HasDirective(directive.DirID) {
+		col.Constraints = append(col.Constraints, "primary key")
+	}
+	if t.HasDirective(directive.DirAuto) {
+		col.Constraints = append(col.Constraints, "auto increment")
+	}
+	if t.HasDirective(directive.DirUnique) {
+		col.Constraints = append(col.Constraints, "unique")
+	}
+	if f.IsNullable() {
+		col.Constraints = append(col.Constraints, "nullable")
+	} else if !t.HasDirective(directive.DirID) {
+		col.Constraints = append(col.Constraints, "not null")
+	}
+	for _, d := range t.Directives {
+		switch d.Kind {
+		case directive.DirLength:
+			col.Constraints = append(col.Constraints, "max length "+directive.FormatArgs(d.Args))
+		case directive.DirMin:
+			col.Constraints = append(col.Constraints, "min "+directive.FormatArgs(d.Args))
+		case directive.DirMax:
+			col.Constraints = append(col.Constraints, "max "+directive.FormatArgs(d.Args))
+		case directive.DirPrecision:
+			col.Constraints = append(col.Constraints, "precision "+directive.FormatArgs(d.Args))
+		case directive.DirEnum:
+			col.Constraints = append(col.Constraints, "one of "+strings.Join(d.StringArgs(), ", "))
+		case directive.DirIndex:
+			col.Constraints = append(col.Constraints, "indexed")
+		case directive.DirUpdatedAt:
+			col.Constraints = append(col.Constraints, "set on update")
+		case directive.DirDeletedAt:
+			col.Constraints = append(col.Constraints, "set on soft delete")
+		}
+	}
+
+	switch {
+	case t.HasDirective(directive.DirDefaultNow), t.HasDirective(directive.DirCreatedAt), t.HasDirective(directive.DirUpdatedAt):
+		col.Default = "now()"
+	case t.HasDirective(directive.DirDefault):
+		d, _ := t.Directive(directive.DirDefault)
+		col.Default = directive.FormatArgs(d.Args)
+	}
+	return col
+}
+
+// keyColumn describes the implicit foreign key column of a relation
+func keyColumn(irData *ir.IR, model ir.IRModel, r ir.Relation) columnDoc {
+	key := ir.IRField{Name: r.ForeignKey.Column, Type: *field.NewFieldType(field.KindInt, "", nil)}
+	if target, ok := irData.FindModel(r.ForeignKey.References); ok {
+		if pk, ok := target.FindField(r.ForeignKey.Referenced); ok {
+			key.Type = *field.NewFieldType(pk.Type.Kind, "", nil)
+		}
+	}
+	if f, ok := model.FindField(r.To.Field); ok && f.IsNullable() {
+		key.Type.Directives = []directive.Directive{{Kind: directive.DirNullable}}
+	}
+
+	col := column(key)
+	col.Field = ""
+	return col
+}
+
+// relation describes a relation from the side of one of its ends
+func relation(model ir.IRModel, r ir.Relation, self, other ir.RelationEnd) relationDoc {
+	doc := relationDoc{Field: self.Field, Model: other.Model, Kind: r.Kind.String()}
+	if f, ok := model.FindField(self.Field); ok {
+		doc.Doc = f.Doc
+	}
+	if r.Name != "" {
+		doc.Kind += fmt.Sprintf(" (%s)", r.Name)
+	}
+
+	switch {
+	case r.ForeignKey != nil:
+		fk := r.ForeignKey
+		doc.Details = fmt.Sprintf("%s.%s references %s.%s", fk.Model, fk.Column, fk.References, fk.Referenced)
+	case r.JoinTable != nil:
+		doc.Details = "join table " + r.JoinTable.Name
+	}
+	return doc
+}
@@ -0,0 +1,78 @@
+package docs
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+)
+
+var page = template.Must(template.New("docs").Funcs(template.FuncMap{
+	"anchor": strings.ToLower,
+	"join":   strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Data dictionary</title>
+<style>
+body { font-family: -apple-system, Helvetica, Arial, sans-serif; margin: 0; display: flex; }
+nav { width: 14em; padding: 1em; border-right: 1px solid #ddd; height: 100vh; position: sticky; top: 0; overflow-y: auto; }
+nav ul { list-style: none; padding: 0; }
+main { padding: 1em 2em; flex: 1; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ddd; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+code { font-size: 0.9em; }
+.doc { white-space: pre-line; }
+</style>
+</head>
+<body>
+<nav>
+<h3>Models</h3>
+<ul>
+{{- range .Models}}
+<li><a href="#{{anchor .Name}}">{{.Name}}</a></li>
+{{- end}}
+</ul>
+</nav>
+<main>
+<h1>Data dictionary</h1>
+<p>Database: <code>{{.Driver}}</code></p>
+{{- range .Models}}
+<section id="{{anchor .Name}}">
+<h2>{{.Name}}</h2>
+{{- if .Doc}}
+<p class="doc">{{.Doc}}</p>
+{{- end}}
+<table>
+<tr><th>Field</th><th>Column</th><th>Type</th><th>MySQL</th><th>PostgreSQL</th><th>SQLite</th><th>Constraints</th><th>Default</th><th>Description</th></tr>
+{{- range .Columns}}
+<tr><td>{{.Field}}</td><td><code>{{.Column}}</code></td><td>{{.Type}}</td><td><code>{{.MySQL}}</code></td><td><code>{{.Postgres}}</code></td><td><code>{{.SQLite}}</code></td><td>{{join .Constraints ", "}}</td><td>{{if .Default}}<code>{{.Default}}</code>{{end}}</td><td class="doc">{{.Doc}}</td></tr>
+{{- end}}
+</table>
+{{- if .Relations}}
+<h3>Relations</h3>
+<table>
+<tr><th>Field</th><th>Related model</th><th>Kind</th><th>Implemented by</th><th>Description</th></tr>
+{{- range .Relations}}
+<tr><td>{{.Field}}</td><td><a href="#{{anchor .Model}}">{{.Model}}</a></td><td>{{.Kind}}</td><td>{{.Details}}</td><td class="doc">{{.Doc}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</section>
+{{- end}}
+</main>
+</body>
+</html>
+`))
+
+// HTML renders the data dictionary of the IR as a standalone HTML page
+func HTML(irData *ir.IR) (string, error) {
+	var buf bytes.Buffer
+	if err := page.Execute(&buf, build(irData)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
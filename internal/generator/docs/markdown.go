@@ -0,0 +1,62 @@
+package docs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+)
+
+// Markdown renders the data dictionary of the IR as Markdown
+func Markdown(irData *ir.IR) string {
+	dict := build(irData)
+
+	var b strings.Builder
+	b.WriteString("# Data dictionary\n\n")
+	fmt.Fprintf(&b, "Database: `%s`\n\n", dict.Driver)
+
+	b.WriteString("## Models\n\n")
+	for _, m := range dict.Models {
+		fmt.Fprintf(&b, "- [%s](#%s)\n", m.Name, strings.ToLower(m.Name))
+	}
+
+	for _, m := range dict.Models {
+		fmt.Fprintf(&b, "\n## %s\n\n", m.Name)
+		if m.Doc != "" {
+			b.WriteString(m.Doc)
+			b.WriteString("\n\n")
+		}
+
+		b.WriteString("| Field | Column | Type | MySQL | PostgreSQL | SQLite | Constraints | Default | Description |\n")
+		b.WriteString("|---|---|---|---|---|---|---|---|---|\n")
+		for _, c := range m.Columns {
+			fmt.Fprintf(&b, "| %s | `%s` | %s | `%s` | `%s` | `%s` | %s | %s | %s |\n",
+				cell(c.Field), c.Column, cell(c.Type), c.MySQL, c.Postgres, c.SQLite,
+				cell(strings.Join(c.Constraints, ", ")), code(c.Default), cell(c.Doc))
+		}
+
+		if len(m.Relations) > 0 {
+			b.WriteString("\n### Relations\n\n")
+			b.WriteString("| Field | Related model | Kind | Implemented by | Description |\n")
+			b.WriteString("|---|---|---|---|---|\n")
+			for _, r := range m.Relations {
+				fmt.Fprintf(&b, "| %s | [%s](#%s) | %s | %s | %s |\n",
+					cell(r.Field), r.Model, strings.ToLower(r.Model), cell(r.Kind), cell(r.Details), cell(r.Doc))
+			}
+		}
+	}
+	return b.String()
+}
+
+// cell escapes text for a Markdown table cell
+func cell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+func code(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + cell(s) + "`"
+}
@@ -0,0 +1,76 @@
+package erd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+)
+
+// Dot renders the IR as a Graphviz digraph with crow's-foot arrowheads
+func Dot(irData *ir.IR, opts Options) string {
+	entities, edges := diagram(irData, opts)
+
+	var b strings.Builder
+	b.WriteString("digraph erd {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=plaintext, fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [dir=both, fontname=\"Helvetica\", fontsize=10];\n")
+
+	for _, e := range entities {
+		fmt.Fprintf(&b, "  %q [label=<\n", e.Name)
+		b.WriteString("    <table border=\"0\" cellborder=\"1\" cellspacing=\"0\">\n")
+		fmt.Fprintf(&b, "      <tr><td colspan=\"3\" bgcolor=\"lightgrey\"><b>%s</b></td></tr>\n", html.EscapeString(e.Name))
+		for _, col := range e.Columns {
+			fmt.Fprintf(&b, "      <tr><td align=\"left\">%s</td><td align=\"left\">%s</td><td>%s</td></tr>\n",
+				html.EscapeString(col.Name), html.EscapeString(col.Type), col.Key)
+		}
+		b.WriteString("    </table>\n")
+		b.WriteString("  >];\n")
+	}
+
+	for _, e := range edges {
+		fmt.Fprintf(&b, "  %q -> %q [arrowtail=%s, arrowhead=%s, label=%q];\n",
+			e.From, e.To, dotArrow(e.FromMin, e.FromMax), dotArrow(e.ToMin, e.ToMax), e.Label)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotArrow returns the Graphviz arrow shape drawing a crow's-foot cardinality
+func dotArrow(min, max cardinality) string {
+	switch {
+	case max == many && min == zero:
+		return "crowodot"
+	case max == many:
+		return "crowtee"
+	case min == zero:
+		return "teeodot"
+	default:
+		return "teetee"
+	}
+}
+
+// SVG renders DOT text to SVG with the Graphviz dot executable
+func SVG(ctx context.Context, dot string) ([]byte, error) {
+	path, err := exec.LookPath("dot")
+	if err != nil {
+		return nil, fmt.Errorf("svg output needs Graphviz: %w", err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "-Tsvg")
+	cmd.Stdin = strings.NewReader(dot)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("run dot: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
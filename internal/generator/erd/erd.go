@@ -0,0 +1,179 @@
+// Package erd renders the Storm IR as entity-relationship diagrams.
+package erd
+
+import (
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/directive"
+)
+
+// Options limits a diagram to part of the schema
+type Options struct {
+	Models []string // Models to start from; every model when empty
+	Depth  int      // Relation hops to follow from Models; 0 keeps only Models
+}
+
+// column is an attribute of an entity
+type column struct {
+	Name string
+	Type string
+	Key  string // "PK", "FK", "UK" or ""
+}
+
+// entity is a model with the columns of its table
+type entity struct {
+	Name    string
+	Columns []column
+}
+
+// edge is a relation with the cardinality of both of its ends
+type edge struct {
+	From, To         string
+	FromMin, FromMax cardinality // How many From rows one To row relates to
+	ToMin, ToMax     cardinality // How many To rows one From row relates to
+	Label            string
+}
+
+type cardinality int
+
+const (
+	zero cardinality = iota
+	one
+	many
+)
+
+// diagram collects the entities and edges selected by the options
+func diagram(irData *ir.IR, opts Options) ([]entity, []edge) {
+	relations := irData.Relations()
+	selected := selectModels(irData, relations, opts)
+
+	var entities []entity
+	for _, model := range irData.Models {
+		if selected[model.Name] {
+			entities = append(entities, entity{Name: model.Name, Columns: columns(irData, model, relations)})
+		}
+	}
+
+	var edges []edge
+	for _, r := range relations {
+		if selected[r.From.Model] && selected[r.To.Model] {
+			edges = append(edges, relationEdge(irData, r))
+		}
+	}
+	return entities, edges
+}
+
+// selectModels returns the models within opts.Depth relation hops of opts.Models
+func selectModels(irData *ir.IR, relations []ir.Relation, opts Options) map[string]bool {
+	selected := make(map[string]bool)
+	if len(opts.Models) == 0 {
+		for _, model := range irData.Models {
+			selected[model.Name] = true
+		}
+		return selected
+	}
+
+	frontier := opts.Models
+	for _, name := range frontier {
+		selected[name] = true
+	}
+	for depth := 0; depth < opts.Depth && len(frontier) > 0; depth++ {
+		var next []string
+		for _, name := range frontier {
+			for _, r := range relations {
+				for _, pair := range [][2]string{{r.From.Model, r.To.Model}, {r.To.Model, r.From.Model}} {
+					if pair[0] == name && !selected[pair[1]] {
+						selected[pair[1]] = true
+						next = append(next, pair[1])
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+	return selected
+}
+
+// columns returns the columns of a model's table, including the implicit
+// foreign key columns of its @belongsTo relations
+func columns(irData *ir.IR, model ir.IRModel, relations []ir.Relation) []column {
+	fkColumns := make(map[string]string) // column -> type
+	for _, r := range relations {
+		if fk := r.ForeignKey; fk != nil && fk.Model == model.Name {
+			fkColumns[fk.Column] = keyType(irData, fk)
+		}
+	}
+
+	var cols []column
+	declared := make(map[string]bool)
+	for _, f := range model.Fields {
+		if irData.IsRelation(f) {
+			continue
+		}
+		col := column{Name: f.ColumnName(), Type: f.Type.String()}
+		if f.IsArray {
+			col.Type += "[]"
+		}
+		switch {
+		case f.Type.HasDirective(directive.DirID):
+			col.Key = "PK"
+		case fkColumns[col.Name] != "":
+			col.Key = "FK"
+		case f.Type.HasDirective(directive.DirUnique):
+			col.Key = "UK"
+		}
+		declared[col.Name] = true
+		cols = append(cols, col)
+	}
+
+	// Implicit key columns, in the order of the relation fields
+	for _, f := range model.Fields {
+		name := model.ForeignKeyColumn(f)
+		if typ, ok := fkColumns[name]; ok && !declared[name] && f.Type.HasDirective(directive.DirBelongsTo) {
+			declared[name] = true
+			cols = append(cols, column{Name: name, Type: typ, Key: "FK"})
+		}
+	}
+	return cols
+}
+
+// keyType returns the type of the column a foreign key references
+func keyType(irData *ir.IR, fk *ir.ForeignKey) string {
+	if target, ok := irData.FindModel(fk.References); ok {
+		for _, f := range target.Fields {
+			if f.ColumnName() == fk.Referenced {
+				return f.Type.String()
+			}
+		}
+	}
+	return "Int"
+}
+
+// relationEdge derives the crow's-foot cardinalities of a relation
+func relationEdge(irData *ir.IR, r ir.Relation) edge {
+	e := edge{From: r.From.Model, To: r.To.Model, Label: r.Name}
+	if e.Label == "" {
+		e.Label = r.From.Field
+		if e.Label == "" {
+			e.Label = r.To.Field
+		}
+	}
+
+	switch r.Kind {
+	case ir.ManyToMany:
+		e.FromMin, e.FromMax, e.ToMin, e.ToMax = zero, many, zero, many
+		return e
+	case ir.OneToOne:
+		e.ToMin, e.ToMax = zero, one
+	default:
+		e.ToMin, e.ToMax = zero, many
+	}
+
+	// The child row needs a parent unless its @belongsTo is nullable
+	e.FromMin, e.FromMax = one, one
+	if child, ok := irData.FindModel(r.To.Model); ok && r.To.Field != "" {
+		if f, ok := child.FindField(r.To.Field); ok && f.IsNullable() {
+			e.FromMin = zero
+		}
+	}
+	return e
+}
@@ -0,0 +1,68 @@
+package erd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+)
+
+// Mermaid renders the IR as a Mermaid erDiagram
+func Mermaid(irData *ir.IR, opts Options) string {
+	entities, edges := diagram(irData, opts)
+
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+	for _, e := range entities {
+		fmt.Fprintf(&b, "  %s {\n", e.Name)
+		for _, col := range e.Columns {
+			fmt.Fprintf(&b, "    %s %s", mermaidType(col.Type), col.Name)
+			if col.Key != "" {
+				fmt.Fprintf(&b, " %s", col.Key)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("  }\n")
+	}
+	for _, e := range edges {
+		fmt.Fprintf(&b, "  %s %s--%s %s : %q\n", e.From,
+			mermaidLeft(e.FromMin, e.FromMax), mermaidRight(e.ToMin, e.ToMax), e.To, e.Label)
+	}
+	return b.String()
+}
+
+// mermaidType spells array types the way Mermaid accepts them
+func mermaidType(typ string) string {
+	if base, ok := strings.CutSuffix(typ, "[]"); ok {
+		return base + "_array"
+	}
+	return typ
+}
+
+// mermaidLeft returns the crow's-foot marker on the left end of an edge
+func mermaidLeft(min, max cardinality) string {
+	switch {
+	case max == many && min == zero:
+		return "}o"
+	case max == many:
+		return "}|"
+	case min == zero:
+		return "|o"
+	default:
+		return "||"
+	}
+}
+
+// mermaidRight returns the crow's-foot marker on the right end of an edge
+func mermaidRight(min, max cardinality) string {
+	switch {
+	case max == many && min == zero:
+		return "o{"
+	case max == many:
+		return "|{"
+	case min == zero:
+		return "o|"
+	default:
+		return "||"
+	}
+}
@@ -0,0 +1,88 @@
+// Package external runs third-party generator plugins declared with generator blocks.
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/plugin"
+)
+
+// Run invokes the provider of a generator block and returns the files it produced
+func Run(ctx context.Context, gen ir.IRGenerator, irData *ir.IR) ([]plugin.File, error) {
+	block, err := json.Marshal(gen)
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	doc, err := json.Marshal(ir.ToDocument(irData))
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	req, err := json.Marshal(plugin.Request{
+		ProtocolVersion: plugin.ProtocolVersion,
+		Generator:       block,
+		Schema:          doc,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, gen.Provider)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("generator %s: run %s: %w", gen.Name, gen.Provider, err)
+	}
+
+	var resp plugin.Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("generator %s: decode response: %w", gen.Name, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("generator %s: %s", gen.Name, resp.Error)
+	}
+
+	for _, f := range resp.Files {
+		if err := checkName(f.Name); err != nil {
+			return nil, fmt.Errorf("generator %s: %w", gen.Name, err)
+		}
+	}
+	return resp.Files, nil
+}
+
+// checkName rejects file names that would escape the output directory
+func checkName(name string) error {
+	if name == "" {
+		return errors.New("generated file has no name")
+	}
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("generated file %s is outside the output directory", name)
+	}
+	return nil
+}
+
+// Write writes the generated files below dir
+func Write(dir string, files []plugin.File) error {
+	for _, f := range files {
+		path := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(f.Content), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
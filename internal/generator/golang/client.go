@@ -0,0 +1,239 @@
+package golang
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// clientSource holds the database abstraction and query helpers used by the
+// generated client methods
+const clientSource = `// DBTX is satisfied by *sql.DB, *sql.Tx and *sql.Conn.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// deletedScope selects which rows of a model with a soft delete field a query sees.
+type deletedScope int
+
+const (
+	excludeDeleted deletedScope = iota
+	includeDeleted
+	onlyDeleted
+)
+
+// selectQuery assembles a SELECT from its clauses.
+func selectQuery(selectFrom string, where, orderBy []string, limit int) string {
+	var b strings.Builder
+	b.WriteString(selectFrom)
+	if len(where) > 0 {
+		b.WriteString(" WHERE (" + strings.Join(where, ") AND (") + ")")
+	}
+	if len(orderBy) > 0 {
+		b.WriteString(" ORDER BY " + strings.Join(orderBy, ", "))
+	}
+	if limit > 0 {
+		b.WriteString(" LIMIT " + strconv.Itoa(limit))
+	}
+	return bind(b.String())
+}
+`
+
+// bindSource rewrites the ? placeholders of query conditions for PostgreSQL
+const bindSource = `// bind numbers the ? placeholders of a query, skipping quoted text.
+func bind(query string) string {
+	var b strings.Builder
+	n := 0
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+`
+
+// plainBindSource keeps the ? placeholders of MySQL and SQLite
+const plainBindSource = `// bind returns the query as is: the database takes ? placeholders.
+func bind(query string) string {
+	return query
+}
+`
+
+// jsonColumnSource scans and writes the scalar lists MySQL and SQLite keep as JSON
+const jsonColumnSource = `// jsonColumn scans and writes a scalar list column holding JSON.
+type jsonColumn struct{ dest any }
+
+func (c jsonColumn) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), c.dest)
+	case []byte:
+		return json.Unmarshal(v, c.dest)
+	}
+	return fmt.Errorf("cannot scan %T into a list", src)
+}
+
+func (c jsonColumn) Value() (driver.Value, error) {
+	data, err := json.Marshal(c.dest)
+	if err != nil || string(data) == "null" {
+		return "[]", err
+	}
+	return string(data), nil
+}
+`
+
+// timeColumnSource scans the times SQLite keeps as text
+const timeColumnSource = `// timeLayouts are the forms of the times SQLite stores as text.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+	"15:04:05.999999999",
+}
+
+// parseTime parses a time in one of the timeLayouts.
+func parseTime(text string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse time %q", text)
+}
+
+// timeColumn scans a time column into a *time.Time or a **time.Time.
+type timeColumn struct{ dest any }
+
+func (c timeColumn) Scan(src any) error {
+	var t time.Time
+	switch v := src.(type) {
+	case nil:
+		if p, ok := c.dest.(**time.Time); ok {
+			*p = nil
+			return nil
+		}
+		return fmt.Errorf("cannot scan NULL into time.Time")
+	case time.Time:
+		t = v
+	case string:
+		return c.Scan([]byte(v))
+	case []byte:
+		var err error
+		if t, err = parseTime(string(v)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("cannot scan %T into time.Time", src)
+	}
+
+	switch p := c.dest.(type) {
+	case *time.Time:
+		*p = t
+	case **time.Time:
+		*p = &t
+	}
+	return nil
+}
+`
+
+// clientFile returns the imports and source of client.go for the dialect
+func clientFile(d dialect.Dialect) (map[string]bool, string) {
+	imports := map[string]bool{
+		"context":      true,
+		"database/sql": true,
+		"strconv":      true,
+		"strings":      true,
+	}
+	source := clientSource
+	if d == dialect.Postgres {
+		return imports, source + "\n" + bindSource
+	}
+
+	imports["database/sql/driver"] = true
+	imports["encoding/json"] = true
+	imports["fmt"] = true
+	source += "\n" + plainBindSource + "\n" + jsonColumnSource
+	if d == dialect.SQLite {
+		imports["time"] = true
+		source += "\n" + timeColumnSource
+	}
+	return imports, source
+}
+
+// placeholder returns the n-th (1-based) bind parameter of the dialect
+func placeholder(d dialect.Dialect, n int) string {
+	if d == dialect.Postgres {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// writeJoinMethods emits Connect/Disconnect methods for the many-to-many fields of a model
+func writeJoinMethods(buf *bytes.Buffer, irData *ir.IR, model ir.IRModel, d dialect.Dialect, imports map[string]bool) {
+	sourcePK, ok := model.PrimaryKey()
+	if !ok {
+		return
+	}
+
+	for _, f := range model.Fields {
+		jt, ok := irData.JoinTable(model, f)
+		if !ok {
+			continue
+		}
+		target, _ := irData.FindModel(f.Type.ModelName)
+		targetPK, ok := target.PrimaryKey()
+		if !ok {
+			continue
+		}
+		imports["context"] = true
+
+		modelName := GoName(model.Name)
+		method := GoName(f.Name)
+		table := d.QuoteIdent(jt.Name)
+		source := d.QuoteIdent(jt.Source.Name)
+		dest := d.QuoteIdent(jt.Target.Name)
+
+		insert := fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (%s, %s)",
+			table, source, dest, placeholder(d, 1), placeholder(d, 2))
+		remove := fmt.Sprintf("DELETE FROM %s WHERE %s = %s AND %s = %s",
+			table, source, placeholder(d, 1), dest, placeholder(d, 2))
+
+		fmt.Fprintf(buf, "// Connect%s links related %s rows to the %s through %s.\n", method, target.Name, modelName, jt.Name)
+		writeJoinMethod(buf, modelName, "Connect"+method, GoName(target.Name), insert, GoName(sourcePK.Name), GoName(targetPK.Name))
+
+		fmt.Fprintf(buf, "// Disconnect%s unlinks related %s rows from the %s.\n", method, target.Name, modelName)
+		writeJoinMethod(buf, modelName, "Disconnect"+method, GoName(target.Name), remove, GoName(sourcePK.Name), GoName(targetPK.Name))
+	}
+}
+
+func writeJoinMethod(buf *bytes.Buffer, modelName, method, targetName, query, sourceKey, targetKey string) {
+	fmt.Fprintf(buf, "func (m *%s) %s(ctx context.Context, db DBTX, related ...*%s) error {\n", modelName, method, targetName)
+	fmt.Fprintln(buf, "\tfor _, r := range related {")
+	fmt.Fprintf(buf, "\t\tif _, err := db.ExecContext(ctx, %q, m.%s, r.%s); err != nil {\n", query, sourceKey, targetKey)
+	fmt.Fprintln(buf, "\t\t\treturn err")
+	fmt.Fprintln(buf, "\t\t}")
+	fmt.Fprintln(buf, "\t}")
+	fmt.Fprintln(buf, "\treturn nil")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+}
@@ -0,0 +1,217 @@
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	"github.com/pixperk/storm/internal/types/directive"
+)
+
+// writtenColumn is a column Create and Update write, with the Go expression
+// of its value
+type writtenColumn struct {
+	name  string
+	value string
+	field ir.IRField
+}
+
+// implicitKey is the key column of a @belongsTo field the model does not
+// declare a scalar field for. The generated struct holds it in a field of its own.
+type implicitKey struct {
+	relation   ir.IRField
+	column     string
+	referenced ir.IRField
+}
+
+// goName returns the name of the struct field holding the key
+func (k implicitKey) goName() string {
+	return GoName(k.column)
+}
+
+// implicitKeys returns the implicit key columns of the @belongsTo fields of a model
+func implicitKeys(irData *ir.IR, model ir.IRModel) []implicitKey {
+	var keys []implicitKey
+	for _, f := range model.Fields {
+		if !f.Type.HasDirective(directive.DirBelongsTo) || !irData.IsRelation(f) {
+			continue
+		}
+		if _, declared := model.FindField(ir.ForeignKeyName(f)); declared {
+			continue
+		}
+		target, _ := irData.FindModel(f.Type.ModelName)
+		referenced, ok := target.PrimaryKey()
+		if ref := ir.ForeignKeyReference(f); ref != "" {
+			referenced, ok = target.FindField(ref)
+		}
+		if ok {
+			keys = append(keys, implicitKey{relation: f, column: model.ForeignKeyColumn(f), referenced: referenced})
+		}
+	}
+	return keys
+}
+
+// writtenColumns returns the columns of a model its Create and Update
+// methods write: the scalar fields other than an @auto key and @deletedAt,
+// then the implicit key columns of @belongsTo fields. It also returns the
+// statements filling unset keys from the related structs.
+func writtenColumns(irData *ir.IR, model ir.IRModel, d dialect.Dialect, imports map[string]bool) ([]writtenColumn, []string) {
+	var columns []writtenColumn
+	for _, f := range scalarFields(irData, model) {
+		t := f.Type
+		if t.HasDirective(directive.DirID) && t.HasDirective(directive.DirAuto) || t.HasDirective(directive.DirDeletedAt) {
+			continue
+		}
+		value := "m." + GoName(f.Name)
+		switch {
+		case f.IsArray && d == dialect.Postgres:
+			imports["github.com/lib/pq"] = true
+			value = "pq.Array(" + value + ")"
+		case f.IsArray:
+			value = "jsonColumn{&" + value + "}"
+		}
+		columns = append(columns, writtenColumn{name: f.ColumnName(), value: value, field: f})
+	}
+
+	var fills []string
+	for _, key := range implicitKeys(irData, model) {
+		field := "m." + key.goName()
+		related := "m." + GoName(key.relation.Name)
+		value := related + "." + GoName(key.referenced.Name)
+		if !key.referenced.IsNullable() {
+			value = "&" + value
+		}
+		fills = append(fills, fmt.Sprintf("if %s == nil && %s != nil {\n\t\t%s = %s\n\t}", field, related, field, value))
+		columns = append(columns, writtenColumn{name: key.column, value: field})
+	}
+	return columns, fills
+}
+
+// timestamps returns the statements setting the @createdAt or @updatedAt
+// fields of a model to now, and @defaultNow fields still unset on create
+func timestamps(model ir.IRModel, create bool) []string {
+	var stmts []string
+	for _, f := range model.Fields {
+		t := f.Type
+		field := "m." + GoName(f.Name)
+		switch {
+		case t.HasDirective(directive.DirUpdatedAt), create && t.HasDirective(directive.DirCreatedAt):
+			if f.IsNullable() {
+				stmts = append(stmts, field+" = &now")
+			} else {
+				stmts = append(stmts, field+" = now")
+			}
+		case create && t.HasDirective(directive.DirDefaultNow):
+			if f.IsNullable() {
+				stmts = append(stmts, fmt.Sprintf("if %s == nil {\n\t\t%s = &now\n\t}", field, field))
+			} else {
+				stmts = append(stmts, fmt.Sprintf("if %s.IsZero() {\n\t\t%s = now\n\t}", field, field))
+			}
+		}
+	}
+	return stmts
+}
+
+// writeCreateMethods emits Create and, for models with a primary key,
+// Update. Create sets the @createdAt and @updatedAt fields and Update the
+// @updatedAt ones, so the struct holds what the row does. Implicit keys of
+// @belongsTo fields left nil are taken from the related structs.
+func writeCreateMethods(buf *bytes.Buffer, irData *ir.IR, model ir.IRModel, d dialect.Dialect, imports map[string]bool) {
+	columns, fills := writtenColumns(irData, model, d, imports)
+	if len(columns) == 0 {
+		return
+	}
+	imports["context"] = true
+
+	name := GoName(model.Name)
+	table := d.QuoteIdent(model.Name)
+	pk, hasKey := model.PrimaryKey()
+	auto := hasKey && pk.Type.HasDirective(directive.DirAuto)
+
+	names := make([]string, len(columns))
+	values := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = d.QuoteIdent(col.name)
+		values[i] = col.value
+		placeholders[i] = placeholder(d, i+1)
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(names, ", "), strings.Join(placeholders, ", "))
+
+	preamble := func(stmts []string) {
+		if len(stmts) > 0 {
+			imports["time"] = true
+			fmt.Fprintln(buf, "\tnow := time.Now().UTC()")
+		}
+		for _, stmt := range stmts {
+			fmt.Fprintf(buf, "\t%s\n", stmt)
+		}
+		for _, fill := range fills {
+			fmt.Fprintf(buf, "\t%s\n", fill)
+		}
+	}
+
+	created := timestamps(model, true)
+	fmt.Fprintf(buf, "// Create inserts the %s row", model.Name)
+	if auto {
+		fmt.Fprintf(buf, " and sets %s to its generated key", GoName(pk.Name))
+	}
+	fmt.Fprintln(buf, ". Every column is")
+	if len(created) > 0 {
+		fmt.Fprintln(buf, "// written, zero values included, with the creation and update times set to now.")
+	} else {
+		fmt.Fprintln(buf, "// written, zero values included.")
+	}
+	fmt.Fprintf(buf, "func (m *%s) Create(ctx context.Context, db DBTX) error {\n", name)
+	preamble(created)
+	switch {
+	case auto && d == dialect.Postgres:
+		fmt.Fprintf(buf, "\treturn db.QueryRowContext(ctx, %q, %s).Scan(&m.%s)\n",
+			insert+" RETURNING "+d.QuoteIdent(pk.ColumnName()), strings.Join(values, ", "), GoName(pk.Name))
+	case auto:
+		fmt.Fprintf(buf, "\tresult, err := db.ExecContext(ctx, %q, %s)\n", insert, strings.Join(values, ", "))
+		fmt.Fprintln(buf, "\tif err != nil {\n\t\treturn err\n\t}")
+		fmt.Fprintln(buf, "\tid, err := result.LastInsertId()")
+		fmt.Fprintln(buf, "\tif err != nil {\n\t\treturn err\n\t}")
+		fmt.Fprintf(buf, "\tm.%s = %s(id)\n", GoName(pk.Name), pk.Type.GoType())
+		fmt.Fprintln(buf, "\treturn nil")
+	default:
+		fmt.Fprintf(buf, "\t_, err := db.ExecContext(ctx, %q, %s)\n", insert, strings.Join(values, ", "))
+		fmt.Fprintln(buf, "\treturn err")
+	}
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+
+	if !hasKey {
+		return
+	}
+	var sets, args []string
+	for _, col := range columns {
+		if col.name == pk.ColumnName() || col.field.Type.HasDirective(directive.DirCreatedAt) {
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%s = %s", d.QuoteIdent(col.name), placeholder(d, len(sets)+1)))
+		args = append(args, col.value)
+	}
+	if len(sets) == 0 {
+		return
+	}
+	args = append(args, "m."+GoName(pk.Name))
+	update := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s", table, strings.Join(sets, ", "), d.QuoteIdent(pk.ColumnName()), placeholder(d, len(args)))
+
+	updated := timestamps(model, false)
+	if len(updated) > 0 {
+		fmt.Fprintf(buf, "// Update writes every column of the %s row but its key and creation time,\n", model.Name)
+		fmt.Fprintln(buf, "// with the update time set to now.")
+	} else {
+		fmt.Fprintf(buf, "// Update writes every column of the %s row but its key.\n", model.Name)
+	}
+	fmt.Fprintf(buf, "func (m *%s) Update(ctx context.Context, db DBTX) error {\n", name)
+	preamble(updated)
+	fmt.Fprintf(buf, "\t_, err := db.ExecContext(ctx, %q, %s)\n", update, strings.Join(args, ", "))
+	fmt.Fprintln(buf, "\treturn err")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+}
@@ -0,0 +1,153 @@
+// Package golang generates Go model structs from the Storm IR.
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// Options configures the generated Go code
+type Options struct {
+	Package string          // Package name of the generated files, defaults to "models"
+	Dialect dialect.Dialect // SQL dialect of the queries in generated client methods
+}
+
+// File is a single generated source file
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// Generate produces the Go source files for every model in the IR
+func Generate(irData *ir.IR, opts Options) ([]File, error) {
+	if opts.Package == "" {
+		opts.Package = "models"
+	}
+
+	imports := make(map[string]bool)
+	body := new(bytes.Buffer)
+
+	for _, model := range irData.Models {
+		writeModel(body, irData, model, imports)
+		writeValidate(body, irData, model, imports)
+		writeJoinMethods(body, irData, model, opts.Dialect, imports)
+		writeQuery(body, irData, model, opts.Dialect, imports)
+		writeCreateMethods(body, irData, model, opts.Dialect, imports)
+		writeDeleteMethods(body, model, opts.Dialect, imports)
+	}
+
+	models, err := render(opts.Package, imports, body.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("models.go: %w", err)
+	}
+
+	validation, err := render(opts.Package, validationImports, []byte(validationSource))
+	if err != nil {
+		return nil, fmt.Errorf("validation.go: %w", err)
+	}
+
+	clientImports, clientSource := clientFile(opts.Dialect)
+	client, err := render(opts.Package, clientImports, []byte(clientSource))
+	if err != nil {
+		return nil, fmt.Errorf("client.go: %w", err)
+	}
+
+	return []File{
+		{Name: "models.go", Content: models},
+		{Name: "validation.go", Content: validation},
+		{Name: "client.go", Content: client},
+	}, nil
+}
+
+// render assembles the file header and body, then gofmts the result
+func render(pkg string, imports map[string]bool, body []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	fmt.Fprintln(buf, "// Code generated by storm. DO NOT EDIT.")
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "package %s\n\n", pkg)
+
+	if len(imports) > 0 {
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		fmt.Fprintln(buf, "import (")
+		for _, path := range paths {
+			fmt.Fprintf(buf, "\t%q\n", path)
+		}
+		fmt.Fprintln(buf, ")")
+		fmt.Fprintln(buf)
+	}
+
+	buf.Write(body)
+	return format.Source(buf.Bytes())
+}
+
+// writeModel emits the struct declaration for a model
+func writeModel(buf *bytes.Buffer, irData *ir.IR, model ir.IRModel, imports map[string]bool) {
+	fmt.Fprintf(buf, "// %s is the generated model for the %s table.\n", GoName(model.Name), model.Name)
+	fmt.Fprintf(buf, "type %s struct {\n", GoName(model.Name))
+
+	for _, f := range model.Fields {
+		goType := fieldGoType(irData, f, imports)
+
+		tag := fmt.Sprintf("`db:%q json:%q`", f.ColumnName(), f.Name)
+		if f.Type.Kind == fld.KindDecimal && !f.IsArray {
+			// Decimals are text in JSON, as in the other generated clients
+			tag = fmt.Sprintf("`db:%q json:%q`", f.ColumnName(), f.Name+",string")
+		}
+		if irData.IsRelation(f) {
+			tag = fmt.Sprintf("`db:\"-\" json:%q`", f.Name+",omitempty")
+		}
+
+		fmt.Fprintf(buf, "\t%s %s %s\n", GoName(f.Name), goType, tag)
+	}
+
+	// Keys of @belongsTo fields without a declared field, nil when unset
+	for _, key := range implicitKeys(irData, model) {
+		goType := fieldGoType(irData, key.referenced, imports)
+		if !strings.HasPrefix(goType, "*") {
+			goType = "*" + goType
+		}
+		tag := fmt.Sprintf("`db:%q json:%q`", key.column, key.column+",omitempty")
+		fmt.Fprintf(buf, "\t%s %s %s\n", key.goName(), goType, tag)
+	}
+
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+}
+
+// fieldGoType returns the Go type of a struct field, recording the imports it needs
+func fieldGoType(irData *ir.IR, f ir.IRField, imports map[string]bool) string {
+	if irData.IsRelation(f) {
+		if f.IsArray {
+			return "[]*" + GoName(f.Type.ModelName)
+		}
+		return "*" + GoName(f.Type.ModelName)
+	}
+
+	goType := f.Type.GoType()
+	switch f.Type.Kind {
+	case fld.KindDateTime, fld.KindDate, fld.KindTime, fld.KindTimestamp:
+		imports["time"] = true
+	case fld.KindJSON:
+		imports["encoding/json"] = true
+	}
+
+	if f.IsArray {
+		return "[]" + goType
+	}
+	if f.IsNullable() {
+		return "*" + goType
+	}
+	return goType
+}
@@ -0,0 +1,60 @@
+package golang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// commonInitialisms are words rendered fully upper-case in Go identifiers
+var commonInitialisms = map[string]bool{
+	"ID":   true,
+	"URL":  true,
+	"URI":  true,
+	"UUID": true,
+	"CUID": true,
+	"API":  true,
+	"HTTP": true,
+	"JSON": true,
+	"SQL":  true,
+	"IP":   true,
+}
+
+// splitWords splits a schema identifier on underscores and lower-to-upper case transitions
+func splitWords(s string) []string {
+	var words []string
+	var current []rune
+
+	for _, r := range s {
+		switch {
+		case r == '_':
+			if len(current) > 0 {
+				words = append(words, string(current))
+				current = nil
+			}
+		case unicode.IsUpper(r) && len(current) > 0 && !unicode.IsUpper(current[len(current)-1]):
+			words = append(words, string(current))
+			current = []rune{r}
+		default:
+			current = append(current, r)
+		}
+	}
+	if len(current) > 0 {
+		words = append(words, string(current))
+	}
+	return words
+}
+
+// GoName converts a schema identifier such as "userId" or "created_at" into an exported Go name
+func GoName(s string) string {
+	var b strings.Builder
+	for _, word := range splitWords(s) {
+		if upper := strings.ToUpper(word); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	return b.String()
+}
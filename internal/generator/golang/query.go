@@ -0,0 +1,245 @@
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// deletedAtField returns the field of a model marked with @deletedAt
+func deletedAtField(model ir.IRModel) (ir.IRField, bool) {
+	for _, f := range model.Fields {
+		if f.Type.HasDirective(directive.DirDeletedAt) {
+			return f, true
+		}
+	}
+	return ir.IRField{}, false
+}
+
+// scalarFields returns the fields of a model stored in its own columns
+func scalarFields(irData *ir.IR, model ir.IRModel) []ir.IRField {
+	var fields []ir.IRField
+	for _, f := range model.Fields {
+		if !irData.IsRelation(f) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// scanTarget returns the Scan destination of a struct field of m, wrapping
+// the columns database/sql cannot scan on its own
+func scanTarget(f ir.IRField, d dialect.Dialect, imports map[string]bool) string {
+	target := "&m." + GoName(f.Name)
+	switch {
+	case f.IsArray && d == dialect.Postgres:
+		imports["github.com/lib/pq"] = true
+		return "pq.Array(" + target + ")"
+	case f.IsArray:
+		// MySQL and SQLite keep scalar lists as JSON
+		return "jsonColumn{" + target + "}"
+	case d == dialect.SQLite && isTimeKind(f.Type.Kind):
+		return "timeColumn{" + target + "}"
+	}
+	return target
+}
+
+func isTimeKind(kind fld.FieldKind) bool {
+	return kind == fld.KindDateTime || kind == fld.KindDate || kind == fld.KindTime || kind == fld.KindTimestamp
+}
+
+// writeQuery emits the query builder of a model. On models with a @deletedAt
+// field it leaves soft deleted rows out unless told otherwise.
+func writeQuery(buf *bytes.Buffer, irData *ir.IR, model ir.IRModel, d dialect.Dialect, imports map[string]bool) {
+	fields := scalarFields(irData, model)
+	if len(fields) == 0 {
+		return
+	}
+	imports["context"] = true
+	imports["database/sql"] = true
+
+	name := GoName(model.Name)
+	query := name + "Query"
+	deletedAt, soft := deletedAtField(model)
+
+	columns := make([]string, len(fields))
+	targets := make([]string, len(fields))
+	for i, f := range fields {
+		columns[i] = d.QuoteIdent(f.ColumnName())
+		targets[i] = scanTarget(f, d, imports)
+	}
+	for _, key := range implicitKeys(irData, model) {
+		columns = append(columns, d.QuoteIdent(key.column))
+		target := "&m." + key.goName()
+		if d == dialect.SQLite && isTimeKind(key.referenced.Type.Kind) {
+			target = "timeColumn{" + target + "}"
+		}
+		targets = append(targets, target)
+	}
+	table := d.QuoteIdent(model.Name)
+
+	if soft {
+		fmt.Fprintf(buf, "// %s selects rows of the %s table. Rows soft deleted through %s are\n", query, model.Name, deletedAt.Name)
+		fmt.Fprintln(buf, "// left out unless WithDeleted or OnlyDeleted is called.")
+	} else {
+		fmt.Fprintf(buf, "// %s selects rows of the %s table.\n", query, model.Name)
+	}
+	fmt.Fprintf(buf, "type %s struct {\n", query)
+	fmt.Fprintln(buf, "\tdb      DBTX")
+	fmt.Fprintln(buf, "\twhere   []string")
+	fmt.Fprintln(buf, "\targs    []any")
+	fmt.Fprintln(buf, "\torderBy []string")
+	fmt.Fprintln(buf, "\tlimit   int")
+	if soft {
+		fmt.Fprintln(buf, "\tdeleted deletedScope")
+	}
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+
+	fmt.Fprintf(buf, "// Query%s starts a query on the %s table.\n", name, model.Name)
+	fmt.Fprintf(buf, "func Query%s(db DBTX) *%s {\n\treturn &%s{db: db}\n}\n\n", name, query, query)
+
+	fmt.Fprintln(buf, "// Where adds a condition on the rows, with ? placeholders for its arguments.")
+	fmt.Fprintf(buf, "func (q *%s) Where(cond string, args ...any) *%s {\n", query, query)
+	fmt.Fprintln(buf, "\tq.where = append(q.where, cond)")
+	fmt.Fprintln(buf, "\tq.args = append(q.args, args...)")
+	fmt.Fprintln(buf, "\treturn q")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+
+	fmt.Fprintln(buf, "// OrderBy sorts the rows by the expressions, such as \"name DESC\".")
+	fmt.Fprintf(buf, "func (q *%s) OrderBy(exprs ...string) *%s {\n", query, query)
+	fmt.Fprintln(buf, "\tq.orderBy = append(q.orderBy, exprs...)")
+	fmt.Fprintln(buf, "\treturn q")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+
+	fmt.Fprintln(buf, "// Limit returns at most n rows.")
+	fmt.Fprintf(buf, "func (q *%s) Limit(n int) *%s {\n", query, query)
+	fmt.Fprintln(buf, "\tq.limit = n")
+	fmt.Fprintln(buf, "\treturn q")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+
+	if soft {
+		fmt.Fprintln(buf, "// WithDeleted includes soft deleted rows.")
+		fmt.Fprintf(buf, "func (q *%s) WithDeleted() *%s {\n\tq.deleted = includeDeleted\n\treturn q\n}\n\n", query, query)
+		fmt.Fprintln(buf, "// OnlyDeleted selects soft deleted rows only.")
+		fmt.Fprintf(buf, "func (q *%s) OnlyDeleted() *%s {\n\tq.deleted = onlyDeleted\n\treturn q\n}\n\n", query, query)
+	}
+
+	fmt.Fprintln(buf, "// All returns the matching rows.")
+	fmt.Fprintf(buf, "func (q *%s) All(ctx context.Context) ([]*%s, error) {\n", query, name)
+	fmt.Fprintf(buf, "\trows, err := q.db.QueryContext(ctx, selectQuery(%q, q.conditions(), q.orderBy, q.limit), q.args...)\n",
+		fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), table))
+	fmt.Fprintln(buf, "\tif err != nil {\n\t\treturn nil, err\n\t}")
+	fmt.Fprintln(buf, "\tdefer rows.Close()")
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "\tvar result []*%s\n", name)
+	fmt.Fprintln(buf, "\tfor rows.Next() {")
+	fmt.Fprintf(buf, "\t\tm := new(%s)\n", name)
+	fmt.Fprintf(buf, "\t\tif err := rows.Scan(%s); err != nil {\n\t\t\treturn nil, err\n\t\t}\n", strings.Join(targets, ", "))
+	fmt.Fprintln(buf, "\t\tresult = append(result, m)")
+	fmt.Fprintln(buf, "\t}")
+	fmt.Fprintln(buf, "\treturn result, rows.Err()")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+
+	fmt.Fprintln(buf, "// First returns the first matching row, or sql.ErrNoRows when there is none.")
+	fmt.Fprintf(buf, "func (q *%s) First(ctx context.Context) (*%s, error) {\n", query, name)
+	fmt.Fprintln(buf, "\tresult, err := q.Limit(1).All(ctx)")
+	fmt.Fprintln(buf, "\tif err != nil {\n\t\treturn nil, err\n\t}")
+	fmt.Fprintln(buf, "\tif len(result) == 0 {\n\t\treturn nil, sql.ErrNoRows\n\t}")
+	fmt.Fprintln(buf, "\treturn result[0], nil")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+
+	fmt.Fprintln(buf, "// Count returns the number of matching rows.")
+	fmt.Fprintf(buf, "func (q *%s) Count(ctx context.Context) (int64, error) {\n", query)
+	fmt.Fprintln(buf, "\tvar n int64")
+	fmt.Fprintf(buf, "\terr := q.db.QueryRowContext(ctx, selectQuery(%q, q.conditions(), nil, 0), q.args...).Scan(&n)\n",
+		"SELECT COUNT(*) FROM "+table)
+	fmt.Fprintln(buf, "\treturn n, err")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+
+	fmt.Fprintf(buf, "func (q *%s) conditions() []string {\n", query)
+	if soft {
+		column := d.QuoteIdent(deletedAt.ColumnName())
+		fmt.Fprintln(buf, "\tswitch q.deleted {")
+		fmt.Fprintln(buf, "\tcase excludeDeleted:")
+		fmt.Fprintf(buf, "\t\treturn append(q.where[:len(q.where):len(q.where)], %q)\n", column+" IS NULL")
+		fmt.Fprintln(buf, "\tcase onlyDeleted:")
+		fmt.Fprintf(buf, "\t\treturn append(q.where[:len(q.where):len(q.where)], %q)\n", column+" IS NOT NULL")
+		fmt.Fprintln(buf, "\t}")
+	}
+	fmt.Fprintln(buf, "\treturn q.where")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+}
+
+// writeDeleteMethods emits Delete for a model with a primary key. Delete
+// soft deletes the rows of models with a @deletedAt field, which also get
+// Restore and HardDelete.
+func writeDeleteMethods(buf *bytes.Buffer, model ir.IRModel, d dialect.Dialect, imports map[string]bool) {
+	pk, ok := model.PrimaryKey()
+	if !ok {
+		return
+	}
+	imports["context"] = true
+
+	name := GoName(model.Name)
+	table := d.QuoteIdent(model.Name)
+	key := d.QuoteIdent(pk.ColumnName())
+	remove := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", table, key, placeholder(d, 1))
+
+	deletedAt, soft := deletedAtField(model)
+	if !soft {
+		fmt.Fprintf(buf, "// Delete deletes the %s row.\n", model.Name)
+		writeExecMethod(buf, name, "Delete", remove, "m."+GoName(pk.Name))
+		return
+	}
+	imports["time"] = true
+
+	column := d.QuoteIdent(deletedAt.ColumnName())
+	field := "m." + GoName(deletedAt.Name)
+	fmt.Fprintf(buf, "// Delete soft deletes the %s row, setting %s. Queries leave it out\n", model.Name, deletedAt.Name)
+	fmt.Fprintln(buf, "// from then on, unless they call WithDeleted or OnlyDeleted.")
+	fmt.Fprintf(buf, "func (m *%s) Delete(ctx context.Context, db DBTX) error {\n", name)
+	fmt.Fprintln(buf, "\tnow := time.Now().UTC()")
+	fmt.Fprintf(buf, "\tif _, err := db.ExecContext(ctx, %q, now, m.%s); err != nil {\n",
+		fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s = %s", table, column, placeholder(d, 1), key, placeholder(d, 2)), GoName(pk.Name))
+	fmt.Fprintln(buf, "\t\treturn err")
+	fmt.Fprintln(buf, "\t}")
+	fmt.Fprintf(buf, "\t%s = &now\n", field)
+	fmt.Fprintln(buf, "\treturn nil")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+
+	fmt.Fprintf(buf, "// Restore undoes the soft delete of the %s row.\n", model.Name)
+	fmt.Fprintf(buf, "func (m *%s) Restore(ctx context.Context, db DBTX) error {\n", name)
+	fmt.Fprintf(buf, "\tif _, err := db.ExecContext(ctx, %q, m.%s); err != nil {\n",
+		fmt.Sprintf("UPDATE %s SET %s = NULL WHERE %s = %s", table, column, key, placeholder(d, 1)), GoName(pk.Name))
+	fmt.Fprintln(buf, "\t\treturn err")
+	fmt.Fprintln(buf, "\t}")
+	fmt.Fprintf(buf, "\t%s = nil\n", field)
+	fmt.Fprintln(buf, "\treturn nil")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+
+	fmt.Fprintf(buf, "// HardDelete deletes the %s row for good, soft deleted or not.\n", model.Name)
+	writeExecMethod(buf, name, "HardDelete", remove, "m."+GoName(pk.Name))
+}
+
+func writeExecMethod(buf *bytes.Buffer, modelName, method, query string, args ...string) {
+	fmt.Fprintf(buf, "func (m *%s) %s(ctx context.Context, db DBTX) error {\n", modelName, method)
+	fmt.Fprintf(buf, "\t_, err := db.ExecContext(ctx, %q, %s)\n", query, strings.Join(args, ", "))
+	fmt.Fprintln(buf, "\treturn err")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+}
@@ -0,0 +1,196 @@
+package golang
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+var validationImports = map[string]bool{
+	"math":    true,
+	"strconv": true,
+	"strings": true,
+}
+
+// validationSource holds the error types and helpers shared by every Validate method
+const validationSource = `// FieldError describes a constraint violation on a single schema field.
+type FieldError struct {
+	Field   string // Field name as declared in the .storm schema
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors collects every FieldError found by a Validate method.
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e ValidationErrors) orNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// checkPrecision reports whether v fits in a NUMERIC(precision, scale) column.
+func checkPrecision(v float64, precision, scale int) bool {
+	digits := strconv.FormatFloat(math.Abs(v), 'f', -1, 64)
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+	intPart = strings.TrimLeft(intPart, "0")
+	return len(fracPart) <= scale && len(intPart) <= precision-scale
+}
+`
+
+// writeValidate emits the Validate method enforcing the schema constraints of a model
+func writeValidate(buf *bytes.Buffer, irData *ir.IR, model ir.IRModel, imports map[string]bool) {
+	name := GoName(model.Name)
+	fmt.Fprintf(buf, "// Validate checks the %s against the constraints declared in the schema.\n", name)
+	fmt.Fprintf(buf, "func (m *%s) Validate() error {\n", name)
+	fmt.Fprintln(buf, "\tvar errs ValidationErrors")
+
+	for _, f := range model.Fields {
+		// Relations and scalar lists carry no column-level constraints
+		if irData.IsRelation(f) || f.IsArray {
+			continue
+		}
+
+		checks := fieldChecks(f, imports)
+		if len(checks) == 0 {
+			continue
+		}
+
+		if f.IsNullable() {
+			fmt.Fprintf(buf, "\tif m.%s != nil {\n", GoName(f.Name))
+			fmt.Fprintf(buf, "\t\tv := *m.%s\n", GoName(f.Name))
+		} else {
+			fmt.Fprintln(buf, "\t{")
+			fmt.Fprintf(buf, "\t\tv := m.%s\n", GoName(f.Name))
+		}
+		for _, check := range checks {
+			buf.WriteString(check)
+		}
+		fmt.Fprintln(buf, "\t}")
+	}
+
+	fmt.Fprintln(buf, "\treturn errs.orNil()")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+}
+
+// fieldChecks returns the statements validating a single field value bound to v
+func fieldChecks(f ir.IRField, imports map[string]bool) []string {
+	var checks []string
+	kind := f.Type.Kind
+
+	if check := requiredCheck(f); check != "" {
+		checks = append(checks, check)
+	}
+
+	for _, dir := range f.Type.Directives {
+		args := dir.Positional()
+
+		switch dir.Kind {
+		case directive.DirLength:
+			if !isStringKind(kind) || len(args) != 1 || args[0].Kind != directive.ValueInt {
+				continue
+			}
+			imports["unicode/utf8"] = true
+			checks = append(checks, failIf(f,
+				fmt.Sprintf("utf8.RuneCountInString(v) > %d", args[0].Int),
+				fmt.Sprintf("length must be at most %d", args[0].Int)))
+
+		case directive.DirMin, directive.DirMax:
+			if !isNumericKind(kind) || len(args) != 1 {
+				continue
+			}
+			bound, ok := args[0].Number()
+			if !ok {
+				continue
+			}
+			literal := strconv.FormatFloat(bound, 'g', -1, 64)
+			if dir.Kind == directive.DirMin {
+				checks = append(checks, failIf(f,
+					fmt.Sprintf("float64(v) < %s", literal),
+					fmt.Sprintf("must be at least %s", literal)))
+			} else {
+				checks = append(checks, failIf(f,
+					fmt.Sprintf("float64(v) > %s", literal),
+					fmt.Sprintf("must be at most %s", literal)))
+			}
+
+		case directive.DirEnum:
+			values := dir.StringArgs()
+			if !isStringKind(kind) || len(values) == 0 {
+				continue
+			}
+			quoted := make([]string, 0, len(values))
+			for _, value := range values {
+				quoted = append(quoted, strconv.Quote(value))
+			}
+			checks = append(checks, fmt.Sprintf(
+				"\t\tswitch v {\n\t\tcase %s:\n\t\tdefault:\n\t\t\terrs = append(errs, &FieldError{Field: %q, Message: %q})\n\t\t}\n",
+				strings.Join(quoted, ", "), f.Name, "must be one of "+strings.Join(values, ", ")))
+
+		case directive.DirPrecision:
+			precision, scale := f.Type.GetPrecisionScale()
+			if precision == "" || (kind != fld.KindDecimal && kind != fld.KindFloat) {
+				continue
+			}
+			checks = append(checks, failIf(f,
+				fmt.Sprintf("!checkPrecision(float64(v), %s, %s)", precision, scale),
+				fmt.Sprintf("must fit precision %s and scale %s", precision, scale)))
+		}
+	}
+
+	return checks
+}
+
+// requiredCheck rejects zero values for non-nullable fields the database will not fill in
+func requiredCheck(f ir.IRField) string {
+	if f.IsNullable() ||
+		f.Type.HasDirective(directive.DirDefault) ||
+		f.Type.HasDirective(directive.DirDefaultNow) ||
+		f.Type.HasDirective(directive.DirCreatedAt) ||
+		f.Type.HasDirective(directive.DirUpdatedAt) ||
+		(f.Type.HasDirective(directive.DirID) && f.Type.HasDirective(directive.DirAuto)) {
+		return ""
+	}
+
+	switch f.Type.Kind {
+	case fld.KindString, fld.KindText, fld.KindChar, fld.KindUUID, fld.KindCUID:
+		return failIf(f, `v == ""`, "is required")
+	case fld.KindBinary, fld.KindJSON:
+		return failIf(f, "len(v) == 0", "is required")
+	case fld.KindDateTime, fld.KindDate, fld.KindTime, fld.KindTimestamp:
+		return failIf(f, "v.IsZero()", "is required")
+	}
+	return ""
+}
+
+// failIf renders a statement appending a FieldError when cond holds
+func failIf(f ir.IRField, cond, message string) string {
+	return fmt.Sprintf("\t\tif %s {\n\t\t\terrs = append(errs, &FieldError{Field: %q, Message: %q})\n\t\t}\n",
+		cond, f.Name, message)
+}
+
+func isStringKind(kind fld.FieldKind) bool {
+	return kind == fld.KindString || kind == fld.KindText || kind == fld.KindChar
+}
+
+func isNumericKind(kind fld.FieldKind) bool {
+	return kind == fld.KindInt || kind == fld.KindFloat || kind == fld.KindDecimal || kind == fld.KindBigInt
+}
@@ -0,0 +1,321 @@
+// Package graphql renders the Storm IR as a GraphQL schema (SDL): an object
+// type per model, enums for @enum fields, Relay-style connections for to-many
+// relations and create/update input types.
+package graphql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// scalarDescriptions documents the custom scalars the generated types may use
+var scalarDescriptions = map[string]string{
+	"BigInt":   "64-bit integer, serialized as a string",
+	"Bytes":    "Binary data, serialized as base64",
+	"Date":     "Calendar date in ISO 8601 format (2006-01-02)",
+	"DateTime": "Date and time in RFC 3339 format",
+	"Decimal":  "Exact decimal number, serialized as a string",
+	"JSON":     "Arbitrary JSON value",
+	"Time":     "Time of day in ISO 8601 format (15:04:05)",
+	"UUID":     "RFC 4122 UUID",
+}
+
+// enumType is a GraphQL enum generated for an @enum field
+type enumType struct {
+	Name   string
+	Values []string
+}
+
+// generator holds the state of one SDL rendering
+type generator struct {
+	ir          *ir.IR
+	scalars     map[string]bool
+	enums       map[ir.RelationEnd]enumType
+	connections map[string]bool
+}
+
+// Generate renders the GraphQL SDL of the IR
+func Generate(irData *ir.IR) string {
+	g := &generator{
+		ir:          irData,
+		scalars:     make(map[string]bool),
+		enums:       make(map[ir.RelationEnd]enumType),
+		connections: make(map[string]bool),
+	}
+
+	var enums []enumType
+	for _, model := range irData.Models {
+		for _, f := range model.Fields {
+			if d, ok := f.Type.Directive(directive.DirEnum); ok && !irData.IsRelation(f) {
+				e := enumType{Name: model.Name + pascal(f.Name)}
+				for _, v := range d.StringArgs() {
+					e.Values = append(e.Values, enumValue(v))
+				}
+				g.enums[ir.RelationEnd{Model: model.Name, Field: f.Name}] = e
+				enums = append(enums, e)
+			}
+		}
+	}
+
+	// Render the models first so the scalars and connections they use are known
+	var body strings.Builder
+	for _, model := range irData.Models {
+		g.writeType(&body, model)
+		g.writeCreateInput(&body, model)
+		g.writeUpdateInput(&body, model)
+	}
+
+	var b strings.Builder
+	b.WriteString("# Code generated by storm. DO NOT EDIT.\n")
+
+	scalars := make([]string, 0, len(g.scalars))
+	for s := range g.scalars {
+		scalars = append(scalars, s)
+	}
+	sort.Strings(scalars)
+	for _, s := range scalars {
+		fmt.Fprintf(&b, "\n\"%s\"\nscalar %s\n", scalarDescriptions[s], s)
+	}
+
+	for _, e := range enums {
+		fmt.Fprintf(&b, "\nenum %s {\n", e.Name)
+		for _, v := range e.Values {
+			fmt.Fprintf(&b, "  %s\n", v)
+		}
+		b.WriteString("}\n")
+	}
+
+	if len(g.connections) > 0 {
+		b.WriteString("\ntype PageInfo {\n")
+		b.WriteString("  hasNextPage: Boolean!\n")
+		b.WriteString("  hasPreviousPage: Boolean!\n")
+		b.WriteString("  startCursor: String\n")
+		b.WriteString("  endCursor: String\n")
+		b.WriteString("}\n")
+	}
+
+	b.WriteString(body.String())
+
+	for _, model := range irData.Models {
+		if !g.connections[model.Name] {
+			continue
+		}
+		fmt.Fprintf(&b, "\ntype %sConnection {\n", model.Name)
+		fmt.Fprintf(&b, "  edges: [%sEdge!]!\n", model.Name)
+		b.WriteString("  pageInfo: PageInfo!\n")
+		b.WriteString("  totalCount: Int!\n")
+		b.WriteString("}\n")
+
+		fmt.Fprintf(&b, "\ntype %sEdge {\n", model.Name)
+		b.WriteString("  cursor: String!\n")
+		fmt.Fprintf(&b, "  node: %s!\n", model.Name)
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// writeType writes the object type of a model
+func (g *generator) writeType(b *strings.Builder, model ir.IRModel) {
+	b.WriteString("\n")
+	writeDescription(b, "", model.Doc)
+	fmt.Fprintf(b, "type %s {\n", model.Name)
+	for _, f := range model.Fields {
+		writeDescription(b, "  ", f.Doc)
+		if !g.ir.IsRelation(f) {
+			fmt.Fprintf(b, "  %s: %s\n", f.Name, g.fieldType(model, f, !f.IsNullable()))
+			continue
+		}
+
+		if f.IsArray {
+			g.connections[f.Type.ModelName] = true
+			fmt.Fprintf(b, "  %s(first: Int, after: String, last: Int, before: String): %sConnection!\n",
+				f.Name, f.Type.ModelName)
+			continue
+		}
+		typ := f.Type.ModelName
+		if !f.IsNullable() && !f.Type.HasDirective(directive.DirHasOne) {
+			// The row on the other side of a @hasOne may not exist
+			typ += "!"
+		}
+		fmt.Fprintf(b, "  %s: %s\n", f.Name, typ)
+	}
+	b.WriteString("}\n")
+}
+
+// writeCreateInput writes the input type for creating a row. Fields the
+// database fills are left out and fields with a default are optional.
+func (g *generator) writeCreateInput(b *strings.Builder, model ir.IRModel) {
+	fmt.Fprintf(b, "\ninput Create%sInput {\n", model.Name)
+	for _, f := range model.Fields {
+		switch {
+		case g.ir.IsRelation(f):
+			if key, ok := g.foreignKey(model, f); ok {
+				required := !f.IsNullable()
+				fmt.Fprintf(b, "  %s: %s\n", key, nonNull("ID", required))
+			}
+		case isGenerated(f):
+		default:
+			required := !f.IsNullable() && !f.Type.HasDirective(directive.DirDefault)
+			fmt.Fprintf(b, "  %s: %s\n", f.Name, g.fieldType(model, f, required))
+		}
+	}
+	b.WriteString("}\n")
+}
+
+// writeUpdateInput writes the input type for updating a row; every field is optional
+func (g *generator) writeUpdateInput(b *strings.Builder, model ir.IRModel) {
+	fmt.Fprintf(b, "\ninput Update%sInput {\n", model.Name)
+	for _, f := range model.Fields {
+		switch {
+		case g.ir.IsRelation(f):
+			if key, ok := g.foreignKey(model, f); ok {
+				fmt.Fprintf(b, "  %s: ID\n", key)
+			}
+		case isGenerated(f), f.Type.HasDirective(directive.DirID):
+		default:
+			fmt.Fprintf(b, "  %s: %s\n", f.Name, g.fieldType(model, f, false))
+		}
+	}
+	b.WriteString("}\n")
+}
+
+// foreignKey returns the input field setting the key of a @belongsTo field,
+// unless the model declares the key as a scalar field of its own
+func (g *generator) foreignKey(model ir.IRModel, f ir.IRField) (string, bool) {
+	if !f.Type.HasDirective(directive.DirBelongsTo) {
+		return "", false
+	}
+	key := ir.ForeignKeyName(f)
+	if _, ok := model.FindField(key); ok {
+		return "", false
+	}
+	return key, true
+}
+
+// fieldType returns the GraphQL type of a scalar field
+func (g *generator) fieldType(model ir.IRModel, f ir.IRField, required bool) string {
+	var typ string
+	if e, ok := g.enums[ir.RelationEnd{Model: model.Name, Field: f.Name}]; ok {
+		typ = e.Name
+	} else if f.Type.HasDirective(directive.DirID) {
+		typ = "ID"
+	} else {
+		typ = g.scalarType(f.Type.Kind)
+	}
+	if f.IsArray {
+		typ = "[" + typ + "!]"
+	}
+	return nonNull(typ, required)
+}
+
+// scalarType returns the GraphQL scalar of a field kind, recording custom scalars as used
+func (g *generator) scalarType(kind fld.FieldKind) string {
+	var typ string
+	switch kind {
+	case fld.KindInt:
+		return "Int"
+	case fld.KindFloat:
+		return "Float"
+	case fld.KindBoolean:
+		return "Boolean"
+	case fld.KindBigInt:
+		typ = "BigInt"
+	case fld.KindDecimal:
+		typ = "Decimal"
+	case fld.KindDateTime, fld.KindTimestamp:
+		typ = "DateTime"
+	case fld.KindDate:
+		typ = "Date"
+	case fld.KindTime:
+		typ = "Time"
+	case fld.KindUUID:
+		typ = "UUID"
+	case fld.KindBinary:
+		typ = "Bytes"
+	case fld.KindJSON, fld.KindPoint:
+		typ = "JSON"
+	default:
+		return "String"
+	}
+	g.scalars[typ] = true
+	return typ
+}
+
+// isGenerated reports whether the database assigns the field's value
+func isGenerated(f ir.IRField) bool {
+	t := f.Type
+	return (t.HasDirective(directive.DirID) && t.HasDirective(directive.DirAuto)) ||
+		t.HasDirective(directive.DirCreatedAt) || t.HasDirective(directive.DirUpdatedAt) ||
+		t.HasDirective(directive.DirDefaultNow)
+}
+
+func nonNull(typ string, required bool) string {
+	if required {
+		return typ + "!"
+	}
+	return typ
+}
+
+// writeDescription writes a doc comment as a GraphQL block string
+func writeDescription(b *strings.Builder, indent, doc string) {
+	if doc == "" {
+		return
+	}
+	doc = strings.ReplaceAll(doc, `"""`, `\"""`)
+	if !strings.Contains(doc, "\n") {
+		fmt.Fprintf(b, "%s\"\"\"%s\"\"\"\n", indent, doc)
+		return
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+	for _, line := range strings.Split(doc, "\n") {
+		fmt.Fprintf(b, "%s%s\n", indent, line)
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+}
+
+// enumValue turns an @enum value into a GraphQL name, replacing the characters names cannot hold
+func enumValue(v string) string {
+	var b strings.Builder
+	for i, r := range v {
+		switch {
+		case r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || i > 0 && unicode.IsDigit(r)):
+			b.WriteRune(r)
+		case i == 0 && unicode.IsDigit(r):
+			b.WriteString("_")
+			b.WriteRune(r)
+		default:
+			b.WriteString("_")
+		}
+	}
+	switch name := b.String(); name {
+	case "", "true", "false", "null":
+		// Not allowed as enum values
+		return name + "_"
+	default:
+		return name
+	}
+}
+
+// pascal upper-cases the first letter of a field name and drops underscores
+func pascal(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
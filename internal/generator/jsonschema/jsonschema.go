@@ -0,0 +1,331 @@
+// Package jsonschema renders the models of the Storm IR as JSON Schema
+// (draft 2020-12) and as OpenAPI 3.1 component schemas.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// Draft is the JSON Schema dialect of the generated documents
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Options configures the generated schemas
+type Options struct {
+	ExcludeRelations bool   // Leave relation fields out of the schemas
+	ReadOnly         bool   // Mark generated fields (@id @auto, @createdAt, @updatedAt) readOnly
+	Title            string // Title of the document; the OpenAPI info.title
+	Version          string // OpenAPI info.version, defaults to "1.0.0"
+}
+
+// JSONSchema renders every model as a $defs entry of a single JSON Schema document
+func JSONSchema(irData *ir.IR, opts Options) ([]byte, error) {
+	doc := object{{"$schema", Draft}}
+	if opts.Title != "" {
+		doc = append(doc, member{"title", opts.Title})
+	}
+	doc = append(doc, member{"$defs", schemas(irData, opts, "#/$defs/")})
+	return encode(doc)
+}
+
+// OpenAPI renders every model as an entry of components.schemas of an OpenAPI 3.1 document
+func OpenAPI(irData *ir.IR, opts Options) ([]byte, error) {
+	title, version := opts.Title, opts.Version
+	if title == "" {
+		title = "Storm models"
+	}
+	if version == "" {
+		version = "1.0.0"
+	}
+
+	doc := object{
+		{"openapi", "3.1.0"},
+		{"info", object{{"title", title}, {"version", version}}},
+		{"jsonSchemaDialect", Draft},
+		{"paths", object{}},
+		{"components", object{{"schemas", schemas(irData, opts, "#/components/schemas/")}}},
+	}
+	return encode(doc)
+}
+
+// schemas returns the schema of every model; refPrefix locates them for $ref
+func schemas(irData *ir.IR, opts Options, refPrefix string) object {
+	defs := object{}
+	for _, model := range irData.Models {
+		defs = append(defs, member{model.Name, modelSchema(irData, model, opts, refPrefix)})
+	}
+	return defs
+}
+
+// modelSchema returns the object schema of a model
+func modelSchema(irData *ir.IR, model ir.IRModel, opts Options, refPrefix string) object {
+	schema := object{{"type", "object"}}
+	if model.Doc != "" {
+		schema = append(schema, member{"description", model.Doc})
+	}
+
+	properties := object{}
+	var required []string
+	for _, f := range model.Fields {
+		relation := irData.IsRelation(f)
+		if relation && opts.ExcludeRelations {
+			continue
+		}
+
+		var prop object
+		if relation {
+			prop = relationSchema(f, refPrefix)
+		} else {
+			prop = fieldSchema(f)
+		}
+		if opts.ReadOnly && isGenerated(f) {
+			prop = append(prop, member{"readOnly", true})
+		}
+		properties = append(properties, member{f.Name, prop})
+
+		if !relation && !f.IsNullable() && !isGenerated(f) && !f.Type.HasDirective(directive.DirDefault) {
+			required = append(required, f.Name)
+		}
+	}
+
+	// Keys of @belongsTo fields without a declared field, as the generated
+	// Go structs carry them; they may be left out
+	for _, f := range model.Fields {
+		if !f.Type.HasDirective(directive.DirBelongsTo) || !irData.IsRelation(f) {
+			continue
+		}
+		if _, declared := model.FindField(ir.ForeignKeyName(f)); declared {
+			continue
+		}
+		target, _ := irData.FindModel(f.Type.ModelName)
+		referenced, ok := target.PrimaryKey()
+		if ref := ir.ForeignKeyReference(f); ref != "" {
+			referenced, ok = target.FindField(ref)
+		}
+		if ok {
+			properties = append(properties, member{model.ForeignKeyColumn(f), nullable(scalarSchema(referenced.Type.Kind))})
+		}
+	}
+
+	schema = append(schema, member{"properties", properties})
+	if len(required) > 0 {
+		schema = append(schema, member{"required", required})
+	}
+	return append(schema, member{"additionalProperties", false})
+}
+
+// isGenerated reports whether the database assigns the field's value
+func isGenerated(f ir.IRField) bool {
+	t := f.Type
+	return (t.HasDirective(directive.DirID) && t.HasDirective(directive.DirAuto)) ||
+		t.HasDirective(directive.DirCreatedAt) || t.HasDirective(directive.DirUpdatedAt) ||
+		t.HasDirective(directive.DirDefaultNow)
+}
+
+// relationSchema returns a $ref to the related model, or an array of them
+func relationSchema(f ir.IRField, refPrefix string) object {
+	ref := object{{"$ref", refPrefix + f.Type.ModelName}}
+	var prop object
+	switch {
+	case f.IsArray:
+		prop = object{{"type", "array"}, {"items", ref}}
+	case f.IsNullable():
+		prop = object{{"anyOf", []object{ref, {{"type", "null"}}}}}
+	default:
+		prop = ref
+	}
+	if f.Doc != "" {
+		prop = append(prop, member{"description", f.Doc})
+	}
+	return prop
+}
+
+// fieldSchema returns the schema of a scalar field
+func fieldSchema(f ir.IRField) object {
+	item := scalarSchema(f.Type.Kind)
+
+	t := f.Type
+	for _, d := range t.Directives {
+		args := d.Positional()
+		if len(args) == 0 {
+			continue
+		}
+		switch d.Kind {
+		case directive.DirLength:
+			item = append(item, member{"maxLength", args[0].Int})
+		case directive.DirMin:
+			if t.Kind != fld.KindDecimal {
+				item = append(item, member{"minimum", number(args[0])})
+			}
+		case directive.DirMax:
+			if t.Kind != fld.KindDecimal {
+				item = append(item, member{"maximum", number(args[0])})
+			}
+		case directive.DirEnum:
+			item = append(item, member{"enum", d.StringArgs()})
+		}
+	}
+
+	prop := item
+	if f.IsArray {
+		prop = object{{"type", "array"}, {"items", item}}
+	}
+	if f.IsNullable() {
+		prop = nullable(prop)
+	}
+	if f.Doc != "" {
+		prop = append(prop, member{"description", f.Doc})
+	}
+	if d, ok := t.Directive(directive.DirDefault); ok && len(d.Positional()) > 0 {
+		if value, ok := defaultValue(d.Positional()[0]); ok {
+			if t.Kind == fld.KindDecimal {
+				value = fmt.Sprint(value)
+			}
+			prop = append(prop, member{"default", value})
+		}
+	}
+	return prop
+}
+
+// scalarSchema returns the type and format of a field kind
+func scalarSchema(kind fld.FieldKind) object {
+	switch kind {
+	case fld.KindInt:
+		return object{{"type", "integer"}, {"format", "int32"}}
+	case fld.KindBigInt:
+		return object{{"type", "integer"}, {"format", "int64"}}
+	case fld.KindFloat:
+		return object{{"type", "number"}, {"format", "double"}}
+	case fld.KindDecimal:
+		// Kept as text, as the other generators do, so no precision is lost
+		return object{{"type", "string"}, {"format", "decimal"}}
+	case fld.KindBoolean:
+		return object{{"type", "boolean"}}
+	case fld.KindDateTime, fld.KindTimestamp:
+		return object{{"type", "string"}, {"format", "date-time"}}
+	case fld.KindDate:
+		return object{{"type", "string"}, {"format", "date"}}
+	case fld.KindTime:
+		return object{{"type", "string"}, {"format", "time"}}
+	case fld.KindUUID:
+		return object{{"type", "string"}, {"format", "uuid"}}
+	case fld.KindBinary:
+		return object{{"type", "string"}, {"contentEncoding", "base64"}}
+	case fld.KindJSON:
+		// Any JSON value
+		return object{}
+	case fld.KindPoint:
+		return object{
+			{"type", "object"},
+			{"properties", object{{"x", object{{"type", "number"}}}, {"y", object{{"type", "number"}}}}},
+			{"required", []string{"x", "y"}},
+		}
+	default:
+		return object{{"type", "string"}}
+	}
+}
+
+// nullable widens a schema to also accept null
+func nullable(schema object) object {
+	typed := false
+	for i, m := range schema {
+		if m.Key == "type" {
+			if typ, ok := m.Value.(string); ok {
+				schema[i].Value = []string{typ, "null"}
+				typed = true
+			}
+		}
+	}
+	if typed {
+		// An enum restricts the values on its own, so it lists null as well
+		for i, m := range schema {
+			if values, ok := m.Value.([]string); ok && m.Key == "enum" {
+				widened := make([]interface{}, 0, len(values)+1)
+				for _, v := range values {
+					widened = append(widened, v)
+				}
+				schema[i].Value = append(widened, nil)
+			}
+		}
+		return schema
+	}
+	if len(schema) == 0 {
+		// Untyped schemas accept null already
+		return schema
+	}
+	return object{{"anyOf", []object{schema, {{"type", "null"}}}}}
+}
+
+// number returns the numeric value of a @min or @max argument
+func number(v directive.Value) interface{} {
+	if v.Kind == directive.ValueInt {
+		return v.Int
+	}
+	return v.Float
+}
+
+// defaultValue converts a @default argument; function calls such as now() have no JSON value
+func defaultValue(v directive.Value) (interface{}, bool) {
+	switch v.Kind {
+	case directive.ValueInt:
+		return v.Int, true
+	case directive.ValueFloat:
+		return v.Float, true
+	case directive.ValueBool:
+		return v.Bool, true
+	case directive.ValueString, directive.ValueIdent:
+		return v.Text, true
+	default:
+		return nil, false
+	}
+}
+
+// object is a JSON object that keeps the order of its members
+type object []member
+
+type member struct {
+	Key   string
+	Value interface{}
+}
+
+// MarshalJSON encodes the members in order
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func encode(doc object) ([]byte, error) {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,125 @@
+package protobuf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// LockVersion is the format version of lock files written by this package
+const LockVersion = 1
+
+// Lock records the numbers given to the fields of every message and the values
+// of every enum, so regenerating after a schema change keeps them stable and
+// never hands out the number of a removed field again.
+type Lock struct {
+	Version  int                   `json:"version"`
+	Messages map[string]*Numbering `json:"messages"`
+	Enums    map[string]*Numbering `json:"enums,omitempty"`
+}
+
+// Numbering is the numbering of the fields of a message or the values of an enum
+type Numbering struct {
+	Numbers       map[string]int `json:"numbers"`
+	Reserved      []int          `json:"reserved,omitempty"`      // Numbers of removed entries
+	ReservedNames []string       `json:"reservedNames,omitempty"` // Names of removed entries
+	Next          int            `json:"next"`
+}
+
+// NewLock returns an empty lock
+func NewLock() *Lock {
+	return &Lock{Version: LockVersion, Messages: make(map[string]*Numbering), Enums: make(map[string]*Numbering)}
+}
+
+// LoadLock reads a lock file; a missing file yields an empty lock
+func LoadLock(path string) (*Lock, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewLock(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lock := NewLock()
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if lock.Version != LockVersion {
+		return nil, fmt.Errorf("%s: unsupported lock version %d", path, lock.Version)
+	}
+	if lock.Messages == nil {
+		lock.Messages = make(map[string]*Numbering)
+	}
+	if lock.Enums == nil {
+		lock.Enums = make(map[string]*Numbering)
+	}
+	return lock, nil
+}
+
+// Save writes the lock file
+func (l *Lock) Save(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// number assigns numbers to names, in order, within a numbering of the table
+// and returns it. Names no longer present are retired into the reserved lists.
+func number(table map[string]*Numbering, key string, names []string) *Numbering {
+	n, ok := table[key]
+	if !ok {
+		n = &Numbering{Numbers: make(map[string]int), Next: 1}
+		table[key] = n
+	}
+	if n.Numbers == nil {
+		n.Numbers = make(map[string]int)
+	}
+
+	present := make(map[string]bool, len(names))
+	for _, name := range names {
+		present[name] = true
+	}
+	var retired []string
+	for name := range n.Numbers {
+		if !present[name] {
+			retired = append(retired, name)
+		}
+	}
+	sort.Strings(retired)
+	for _, name := range retired {
+		n.Reserved = append(n.Reserved, n.Numbers[name])
+		n.ReservedNames = append(n.ReservedNames, name)
+		delete(n.Numbers, name)
+	}
+	sort.Ints(n.Reserved)
+
+	for _, name := range names {
+		if _, ok := n.Numbers[name]; ok {
+			continue
+		}
+		// A name coming back gets a new number; only its old number stays reserved
+		for i, reserved := range n.ReservedNames {
+			if reserved == name {
+				n.ReservedNames = append(n.ReservedNames[:i], n.ReservedNames[i+1:]...)
+				break
+			}
+		}
+		if n.Next >= firstReserved && n.Next <= lastReserved {
+			n.Next = lastReserved + 1
+		}
+		n.Numbers[name] = n.Next
+		n.Next++
+	}
+	return n
+}
+
+// Field numbers 19000 to 19999 are reserved by the protobuf implementation
+const (
+	firstReserved = 19000
+	lastReserved  = 19999
+)
@@ -0,0 +1,297 @@
+// Package protobuf renders the models of the Storm IR as proto3 messages.
+//
+// Field numbers come from a Lock, which remembers the number of every field
+// ever generated so that renaming or removing a field never reuses a number.
+package protobuf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// Options configures the generated .proto file
+type Options struct {
+	Package   string // Protobuf package, defaults to "storm"
+	GoPackage string // Value of option go_package, omitted when empty
+}
+
+// Well-known type files imported by the generated messages
+const (
+	timestampProto = "google/protobuf/timestamp.proto"
+	wrappersProto  = "google/protobuf/wrappers.proto"
+	structProto    = "google/protobuf/struct.proto"
+)
+
+// wrappers are the well-known wrapper messages of nullable scalars
+var wrappers = map[string]string{
+	"int32":  "google.protobuf.Int32Value",
+	"int64":  "google.protobuf.Int64Value",
+	"double": "google.protobuf.DoubleValue",
+	"string": "google.protobuf.StringValue",
+	"bool":   "google.protobuf.BoolValue",
+	"bytes":  "google.protobuf.BytesValue",
+}
+
+// protoField is a field of a generated message
+type protoField struct {
+	Name     string
+	Type     string
+	Repeated bool
+	Doc      string
+}
+
+// generator holds the state of one .proto rendering
+type generator struct {
+	ir      *ir.IR
+	imports map[string]bool
+	point   bool
+}
+
+// Generate renders the .proto file of the IR, numbering fields and enum values
+// from the lock and recording new numbers in it
+func Generate(irData *ir.IR, lock *Lock, opts Options) string {
+	if opts.Package == "" {
+		opts.Package = "storm"
+	}
+	g := &generator{ir: irData, imports: make(map[string]bool)}
+
+	var body strings.Builder
+	for _, model := range irData.Models {
+		var enums []string
+		var fields []protoField
+		for _, f := range model.Fields {
+			if d, ok := f.Type.Directive(directive.DirEnum); ok && !irData.IsRelation(f) {
+				enum := pascal(f.Name)
+				enums = append(enums, g.enum(lock, model.Name+"."+enum, enum, d.StringArgs()))
+				fields = append(fields, g.enumField(f, enum))
+				continue
+			}
+			fields = append(fields, g.fields(model, f)...)
+		}
+
+		names := make([]string, len(fields))
+		for i, f := range fields {
+			names[i] = f.Name
+		}
+		numbering := number(lock.Messages, model.Name, names)
+
+		body.WriteString("\n")
+		writeComment(&body, "", model.Doc)
+		fmt.Fprintf(&body, "message %s {\n", model.Name)
+		writeReserved(&body, "  ", numbering)
+		for _, e := range enums {
+			body.WriteString(e)
+		}
+		for _, f := range fields {
+			writeComment(&body, "  ", f.Doc)
+			body.WriteString("  ")
+			if f.Repeated {
+				body.WriteString("repeated ")
+			}
+			fmt.Fprintf(&body, "%s %s = %d;\n", f.Type, f.Name, numbering.Numbers[f.Name])
+		}
+		body.WriteString("}\n")
+	}
+
+	if g.point {
+		numbering := number(lock.Messages, "Point", []string{"x", "y"})
+		fmt.Fprintf(&body, "\nmessage Point {\n  double x = %d;\n  double y = %d;\n}\n",
+			numbering.Numbers["x"], numbering.Numbers["y"])
+	}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by storm. DO NOT EDIT.\n\n")
+	b.WriteString("syntax = \"proto3\";\n\n")
+	fmt.Fprintf(&b, "package %s;\n", opts.Package)
+	if opts.GoPackage != "" {
+		fmt.Fprintf(&b, "\noption go_package = %q;\n", opts.GoPackage)
+	}
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for imp := range g.imports {
+			imports = append(imports, imp)
+		}
+		sort.Strings(imports)
+		b.WriteString("\n")
+		for _, imp := range imports {
+			fmt.Fprintf(&b, "import %q;\n", imp)
+		}
+	}
+	b.WriteString(body.String())
+	return b.String()
+}
+
+// fields returns the message fields of a schema field. A @belongsTo relation
+// also gets a field for its key when the model does not declare one.
+func (g *generator) fields(model ir.IRModel, f ir.IRField) []protoField {
+	name := snake(f.Name)
+	if !g.ir.IsRelation(f) {
+		return []protoField{{Name: name, Type: g.scalarType(f.Type.Kind, f.IsNullable() && !f.IsArray), Repeated: f.IsArray, Doc: f.Doc}}
+	}
+
+	field := protoField{Name: name, Type: f.Type.ModelName, Repeated: f.IsArray, Doc: f.Doc}
+	if !f.Type.HasDirective(directive.DirBelongsTo) {
+		return []protoField{field}
+	}
+	key := ir.ForeignKeyName(f)
+	if _, ok := model.FindField(key); ok {
+		return []protoField{field}
+	}
+
+	kind := fld.KindInt
+	if target, ok := g.ir.FindModel(f.Type.ModelName); ok {
+		referenced, ok := target.PrimaryKey()
+		if ref := ir.ForeignKeyReference(f); ref != "" {
+			referenced, ok = target.FindField(ref)
+		}
+		if ok {
+			kind = referenced.Type.Kind
+		}
+	}
+	return []protoField{{Name: snake(key), Type: g.scalarType(kind, f.IsNullable())}, field}
+}
+
+// enumField returns the message field of an @enum field. The zero value of
+// the enum stands for a null column, so nullable enums need no wrapper.
+func (g *generator) enumField(f ir.IRField, enum string) protoField {
+	return protoField{Name: snake(f.Name), Type: enum, Repeated: f.IsArray, Doc: f.Doc}
+}
+
+// enum renders a nested enum, numbering its values from the lock
+func (g *generator) enum(lock *Lock, key, name string, values []string) string {
+	prefix := strings.ToUpper(snake(name)) + "_"
+	constants := make([]string, len(values))
+	for i, v := range values {
+		constants[i] = prefix + constant(v)
+	}
+	numbering := number(lock.Enums, key, constants)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  enum %s {\n", name)
+	writeReserved(&b, "    ", numbering)
+	fmt.Fprintf(&b, "    %sUNSPECIFIED = 0;\n", prefix)
+	for _, c := range constants {
+		fmt.Fprintf(&b, "    %s = %d;\n", c, numbering.Numbers[c])
+	}
+	b.WriteString("  }\n\n")
+	return b.String()
+}
+
+// scalarType returns the protobuf type of a field kind, wrapped when nullable
+func (g *generator) scalarType(kind fld.FieldKind, nullable bool) string {
+	var typ string
+	switch kind {
+	case fld.KindInt:
+		typ = "int32"
+	case fld.KindBigInt:
+		typ = "int64"
+	case fld.KindFloat:
+		typ = "double"
+	case fld.KindBoolean:
+		typ = "bool"
+	case fld.KindBinary:
+		typ = "bytes"
+	case fld.KindDateTime, fld.KindTimestamp:
+		// Messages are nullable already
+		g.imports[timestampProto] = true
+		return "google.protobuf.Timestamp"
+	case fld.KindJSON:
+		g.imports[structProto] = true
+		return "google.protobuf.Value"
+	case fld.KindPoint:
+		g.point = true
+		return "Point"
+	default:
+		// Decimal keeps its precision as a string; dates and times are ISO 8601 strings
+		typ = "string"
+	}
+	if nullable {
+		g.imports[wrappersProto] = true
+		return wrappers[typ]
+	}
+	return typ
+}
+
+// writeReserved writes the reserved statements of retired numbers and names
+func writeReserved(b *strings.Builder, indent string, n *Numbering) {
+	if len(n.Reserved) > 0 {
+		numbers := make([]string, len(n.Reserved))
+		for i, r := range n.Reserved {
+			numbers[i] = fmt.Sprint(r)
+		}
+		fmt.Fprintf(b, "%sreserved %s;\n", indent, strings.Join(numbers, ", "))
+	}
+	if len(n.ReservedNames) > 0 {
+		names := make([]string, len(n.ReservedNames))
+		for i, r := range n.ReservedNames {
+			names[i] = fmt.Sprintf("%q", r)
+		}
+		fmt.Fprintf(b, "%sreserved %s;\n", indent, strings.Join(names, ", "))
+	}
+	if len(n.Reserved) > 0 || len(n.ReservedNames) > 0 {
+		b.WriteString("\n")
+	}
+}
+
+// writeComment writes a doc comment as // lines
+func writeComment(b *strings.Builder, indent, doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		fmt.Fprintf(b, "%s// %s\n", indent, line)
+	}
+}
+
+// snake converts a schema identifier such as "createdAt" into "created_at"
+func snake(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && runes[i-1] != '_' && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// pascal upper-cases the first letter of a field name and drops underscores
+func pascal(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// constant turns an @enum value into an upper-case enum constant
+func constant(v string) string {
+	var b strings.Builder
+	for _, r := range snake(v) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(unicode.ToUpper(r))
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,217 @@
+// Package typescript renders the models of the Storm IR as TypeScript
+// interfaces and Zod schemas validating them.
+package typescript
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// Options configures the generated module
+type Options struct {
+	Zod bool // Emit a Zod schema next to every interface
+}
+
+// Generate renders a TypeScript module with an interface per model and,
+// when enabled, a <Model>Schema Zod validator for each of them
+func Generate(irData *ir.IR, opts Options) string {
+	var b strings.Builder
+	b.WriteString("// Code generated by storm. DO NOT EDIT.\n")
+	if opts.Zod {
+		b.WriteString("\nimport { z } from \"zod\";\n")
+	}
+
+	for _, model := range irData.Models {
+		b.WriteString("\n")
+		writeDoc(&b, "", model.Doc)
+		fmt.Fprintf(&b, "export interface %s {\n", model.Name)
+		for _, f := range model.Fields {
+			writeDoc(&b, "  ", f.Doc)
+			if irData.IsRelation(f) {
+				// Relations are only present when loaded
+				fmt.Fprintf(&b, "  %s?: %s;\n", f.Name, relationType(f))
+				continue
+			}
+			if f.IsNullable() {
+				fmt.Fprintf(&b, "  %s?: %s | null;\n", f.Name, fieldType(f))
+				continue
+			}
+			if f.Type.Kind == fld.KindJSON && !f.IsArray {
+				// Zod infers unknown values as optional keys
+				fmt.Fprintf(&b, "  %s?: unknown;\n", f.Name)
+				continue
+			}
+			fmt.Fprintf(&b, "  %s: %s;\n", f.Name, fieldType(f))
+		}
+		b.WriteString("}\n")
+
+		if opts.Zod {
+			writeSchema(&b, irData, model)
+		}
+	}
+	return b.String()
+}
+
+// writeSchema writes the Zod schema of a model. The annotation with the
+// interface lets schemas of related models refer to each other.
+func writeSchema(b *strings.Builder, irData *ir.IR, model ir.IRModel) {
+	fmt.Fprintf(b, "\nexport const %sSchema: z.ZodType<%s> = z.object({\n", model.Name, model.Name)
+	for _, f := range model.Fields {
+		if irData.IsRelation(f) {
+			fmt.Fprintf(b, "  %s: %s,\n", f.Name, relationSchema(f))
+			continue
+		}
+		schema := fieldSchema(f)
+		if f.IsNullable() {
+			schema += ".nullable().optional()"
+		}
+		fmt.Fprintf(b, "  %s: %s,\n", f.Name, schema)
+	}
+	b.WriteString("});\n")
+}
+
+// fieldType returns the TypeScript type of a scalar field
+func fieldType(f ir.IRField) string {
+	var typ string
+	if d, ok := f.Type.Directive(directive.DirEnum); ok {
+		values := d.StringArgs()
+		for i, v := range values {
+			values[i] = strconv.Quote(v)
+		}
+		typ = strings.Join(values, " | ")
+		if f.IsArray {
+			typ = "(" + typ + ")"
+		}
+	} else {
+		typ = scalarType(f.Type.Kind)
+	}
+	if f.IsArray {
+		typ += "[]"
+	}
+	return typ
+}
+
+// scalarType returns the TypeScript type of a field kind
+func scalarType(kind fld.FieldKind) string {
+	switch kind {
+	case fld.KindInt, fld.KindBigInt, fld.KindFloat:
+		return "number"
+	case fld.KindBoolean:
+		return "boolean"
+	case fld.KindDateTime, fld.KindTimestamp, fld.KindDate:
+		return "Date"
+	case fld.KindJSON:
+		return "unknown"
+	case fld.KindPoint:
+		return "{ x: number; y: number }"
+	default:
+		// Decimals keep their precision as strings, binary data is base64
+		return "string"
+	}
+}
+
+// relationType returns the TypeScript type of a relation field
+func relationType(f ir.IRField) string {
+	switch {
+	case f.IsArray:
+		return f.Type.ModelName + "[]"
+	case f.IsNullable():
+		return f.Type.ModelName + " | null"
+	default:
+		return f.Type.ModelName
+	}
+}
+
+// fieldSchema returns the Zod schema of a scalar field with its constraints
+func fieldSchema(f ir.IRField) string {
+	var schema string
+	if d, ok := f.Type.Directive(directive.DirEnum); ok {
+		values := d.StringArgs()
+		for i, v := range values {
+			values[i] = strconv.Quote(v)
+		}
+		schema = "z.enum([" + strings.Join(values, ", ") + "])"
+	} else {
+		schema = scalarSchema(f.Type.Kind)
+		for _, d := range f.Type.Directives {
+			args := d.Positional()
+			if len(args) == 0 {
+				continue
+			}
+			// Decimals are strings, whose min and max would bound their length
+			numeric := f.Type.Kind != fld.KindDecimal
+			switch {
+			case d.Kind == directive.DirLength:
+				schema += fmt.Sprintf(".max(%s)", args[0].Source())
+			case d.Kind == directive.DirMin && numeric:
+				schema += fmt.Sprintf(".min(%s)", args[0].Source())
+			case d.Kind == directive.DirMax && numeric:
+				schema += fmt.Sprintf(".max(%s)", args[0].Source())
+			}
+		}
+	}
+	if f.IsArray {
+		schema = "z.array(" + schema + ")"
+	}
+	return schema
+}
+
+// scalarSchema returns the Zod schema of a field kind
+func scalarSchema(kind fld.FieldKind) string {
+	switch kind {
+	case fld.KindInt, fld.KindBigInt:
+		return "z.number().int()"
+	case fld.KindFloat:
+		return "z.number()"
+	case fld.KindBoolean:
+		return "z.boolean()"
+	case fld.KindDateTime, fld.KindTimestamp, fld.KindDate:
+		// Accepts Date objects as well as the ISO strings of a JSON payload
+		return "z.coerce.date()"
+	case fld.KindUUID:
+		return "z.string().uuid()"
+	case fld.KindCUID:
+		return "z.string().cuid()"
+	case fld.KindJSON:
+		return "z.unknown()"
+	case fld.KindPoint:
+		return "z.object({ x: z.number(), y: z.number() })"
+	default:
+		return "z.string()"
+	}
+}
+
+// relationSchema returns the Zod schema of a relation field, deferred with
+// z.lazy since related models may be declared later or refer back
+func relationSchema(f ir.IRField) string {
+	schema := f.Type.ModelName + "Schema"
+	switch {
+	case f.IsArray:
+		schema = "z.array(" + schema + ")"
+	case f.IsNullable():
+		schema += ".nullable()"
+	}
+	return "z.lazy(() => " + schema + ").optional()"
+}
+
+// writeDoc writes a doc comment as a JSDoc block
+func writeDoc(b *strings.Builder, indent, doc string) {
+	if doc == "" {
+		return
+	}
+	doc = strings.ReplaceAll(doc, "*/", "*\\/")
+	if !strings.Contains(doc, "\n") {
+		fmt.Fprintf(b, "%s/** %s */\n", indent, doc)
+		return
+	}
+	fmt.Fprintf(b, "%s/**\n", indent)
+	for _, line := range strings.Split(doc, "\n") {
+		fmt.Fprintf(b, "%s * %s\n", indent, line)
+	}
+	fmt.Fprintf(b, "%s */\n", indent)
+}
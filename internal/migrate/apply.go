@@ -0,0 +1,234 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// TableName is the table recording the applied migrations
+const TableName = "storm_migrations"
+
+// Applied is a migration the database recorded as applied
+type Applied struct {
+	Name      string
+	Checksum  string // Checksum of the up script that ran
+	AppliedAt time.Time
+	Down      string // Down script at the time it ran, empty when not recorded
+}
+
+// UpOptions configures Up
+type UpOptions struct {
+	AcceptDataLoss bool // Apply migrations despite the hazards found in the data
+	Limit          int  // Apply at most this many migrations, all when 0
+}
+
+// Up applies the migrations the database has not recorded, in order, and
+// returns those it applied. Before each migration the checks of its hazards
+// run against the data; it stops with a *DataLossError at the first
+// migration whose hazards hold, unless opts or the migration accept them.
+// A baseline written by Squash is only recorded on databases that applied
+// the migrations it replaces.
+func Up(ctx context.Context, exec Executor, d dialect.Dialect, migrations []*Migration, opts UpOptions) ([]*Migration, error) {
+	applied, err := exec.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[string]bool)
+	for _, a := range applied {
+		done[a.Name] = true
+	}
+
+	var ran []*Migration
+	for i, m := range migrations {
+		if done[m.Name] {
+			continue
+		}
+		if opts.Limit > 0 && len(ran) == opts.Limit {
+			break
+		}
+
+		if replaces := m.Replaces(); len(replaces) > 0 {
+			recorded := 0
+			for _, name := range replaces {
+				if done[name] {
+					recorded++
+				}
+			}
+			switch recorded {
+			case 0:
+			case len(replaces):
+				if err := exec.Record(ctx, m, replaces); err != nil {
+					return ran, fmt.Errorf("migration %s: %w", m.Name, err)
+				}
+				ran = append(ran, m)
+				continue
+			default:
+				return ran, fmt.Errorf("migration %s: the database applied %d of the %d migrations it replaces; apply the rest from before the squash first",
+					m.Name, recorded, len(replaces))
+			}
+		}
+
+		if !opts.AcceptDataLoss && !m.AcceptsDataLoss() {
+			hazards, err := Confirm(ctx, exec, hazardsOf(migrations, i, d))
+			if err != nil {
+				return ran, fmt.Errorf("migration %s: %w", m.Name, err)
+			}
+			if len(hazards) > 0 {
+				return ran, &DataLossError{Migration: m.Name, Hazards: hazards}
+			}
+		}
+
+		if err := exec.Apply(ctx, m); err != nil {
+			return ran, fmt.Errorf("migration %s: %w", m.Name, err)
+		}
+		ran = append(ran, m)
+	}
+	return ran, nil
+}
+
+// hazardsOf returns the hazards of the i-th migration. Those of a phase of
+// an online plan are the hazards of the plan the phase runs the statements
+// of, found by diffing from the schema before its first phase.
+func hazardsOf(migrations []*Migration, i int, d dialect.Dialect) []Hazard {
+	m := migrations[i]
+	n, phase, ok := m.phase()
+	if !ok {
+		from := previous(migrations, i)
+		return Analyze(from, m.Schema, Diff(from, m.Schema, d), d)
+	}
+
+	from := previous(migrations, max(i-n+1, 0))
+	plan := Diff(from, m.Schema, d)
+	// The expand phase runs the renames
+	plan.renamesRan = phase != PhaseExpand
+	var hazards []Hazard
+	for _, h := range Analyze(from, m.Schema, plan, d) {
+		if hazardPhase(h, d) == phase {
+			hazards = append(hazards, h)
+		}
+	}
+	return hazards
+}
+
+// Down reverts the last n applied migrations, newest first, and returns
+// those it reverted. Each runs the down script recorded when it was applied,
+// or that of its directory for migrations recorded without one.
+func Down(ctx context.Context, exec Executor, migrations []*Migration, n int) ([]Applied, error) {
+	applied, err := exec.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*Migration)
+	for _, m := range migrations {
+		byName[m.Name] = m
+	}
+
+	var reverted []Applied
+	for i := len(applied) - 1; i >= 0 && len(reverted) < n; i-- {
+		a := applied[i]
+		down := a.Down
+		if down == "" {
+			m, ok := byName[a.Name]
+			if !ok || m.Down == "" {
+				return reverted, fmt.Errorf("migration %s: no down script recorded or found", a.Name)
+			}
+			down = m.Down
+		}
+		if err := exec.Revert(ctx, a, down); err != nil {
+			return reverted, fmt.Errorf("migration %s: %w", a.Name, err)
+		}
+		reverted = append(reverted, a)
+	}
+	return reverted, nil
+}
+
+// State is the state of a migration in a database
+type State int
+
+const (
+	StatePending  State = iota // Not applied yet
+	StateApplied               // Applied as it is
+	StateModified              // Applied, but its up script changed since
+	StateMissing               // Applied, but its directory is gone
+)
+
+func (s State) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateApplied:
+		return "applied"
+	case StateModified:
+		return "modified"
+	case StateMissing:
+		return "missing"
+	}
+	return "unknown"
+}
+
+// MigrationStatus is the state of one migration
+type MigrationStatus struct {
+	Name      string
+	State     State
+	AppliedAt time.Time // Zero for pending migrations
+}
+
+// Status compares the migrations with those the database recorded. Applied
+// migrations come first in the order they were applied, followed by the
+// pending ones. The recorded migrations a baseline replaced are left out; once
+// all of them are recorded the baseline counts as applied, as Up would only
+// record it, and takes the place of the last.
+func Status(ctx context.Context, exec Executor, migrations []*Migration) ([]MigrationStatus, error) {
+	applied, err := exec.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recorded := make(map[string]bool)
+	for _, a := range applied {
+		recorded[a.Name] = true
+	}
+	byName := make(map[string]*Migration)
+	replaced := make(map[string]*Migration) // Baselines by the names they replace
+	for _, m := range migrations {
+		byName[m.Name] = m
+		for _, name := range m.Replaces() {
+			replaced[name] = m
+		}
+	}
+
+	var statuses []MigrationStatus
+	done := make(map[string]bool)
+	left := make(map[string]int) // Replaced migrations of a baseline not yet seen
+	for _, a := range applied {
+		done[a.Name] = true
+		m, ok := byName[a.Name]
+		state := StateApplied
+		switch {
+		case !ok && replaced[a.Name] != nil:
+			baseline := replaced[a.Name]
+			if _, seen := left[baseline.Name]; !seen {
+				left[baseline.Name] = len(baseline.Replaces())
+			}
+			left[baseline.Name]--
+			if left[baseline.Name] == 0 && !recorded[baseline.Name] {
+				done[baseline.Name] = true
+				statuses = append(statuses, MigrationStatus{Name: baseline.Name, State: StateApplied, AppliedAt: a.AppliedAt})
+			}
+			continue
+		case !ok:
+			state = StateMissing
+		case m.Checksum() != a.Checksum:
+			state = StateModified
+		}
+		statuses = append(statuses, MigrationStatus{Name: a.Name, State: state, AppliedAt: a.AppliedAt})
+	}
+	for _, m := range migrations {
+		if !done[m.Name] {
+			statuses = append(statuses, MigrationStatus{Name: m.Name, State: StatePending})
+		}
+	}
+	return statuses, nil
+}
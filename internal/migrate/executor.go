@@ -0,0 +1,256 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pixperk/storm/internal/database"
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// Executor runs the scripts of migrations against a database and keeps the
+// record of those applied. Up, Down, Status and Confirm only reach the
+// database through it, so tests can supply their own.
+type Executor interface {
+	// Applied returns the recorded migrations in the order they apply
+	Applied(ctx context.Context) ([]Applied, error)
+	// Apply runs the up script of the migration and records it, with its
+	// down script
+	Apply(ctx context.Context, m *Migration) error
+	// Record records the migration as applied without running it, removing
+	// the records of the migrations it replaces
+	Record(ctx context.Context, m *Migration, replaces []string) error
+	// Revert runs the down script of the applied migration and removes its record
+	Revert(ctx context.Context, a Applied, down string) error
+	// Exists reports whether the query returns a row
+	Exists(ctx context.Context, query string) (bool, error)
+}
+
+// NewExecutor returns the executor running migrations on db. The table
+// recording them is created the first time Applied is called.
+func NewExecutor(db *sql.DB, d dialect.Dialect) Executor {
+	return &sqlExecutor{db: db, d: d}
+}
+
+type sqlExecutor struct {
+	db *sql.DB
+	d  dialect.Dialect
+}
+
+// execer is satisfied by *sql.DB, *sql.Conn and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// ensureTable creates the table recording applied migrations. Tables created
+// before down scripts were recorded get the column added.
+func (e *sqlExecutor) ensureTable(ctx context.Context) error {
+	d := e.d
+	timestamp := "TIMESTAMP"
+	if d == dialect.MySQL {
+		timestamp = "DATETIME"
+	}
+	_, err := e.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+  %s VARCHAR(255) NOT NULL PRIMARY KEY,
+  %s VARCHAR(64) NOT NULL,
+  %s %s NOT NULL,
+  %s TEXT
+)`, d.QuoteIdent(TableName), d.QuoteIdent("name"), d.QuoteIdent("checksum"), d.QuoteIdent("applied_at"), timestamp, d.QuoteIdent("down")))
+	if err != nil {
+		return err
+	}
+
+	// Qualified, as SQLite reads an unknown quoted column as a string
+	rows, err := e.db.QueryContext(ctx, fmt.Sprintf("SELECT %s.%s FROM %s WHERE 1 = 0",
+		d.QuoteIdent(TableName), d.QuoteIdent("down"), d.QuoteIdent(TableName)))
+	if err != nil {
+		_, err = e.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s TEXT", d.QuoteIdent(TableName), d.QuoteIdent("down")))
+		return err
+	}
+	return rows.Close()
+}
+
+func (e *sqlExecutor) Applied(ctx context.Context) ([]Applied, error) {
+	if err := e.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	d := e.d
+	rows, err := e.db.QueryContext(ctx, fmt.Sprintf("SELECT %s, %s, %s, %s FROM %s ORDER BY %s",
+		d.QuoteIdent("name"), d.QuoteIdent("checksum"), d.QuoteIdent("applied_at"), d.QuoteIdent("down"),
+		d.QuoteIdent(TableName), d.QuoteIdent("name")))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applied []Applied
+	for rows.Next() {
+		var a Applied
+		var down sql.NullString
+		if err := rows.Scan(&a.Name, &a.Checksum, &a.AppliedAt, &down); err != nil {
+			return nil, err
+		}
+		a.Down = down.String
+		applied = append(applied, a)
+	}
+	return applied, rows.Err()
+}
+
+func (e *sqlExecutor) Apply(ctx context.Context, m *Migration) error {
+	return e.run(ctx, m.Up, func(db execer) error { return e.record(ctx, db, m) })
+}
+
+func (e *sqlExecutor) Record(ctx context.Context, m *Migration, replaces []string) error {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, name := range replaces {
+		if err := e.forget(ctx, tx, name); err != nil {
+			return err
+		}
+	}
+	if err := e.record(ctx, tx, m); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (e *sqlExecutor) Revert(ctx context.Context, a Applied, down string) error {
+	return e.run(ctx, down, func(db execer) error { return e.forget(ctx, db, a.Name) })
+}
+
+func (e *sqlExecutor) Exists(ctx context.Context, query string) (bool, error) {
+	rows, err := e.db.QueryContext(ctx, query)
+	if err != nil {
+		return false, err
+	}
+	found := rows.Next()
+	rows.Close()
+	return found, rows.Err()
+}
+
+// run runs a script and then its bookkeeping, in one transaction where the
+// dialect has transactional DDL and the script does not carry the
+// NoTransaction line
+func (e *sqlExecutor) run(ctx context.Context, script string, then func(execer) error) error {
+	switch {
+	case e.d == dialect.SQLite:
+		return e.runSQLite(ctx, script, then)
+	case e.d == dialect.MySQL || hasLine(script, NoTransaction):
+		// MySQL commits every DDL statement on its own
+		if err := run(ctx, e.db, script); err != nil {
+			return err
+		}
+		return then(e.db)
+	}
+
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := run(ctx, tx, script); err != nil {
+		return err
+	}
+	if err := then(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// runSQLite runs a script with foreign keys off, as rebuilding a table
+// drops it while other tables still reference it, and checks them before
+// committing
+func (e *sqlExecutor) runSQLite(ctx context.Context, script string, then func(execer) error) error {
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The pragma cannot change inside a transaction
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := run(ctx, tx, script); err != nil {
+		return err
+	}
+
+	var table string
+	var rowid sql.NullInt64
+	var parent string
+	var fkid int
+	err = tx.QueryRowContext(ctx, "PRAGMA foreign_key_check").Scan(&table, &rowid, &parent, &fkid)
+	switch {
+	case err == nil:
+		return fmt.Errorf("row %d of %s references a missing row of %s", rowid.Int64, table, parent)
+	case err != sql.ErrNoRows:
+		return err
+	}
+
+	if err := then(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// run executes a script. Scripts with NoTransaction or Batch lines run one
+// statement at a time, batches until they affect no rows; others at once.
+func run(ctx context.Context, db execer, script string) error {
+	stmts := split(script)
+	if len(stmts) == 0 {
+		return nil
+	}
+	if !hasLine(script, NoTransaction) && !hasLine(script, Batch) {
+		_, err := db.ExecContext(ctx, script)
+		return err
+	}
+	for _, stmt := range stmts {
+		for {
+			result, err := db.ExecContext(ctx, stmt)
+			if err != nil {
+				return err
+			}
+			if !hasLine(stmt, Batch) {
+				break
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// record marks the migration as applied
+func (e *sqlExecutor) record(ctx context.Context, db execer, m *Migration) error {
+	d := e.d
+	_, err := db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s) VALUES (%s, %s, %s, %s)",
+		d.QuoteIdent(TableName), d.QuoteIdent("name"), d.QuoteIdent("checksum"), d.QuoteIdent("applied_at"), d.QuoteIdent("down"),
+		database.Placeholder(d, 1), database.Placeholder(d, 2), database.Placeholder(d, 3), database.Placeholder(d, 4)),
+		m.Name, m.Checksum(), time.Now().UTC(), m.Down)
+	return err
+}
+
+// forget removes the record of a migration
+func (e *sqlExecutor) forget(ctx context.Context, db execer, name string) error {
+	d := e.d
+	_, err := db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = %s",
+		d.QuoteIdent(TableName), d.QuoteIdent("name"), database.Placeholder(d, 1)), name)
+	return err
+}
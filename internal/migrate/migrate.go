@@ -0,0 +1,282 @@
+// Package migrate creates and applies schema migrations.
+//
+// A migration is a directory named <timestamp>_<name> under the migrations
+// directory holding up.sql, down.sql and schema.json, the JSON form of the
+// IR the migration produces. A new migration is planned by diffing the
+// schema against the snapshot of the latest migration, so migrations can be
+// created without a database at hand. Applied migrations are recorded in the
+// storm_migrations table of the database.
+package migrate
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pixperk/storm/internal/generator/ddl"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// File names inside a migration directory
+const (
+	UpFile     = "up.sql"
+	DownFile   = "down.sql"
+	SchemaFile = "schema.json"
+)
+
+// Migration is a migration directory
+type Migration struct {
+	Name   string // <timestamp>_<name>
+	Dir    string
+	Up     string // Script applying the migration
+	Down   string // Script reverting it
+	Schema *ir.IR // Schema once the migration is applied
+	// Suggestions lists the dropped and added columns and tables that look
+	// like renames, as found by Suggest when Create wrote the migration
+	Suggestions []string
+}
+
+// AcceptsDataLoss reports whether the up script carries the AcceptDataLoss line
+func (m *Migration) AcceptsDataLoss() bool {
+	return hasLine(m.Up, AcceptDataLoss)
+}
+
+// Replaces returns the migrations a baseline written by Squash replaces,
+// listed on its Squashed lines
+func (m *Migration) Replaces() []string {
+	return markedWith(m.Up, Squashed)
+}
+
+// phase returns the position, from 1, and name of the online phase the
+// migration was written for, listed on its OnlinePhase line
+func (m *Migration) phase() (int, string, bool) {
+	marked := markedWith(m.Up, OnlinePhase)
+	if len(marked) == 0 {
+		return 0, "", false
+	}
+	pos, name, _ := strings.Cut(marked[0], " ")
+	n, err := strconv.Atoi(pos)
+	if err != nil || n < 1 {
+		return 0, "", false
+	}
+	return n, name, true
+}
+
+// Checksum returns the SHA-256 of the up script, recorded when it is applied
+func (m *Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// Load reads the migrations of dir in the order they apply. A missing
+// directory holds no migrations.
+func Load(dir string) ([]*Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []*Migration
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		m, err := loadMigration(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Name < migrations[j].Name })
+	return migrations, nil
+}
+
+func loadMigration(dir string) (*Migration, error) {
+	m := &Migration{Name: filepath.Base(dir), Dir: dir}
+
+	up, err := os.ReadFile(filepath.Join(dir, UpFile))
+	if err != nil {
+		return nil, err
+	}
+	m.Up = string(up)
+
+	down, err := os.ReadFile(filepath.Join(dir, DownFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	m.Down = string(down)
+
+	snapshot, err := os.ReadFile(filepath.Join(dir, SchemaFile))
+	if err != nil {
+		return nil, err
+	}
+	m.Schema, err = ir.DecodeJSON(bytes.NewReader(snapshot))
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Snapshot returns the schema the migrations produce, empty when there are none
+func Snapshot(migrations []*Migration) *ir.IR {
+	if len(migrations) == 0 {
+		return &ir.IR{}
+	}
+	return migrations[len(migrations)-1].Schema
+}
+
+// previous returns the schema migration i starts from
+func previous(migrations []*Migration, i int) *ir.IR {
+	return Snapshot(migrations[:i])
+}
+
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// migrationName returns the name of a migration created at t
+func migrationName(t time.Time, name string) string {
+	return t.UTC().Format("20060102150405") + "_" + slug(name)
+}
+
+// slug returns the name in lower case with runs of other characters than
+// letters and digits replaced by an underscore
+func slug(name string) string {
+	s := strings.Trim(strings.ToLower(unsafeName.ReplaceAllString(name, "_")), "_")
+	if s == "" {
+		return "migration"
+	}
+	return s
+}
+
+// Create writes a migration to dir moving the schema of the existing
+// migrations to irData. It returns a nil migration when nothing changed, and
+// the hazards of the change, which up.sql lists in its header.
+func Create(dir, name string, migrations []*Migration, irData *ir.IR, d dialect.Dialect, now time.Time) (*Migration, []Hazard, error) {
+	from := Snapshot(migrations)
+	plan := Diff(from, irData, d)
+	if plan.Empty() {
+		return nil, nil, nil
+	}
+	hazards := Analyze(from, irData, plan, d)
+
+	m := &Migration{Name: migrationName(now, name), Schema: irData, Suggestions: Suggest(plan)}
+	m.Dir = filepath.Join(dir, m.Name)
+	m.Up = header(m.Name, hazards, m.Suggestions) + ddl.Render(plan.Statements)
+	m.Down = "-- Reverts " + m.Name + "\n\n" + ddl.Render(diff(irData, from, d, invert(plan)).Statements)
+	if err := write(m); err != nil {
+		return nil, nil, err
+	}
+	return m, hazards, nil
+}
+
+// CreateOnline is Create for large PostgreSQL and MySQL tables: it writes
+// the phases of OnlinePhases as migrations applied one after the other. They
+// all carry the new schema; each lists the hazards of the statements it
+// runs, which Up checks before applying it.
+func CreateOnline(dir, name string, migrations []*Migration, irData *ir.IR, d dialect.Dialect, now time.Time) ([]*Migration, []Hazard, error) {
+	from := Snapshot(migrations)
+	plan := Diff(from, irData, d)
+	if plan.Empty() {
+		return nil, nil, nil
+	}
+	hazards := Analyze(from, irData, plan, d)
+	phases, err := OnlinePhases(plan, diff(irData, from, d, invert(plan)), d)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var created []*Migration
+	for i, phase := range phases {
+		// A second apart, so the phases sort in order
+		m := &Migration{Name: migrationName(now.Add(time.Duration(i)*time.Second), name+"_"+phase.Name), Schema: irData}
+		m.Dir = filepath.Join(dir, m.Name)
+		if i == 0 {
+			m.Suggestions = Suggest(plan)
+		}
+		var own []Hazard
+		for _, h := range hazards {
+			if hazardPhase(h, d) == phase.Name {
+				own = append(own, h)
+			}
+		}
+		m.Up = header(m.Name, own, m.Suggestions)
+		m.Up = fmt.Sprintf("%s-- Phase %d of %d, %s: %s\n%s %d %s\n\n", m.Up, i+1, len(phases), phase.Name, phase.Summary, OnlinePhase, i+1, phase.Name)
+		m.Up += script(phase.Statements)
+
+		m.Down = "-- Reverts " + m.Name + "\n\n"
+		if len(phase.Down) == 0 {
+			m.Down += "-- Nothing to revert\n"
+		} else {
+			m.Down += script(phase.Down)
+		}
+		if err := write(m); err != nil {
+			return nil, nil, err
+		}
+		created = append(created, m)
+	}
+	return created, hazards, nil
+}
+
+// script renders statements, run outside a transaction when they need it
+func script(stmts []string) string {
+	if noTransaction(stmts) {
+		return NoTransaction + "\n\n" + ddl.Render(stmts)
+	}
+	return ddl.Render(stmts)
+}
+
+// write writes the files of a migration into its directory
+func write(m *Migration) error {
+	// The snapshot goes into version control; keep credentials out of it
+	stored := *m.Schema
+	stored.DatabaseURL = ""
+	var snapshot bytes.Buffer
+	if err := ir.EncodeJSON(&snapshot, &stored); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	files := map[string][]byte{UpFile: []byte(m.Up), DownFile: []byte(m.Down), SchemaFile: snapshot.Bytes()}
+	for file, data := range files {
+		if err := os.WriteFile(filepath.Join(m.Dir, file), data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// header renders the comment opening an up script, listing the hazards and
+// the suggested renames
+func header(name string, hazards []Hazard, suggestions []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Migration %s\n", name)
+	if len(suggestions) > 0 {
+		b.WriteString("--\n-- Possible renames:\n")
+		for _, s := range suggestions {
+			fmt.Fprintf(&b, "--   - %s\n", s)
+		}
+	}
+	if len(hazards) > 0 {
+		b.WriteString("--\n-- This migration can lose data or fail on existing rows:\n")
+		for _, h := range hazards {
+			fmt.Fprintf(&b, "--   - %s\n", h)
+		}
+		b.WriteString("-- Once reviewed, uncomment the next line or pass -accept-data-loss to storm migrate up.\n")
+		fmt.Fprintf(&b, "-- %s\n", AcceptDataLoss)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
@@ -0,0 +1,456 @@
+package migrate
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pixperk/storm/internal/catalog"
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// Phases of an online migration, suffixed to the names of its migrations
+const (
+	PhaseExpand   = "expand"
+	PhaseBackfill = "backfill"
+	PhaseContract = "contract"
+)
+
+// BatchSize is the number of rows a backfill statement updates at a time
+const BatchSize = 1000
+
+// shadowPrefix names the column a column changing type is copied into
+const shadowPrefix = "_storm_"
+
+// Phase is one of the migrations an online plan is split into
+type Phase struct {
+	Name       string
+	Summary    string // What the phase does, for the header of its script
+	Statements []string
+	Down       []string
+}
+
+// online collects the statements of the phases of an online plan
+type online struct {
+	p *Plan
+	d dialect.Dialect
+
+	expand, expandDown []string
+	backfill           []string
+	contract           []string
+}
+
+// step adds a statement to the expand phase with the statements undoing it
+func (o *online) step(up string, down ...string) {
+	o.steps([]string{up}, down)
+}
+
+// steps adds statements to the expand phase with the statements undoing them
+func (o *online) steps(up, down []string) {
+	o.expand = append(o.expand, up...)
+	// The down script runs the steps in reverse
+	o.expandDown = append(slices.Clone(down), o.expandDown...)
+}
+
+// OnlinePhases splits a PostgreSQL or MySQL plan into migrations that avoid
+// long locks on large tables, applied in turn while the application keeps
+// running:
+//
+//   - expand adds tables, columns, indexes and constraints without touching
+//     the rows: indexes are built concurrently, or in place without locking
+//     on MySQL, NOT NULL and foreign keys start as NOT VALID constraints and
+//     a column changing type gets a shadow column kept in sync by triggers
+//   - backfill copies the rows into the shadow columns in batches of
+//     BatchSize and validates the constraints
+//   - contract swaps the shadow columns in, turns the validated constraints
+//     into NOT NULL and drops what the schema no longer has
+//
+// It fails on changes of type no form of which avoids rewriting the table
+// under lock, such as the type of a key or indexed column. back is the plan
+// reverting p; phases with nothing to do are left out.
+func OnlinePhases(p, back *Plan, d dialect.Dialect) ([]Phase, error) {
+	if d != dialect.Postgres && d != dialect.MySQL {
+		return nil, fmt.Errorf("online migrations need PostgreSQL or MySQL, not %s", d)
+	}
+	o := &online{p: p, d: d}
+
+	var added, dropped []*catalog.Table
+	var dropIndexes, dropColumns []string
+	redefined := make(map[string]bool)
+	for _, c := range p.Changes {
+		if c.Kind == catalog.AddIndex {
+			redefined[c.Table.Name+"."+c.Index.Name] = true
+		}
+	}
+
+	for _, c := range p.Changes {
+		switch c.Kind {
+		case catalog.RenameTable:
+			o.expand = append(o.expand, renameTable(p, c.Table, c.OldName, d)...)
+		case catalog.RenameColumn:
+			o.expand = append(o.expand, renameColumn(c.Table, c.Column, c.OldName, d)...)
+		}
+	}
+	// The renames are undone by the renames of the reverting plan, once the
+	// other steps are
+	for _, c := range back.Changes {
+		switch c.Kind {
+		case catalog.RenameTable:
+			o.expandDown = append(o.expandDown, renameTable(back, c.Table, c.OldName, d)...)
+		case catalog.RenameColumn:
+			o.expandDown = append(o.expandDown, renameColumn(c.Table, c.Column, c.OldName, d)...)
+		}
+	}
+
+	for _, c := range p.Changes {
+		switch c.Kind {
+		case catalog.DropForeignKey:
+			// Dropping a key only relaxes the schema, so it goes first
+			old, _ := p.From.Table(c.Table.Name)
+			o.step(dropForeignKey(old, c.ForeignKey, d), addForeignKey(c.Table, c.ForeignKey, d))
+		case catalog.DropIndex:
+			if redefined[c.Table.Name+"."+c.Index.Name] {
+				// The new definition takes the name, so the old one goes first
+				o.step(o.dropIndex(c.Table, c.Index), createIndex(c.Table, c.Index, d))
+			} else {
+				dropIndexes = append(dropIndexes, dropIndex(c.Table, c.Index, d))
+			}
+		case catalog.AddTable:
+			added = append(added, c.Table)
+		case catalog.DropTable:
+			dropped = append(dropped, c.Table)
+		case catalog.DropColumn:
+			dropColumns = append(dropColumns, dropColumn(c.Table, c.Column, d))
+		}
+	}
+
+	for _, t := range dependencyOrder(added) {
+		o.step(createTable(t, t.Name, d), "DROP TABLE "+d.QuoteIdent(t.Name))
+		for _, idx := range t.Indexes {
+			o.expand = append(o.expand, createIndex(t, idx, d))
+		}
+	}
+
+	for _, c := range p.Changes {
+		switch c.Kind {
+		case catalog.AddColumn:
+			o.addColumn(c.Table, c.Column)
+		case catalog.AlterColumn:
+			if err := o.alterColumn(c.Table, c.Previous, c.Column); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, c := range p.Changes {
+		switch c.Kind {
+		case catalog.AlterPrimaryKey:
+			o.alterPrimaryKey(c.Table, c.PreviousKey)
+		case catalog.AddIndex:
+			o.step(o.createIndex(createIndex(c.Table, c.Index, d)), o.dropIndex(c.Table, c.Index))
+		case catalog.AddForeignKey:
+			o.addForeignKey(c.Table, c.ForeignKey)
+		}
+	}
+
+	o.contract = append(o.contract, dropIndexes...)
+	o.contract = append(o.contract, dropColumns...)
+	order := dependencyOrder(dropped)
+	for i := len(order) - 1; i >= 0; i-- {
+		o.contract = append(o.contract, "DROP TABLE "+d.QuoteIdent(order[i].Name))
+	}
+
+	all := []Phase{
+		{
+			Name:       PhaseExpand,
+			Summary:    "adds the new tables, columns, indexes and constraints; the current code keeps working",
+			Statements: o.expand,
+			Down:       o.expandDown,
+		},
+		{
+			Name:       PhaseBackfill,
+			Summary:    "copies existing rows in batches and validates the new constraints",
+			Statements: o.backfill,
+		},
+		{
+			Name:    PhaseContract,
+			Summary: "switches to the new columns and drops what the schema no longer has; apply it once the code uses the new schema",
+			// Reverting the contract phase returns to the old schema, then
+			// redoes the expand phase the earlier down scripts undo
+			Statements: o.contract,
+			Down:       append(slices.Clone(back.Statements), o.expand...),
+		},
+	}
+	var phases []Phase
+	for _, phase := range all {
+		if len(phase.Statements) > 0 {
+			phases = append(phases, phase)
+		}
+	}
+	return phases, nil
+}
+
+// addColumn adds a column that may not be null or must be unique as a
+// nullable column, with the constraints following as for altered columns
+func (o *online) addColumn(t *catalog.Table, col *catalog.Column) {
+	relaxed := *col
+	if !col.AutoIncrement {
+		relaxed.Unique = false
+		if col.Default == "" {
+			relaxed.Nullable = true
+		}
+	}
+	o.step(addColumn(t, &relaxed, o.d), dropColumn(t, col, o.d))
+	o.tighten(t, &relaxed, col)
+}
+
+// alterColumn splits the change of a column into the steps relaxing it,
+// done in the expand phase, and those tightening it. A change of type goes
+// through a shadow column, and fails where one cannot replace the column.
+func (o *online) alterColumn(t *catalog.Table, from, to *catalog.Column) error {
+	if from.Type != to.Type || from.AutoIncrement != to.AutoIncrement {
+		if reason := o.inPlace(t, from, to); reason != "" {
+			return fmt.Errorf("%s.%s %s, so changing its type rewrites the table under lock; change it in a migration created without -online",
+				t.Name, to.Name, reason)
+		}
+		o.shadow(t, from, to)
+		return nil
+	}
+
+	relaxed := *from
+	relaxed.Default = to.Default
+	relaxed.Nullable = from.Nullable || to.Nullable
+	relaxed.Unique = from.Unique && to.Unique
+	if relaxed != *from {
+		o.steps(alterColumn(t, from, &relaxed, o.d), alterColumn(t, &relaxed, from, o.d))
+	}
+	o.tighten(t, &relaxed, to)
+	return nil
+}
+
+// inPlace returns why a column cannot change type through a shadow column,
+// empty when it can
+func (o *online) inPlace(t *catalog.Table, from, to *catalog.Column) string {
+	old, _ := o.p.From.Table(t.Name)
+	switch {
+	case from.AutoIncrement || to.AutoIncrement:
+		return "is auto-incremented"
+	case from.Unique || to.Unique || usesColumn(old, from.Name):
+		return "is part of a key or index"
+	}
+	for _, other := range o.p.From.Tables {
+		for _, fk := range other.ForeignKeys {
+			if fk.References == t.Name && slices.Contains(fk.Referenced, from.Name) {
+				return "is referenced by a foreign key of " + other.Name
+			}
+		}
+	}
+	return ""
+}
+
+// hazardPhase returns the phase of an online plan running the statement a
+// hazard is about, which is when its check must run
+func hazardPhase(h Hazard, d dialect.Dialect) string {
+	switch h.Change.Kind {
+	case catalog.DropTable, catalog.DropColumn:
+		return PhaseContract
+	case catalog.AddIndex:
+		return PhaseExpand
+	case catalog.AlterPrimaryKey:
+		// PostgreSQL builds the index of the new key up front
+		if d == dialect.Postgres {
+			return PhaseExpand
+		}
+		return PhaseContract
+	}
+	switch h.aspect {
+	case aspectType:
+		// The backfill copies the values into the shadow column
+		return PhaseBackfill
+	case aspectUnique:
+		return PhaseExpand
+	}
+	// NOT NULL, of added and altered columns alike, is validated by the
+	// PostgreSQL backfill and set by the MySQL contract phase
+	if d == dialect.Postgres {
+		return PhaseBackfill
+	}
+	return PhaseContract
+}
+
+// tighten adds the NOT NULL and UNIQUE constraints of column to the column
+// as it is after the expand phase
+func (o *online) tighten(t *catalog.Table, from, to *catalog.Column) {
+	d := o.d
+	table, column := d.QuoteIdent(t.Name), d.QuoteIdent(to.Name)
+
+	if from.Nullable && !to.Nullable {
+		if d == dialect.MySQL {
+			after := *from
+			after.Nullable = false
+			o.contract = append(o.contract, inPlace(alterColumn(t, from, &after, d))...)
+		} else {
+			check := d.QuoteIdent(t.Name + "_" + to.Name + "_not_null")
+			o.step(fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s IS NOT NULL) NOT VALID", table, check, column),
+				fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, check))
+			o.backfill = append(o.backfill, fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", table, check))
+			// SET NOT NULL skips scanning the table once a valid check covers it
+			o.contract = append(o.contract,
+				fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", table, column),
+				fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, check))
+		}
+	}
+
+	if to.Unique && !from.Unique {
+		if d == dialect.MySQL {
+			// An inline UNIQUE constraint is an index named after the column
+			o.step(fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s) ALGORITHM=INPLACE LOCK=NONE", column, table, column),
+				fmt.Sprintf("DROP INDEX %s ON %s", column, table))
+		} else {
+			constraint := d.QuoteIdent(t.Name + "_" + to.Name + "_key")
+			o.step(fmt.Sprintf("CREATE UNIQUE INDEX CONCURRENTLY %s ON %s (%s)", constraint, table, column),
+				"DROP INDEX "+constraint)
+			o.contract = append(o.contract, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE USING INDEX %s", table, constraint, constraint))
+		}
+	}
+}
+
+// shadow changes the type of a column through a shadow column of the new
+// type: triggers copy every write into it, the backfill copies the existing
+// rows and the contract phase swaps the columns
+func (o *online) shadow(t *catalog.Table, from, to *catalog.Column) {
+	d := o.d
+	table, column := d.QuoteIdent(t.Name), d.QuoteIdent(to.Name)
+	shadow := d.QuoteIdent(shadowPrefix + to.Name)
+	sync := d.QuoteIdent(t.Name + "_" + to.Name + "_sync")
+
+	o.step(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, shadow, to.Type),
+		fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, shadow))
+
+	if d == dialect.MySQL {
+		for _, event := range []string{"INSERT", "UPDATE"} {
+			trigger := d.QuoteIdent(t.Name + "_" + to.Name + "_sync_" + strings.ToLower(event))
+			o.step(fmt.Sprintf("CREATE TRIGGER %s BEFORE %s ON %s FOR EACH ROW SET NEW.%s = NEW.%s", trigger, event, table, shadow, column),
+				"DROP TRIGGER "+trigger)
+			o.contract = append(o.contract, "DROP TRIGGER "+trigger)
+		}
+		o.backfill = append(o.backfill, fmt.Sprintf("%s\nUPDATE %s SET %s = %s WHERE %s IS NULL AND %s IS NOT NULL LIMIT %d",
+			Batch, table, shadow, column, shadow, column, BatchSize))
+
+		o.contract = append(o.contract,
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column),
+			fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, shadow, column))
+		if !to.Nullable || to.Default != "" {
+			swapped := *to
+			swapped.Nullable, swapped.Default = true, ""
+			o.contract = append(o.contract, inPlace(alterColumn(t, &swapped, to, d))...)
+		}
+		return
+	}
+
+	o.step(fmt.Sprintf("CREATE FUNCTION %s() RETURNS trigger LANGUAGE plpgsql AS $$\nBEGIN\n  NEW.%s := NEW.%s::%s;\n  RETURN NEW;\nEND\n$$", sync, shadow, column, to.Type),
+		fmt.Sprintf("DROP FUNCTION %s()", sync))
+	o.step(fmt.Sprintf("CREATE TRIGGER %s BEFORE INSERT OR UPDATE ON %s FOR EACH ROW EXECUTE FUNCTION %s()", sync, table, sync),
+		fmt.Sprintf("DROP TRIGGER %s ON %s", sync, table))
+	o.backfill = append(o.backfill, fmt.Sprintf("%s\nUPDATE %s SET %s = %s::%s WHERE ctid IN (SELECT ctid FROM %s WHERE %s IS NULL AND %s IS NOT NULL LIMIT %d)",
+		Batch, table, shadow, column, to.Type, table, shadow, column, BatchSize))
+	o.contract = append(o.contract,
+		fmt.Sprintf("DROP TRIGGER %s ON %s", sync, table),
+		fmt.Sprintf("DROP FUNCTION %s()", sync),
+		fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, column),
+		fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, shadow, column))
+	if to.Default != "" {
+		o.contract = append(o.contract, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s", table, column, to.Default))
+	}
+	if !to.Nullable {
+		// The check is on the shadow column and follows it through the rename
+		check := d.QuoteIdent(t.Name + "_" + to.Name + "_not_null")
+		o.step(fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s CHECK (%s IS NOT NULL) NOT VALID", table, check, shadow),
+			fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, check))
+		o.backfill = append(o.backfill, fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", table, check))
+		o.contract = append(o.contract,
+			fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL", table, column),
+			fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, check))
+	}
+}
+
+// alterPrimaryKey builds the index of a new PostgreSQL primary key
+// concurrently and swaps the keys in the contract phase
+func (o *online) alterPrimaryKey(t *catalog.Table, previous []string) {
+	d := o.d
+	if d == dialect.MySQL || len(t.PrimaryKey) == 0 {
+		o.contract = append(o.contract, alterPrimaryKey(t, previous, d)...)
+		return
+	}
+
+	table := d.QuoteIdent(t.Name)
+	index := d.QuoteIdent(t.Name + "_pkey_new")
+	o.step(fmt.Sprintf("CREATE UNIQUE INDEX CONCURRENTLY %s ON %s (%s)", index, table, quoteList(t.PrimaryKey, d)),
+		"DROP INDEX "+index)
+	if len(previous) > 0 {
+		o.contract = append(o.contract, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, d.QuoteIdent(t.Name+"_pkey")))
+	}
+	// The index is renamed after the constraint
+	o.contract = append(o.contract, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s PRIMARY KEY USING INDEX %s", table, d.QuoteIdent(t.Name+"_pkey"), index))
+}
+
+// addForeignKey adds a PostgreSQL foreign key without checking the rows,
+// which the backfill phase validates
+func (o *online) addForeignKey(t *catalog.Table, fk *catalog.ForeignKey) {
+	d := o.d
+	if d == dialect.MySQL {
+		old, _ := o.p.From.Table(t.Name)
+		o.step(addForeignKey(t, fk, d), dropForeignKey(old, fk, d))
+		return
+	}
+
+	table := d.QuoteIdent(t.Name)
+	// Named as PostgreSQL names the keys of the DDL generator
+	name := d.QuoteIdent(t.Name + "_" + fk.Columns[0] + "_fkey")
+	o.step(fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s NOT VALID", table, name, foreignKeyClause(fk, d)),
+		fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, name))
+	o.backfill = append(o.backfill, fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", table, name))
+}
+
+// createIndex turns a CREATE INDEX statement into one that does not block
+// writes while the index builds
+func (o *online) createIndex(stmt string) string {
+	if o.d == dialect.MySQL {
+		return stmt + " ALGORITHM=INPLACE LOCK=NONE"
+	}
+	for _, prefix := range []string{"CREATE INDEX ", "CREATE UNIQUE INDEX "} {
+		if rest, ok := strings.CutPrefix(stmt, prefix); ok {
+			return prefix + "CONCURRENTLY " + rest
+		}
+	}
+	return stmt
+}
+
+// dropIndex drops an index without blocking the queries using the table
+func (o *online) dropIndex(t *catalog.Table, idx *catalog.Index) string {
+	if o.d == dialect.MySQL {
+		return dropIndex(t, idx, o.d) + " ALGORITHM=INPLACE LOCK=NONE"
+	}
+	return "DROP INDEX CONCURRENTLY " + o.d.QuoteIdent(idx.Name)
+}
+
+// inPlace asks MySQL to alter the table without copying it or blocking writes
+func inPlace(stmts []string) []string {
+	out := make([]string, len(stmts))
+	for i, stmt := range stmts {
+		if strings.HasPrefix(stmt, "ALTER TABLE ") {
+			stmt += ", ALGORITHM=INPLACE, LOCK=NONE"
+		}
+		out[i] = stmt
+	}
+	return out
+}
+
+// noTransaction reports whether statements must run outside a transaction
+func noTransaction(stmts []string) bool {
+	for _, stmt := range stmts {
+		if strings.Contains(stmt, " CONCURRENTLY ") || hasLine(stmt, Batch) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,208 @@
+package migrate
+
+import (
+	"slices"
+
+	"github.com/pixperk/storm/internal/catalog"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// Plan is the difference between two schemas and the statements applying it
+type Plan struct {
+	// From is the catalog of the old schema with the renames of the plan
+	// applied, which the changes other than renames start from
+	From, To   *catalog.Catalog
+	Changes    []catalog.Change
+	Statements []string
+
+	renamed catalog.Renames // Renames the plan applies
+	// renamesRan tells that the renames already ran, as they have for the
+	// later phases of an online plan, so hazard checks use the new names
+	renamesRan bool
+}
+
+// Diff plans the migration of a database from schema from to schema to,
+// renaming the tables and columns to declares with @renamedFrom
+func Diff(from, to *ir.IR, d dialect.Dialect) *Plan {
+	return diff(from, to, d, renames(to, d))
+}
+
+func diff(from, to *ir.IR, d dialect.Dialect, r catalog.Renames) *Plan {
+	p := &Plan{To: catalog.FromIR(to, d)}
+	p.Changes, p.From = catalog.DiffRenamed(catalog.FromIR(from, d), p.To, r)
+	for _, c := range p.Changes {
+		switch c.Kind {
+		case catalog.RenameTable:
+			p.renamed.RenameTable(c.OldName, c.Table.Name)
+		case catalog.RenameColumn:
+			p.renamed.RenameColumn(c.Table.Name, c.OldName, c.Column.Name)
+		}
+	}
+	p.Statements = statements(p, d)
+	return p
+}
+
+// Empty reports whether the schemas are the same
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// statements renders the changes of a plan in an order every dialect accepts:
+// tables and columns are renamed first, then constraints and indexes that go
+// away are dropped and tables are created before the keys pointing at them
+func statements(p *Plan, d dialect.Dialect) []string {
+	rebuilt := make(map[string]bool)
+	if d == dialect.SQLite {
+		for _, c := range p.Changes {
+			if needsRebuild(p, c) {
+				rebuilt[c.Table.Name] = true
+			}
+		}
+	}
+
+	var renames, dropKeys, dropIndexes, creates, alters, rebuilds, dropColumns, addIndexes, addKeys []string
+	for _, c := range p.Changes {
+		switch c.Kind {
+		case catalog.RenameTable:
+			renames = append(renames, renameTable(p, c.Table, c.OldName, d)...)
+			continue
+		case catalog.RenameColumn:
+			renames = append(renames, renameColumn(c.Table, c.Column, c.OldName, d)...)
+			continue
+		}
+		if rebuilt[c.Table.Name] {
+			continue
+		}
+		switch c.Kind {
+		case catalog.DropForeignKey:
+			old, _ := p.From.Table(c.Table.Name)
+			dropKeys = append(dropKeys, dropForeignKey(old, c.ForeignKey, d))
+		case catalog.DropIndex:
+			dropIndexes = append(dropIndexes, dropIndex(c.Table, c.Index, d))
+		case catalog.AddColumn:
+			alters = append(alters, addColumn(c.Table, c.Column, d))
+		case catalog.AlterColumn:
+			alters = append(alters, alterColumn(c.Table, c.Previous, c.Column, d)...)
+		case catalog.AlterPrimaryKey:
+			alters = append(alters, alterPrimaryKey(c.Table, c.PreviousKey, d)...)
+		case catalog.DropColumn:
+			dropColumns = append(dropColumns, dropColumn(c.Table, c.Column, d))
+		case catalog.AddIndex:
+			addIndexes = append(addIndexes, createIndex(c.Table, c.Index, d))
+		case catalog.AddForeignKey:
+			addKeys = append(addKeys, addForeignKey(c.Table, c.ForeignKey, d))
+		}
+	}
+
+	var added, dropped []*catalog.Table
+	for _, c := range p.Changes {
+		switch c.Kind {
+		case catalog.AddTable:
+			added = append(added, c.Table)
+		case catalog.DropTable:
+			dropped = append(dropped, c.Table)
+		}
+	}
+	for _, t := range dependencyOrder(added) {
+		creates = append(creates, createTable(t, t.Name, d))
+		for _, idx := range t.Indexes {
+			creates = append(creates, createIndex(t, idx, d))
+		}
+	}
+	for _, t := range p.To.Tables {
+		if rebuilt[t.Name] {
+			old, _ := p.From.Table(t.Name)
+			rebuilds = append(rebuilds, rebuildTable(old, t)...)
+		}
+	}
+
+	var drops []string
+	order := dependencyOrder(dropped)
+	for i := len(order) - 1; i >= 0; i-- {
+		drops = append(drops, "DROP TABLE "+d.QuoteIdent(order[i].Name))
+	}
+
+	var stmts []string
+	for _, group := range [][]string{renames, dropKeys, dropIndexes, creates, alters, rebuilds, dropColumns, addIndexes, addKeys, drops} {
+		stmts = append(stmts, group...)
+	}
+	return stmts
+}
+
+// needsRebuild reports whether SQLite can only apply the change by copying
+// the table into a new one, lacking the ALTER TABLE form for it
+func needsRebuild(p *Plan, c catalog.Change) bool {
+	switch c.Kind {
+	case catalog.AlterColumn, catalog.AlterPrimaryKey, catalog.AddForeignKey, catalog.DropForeignKey:
+		return true
+	case catalog.AddColumn:
+		col := c.Column
+		return !col.Nullable && col.Default == "" || col.Unique || col.AutoIncrement || slices.Contains(c.Table.PrimaryKey, col.Name)
+	case catalog.DropColumn:
+		// DROP COLUMN refuses columns that keys or indexes use
+		old, _ := p.From.Table(c.Table.Name)
+		return c.Column.Unique || usesColumn(old, c.Column.Name)
+	}
+	return false
+}
+
+// usesColumn reports whether a key or index of the table covers the column
+func usesColumn(t *catalog.Table, name string) bool {
+	if slices.Contains(t.PrimaryKey, name) {
+		return true
+	}
+	for _, idx := range t.Indexes {
+		if slices.Contains(idx.Columns, name) {
+			return true
+		}
+	}
+	for _, fk := range t.ForeignKeys {
+		if slices.Contains(fk.Columns, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencyOrder orders tables so that referenced tables come before the
+// tables referencing them, keeping the given order otherwise
+func dependencyOrder(tables []*catalog.Table) []*catalog.Table {
+	pending := make(map[string]*catalog.Table)
+	for _, t := range tables {
+		pending[t.Name] = t
+	}
+
+	var order []*catalog.Table
+	for len(pending) > 0 {
+		progress := false
+		for _, t := range tables {
+			if pending[t.Name] == nil || !ready(t, pending) {
+				continue
+			}
+			order = append(order, t)
+			delete(pending, t.Name)
+			progress = true
+		}
+		if !progress {
+			// Tables referencing each other in a cycle keep their order
+			for _, t := range tables {
+				if pending[t.Name] != nil {
+					order = append(order, t)
+					delete(pending, t.Name)
+				}
+			}
+		}
+	}
+	return order
+}
+
+// ready reports whether every table t references, other than itself, is placed
+func ready(t *catalog.Table, pending map[string]*catalog.Table) bool {
+	for _, fk := range t.ForeignKeys {
+		if fk.References != t.Name && pending[fk.References] != nil {
+			return false
+		}
+	}
+	return true
+}
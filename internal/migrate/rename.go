@@ -0,0 +1,288 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/pixperk/storm/internal/catalog"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	"github.com/pixperk/storm/internal/types/directive"
+)
+
+// renames returns the tables and columns the @renamedFrom directives of the
+// schema rename. The schema is rewritten under the names it had before, so
+// the implicit foreign key columns and join tables named after a renamed
+// model or relation field are renamed along with it.
+func renames(to *ir.IR, d dialect.Dialect) catalog.Renames {
+	var r catalog.Renames
+	before := previousNames(to)
+	if before == nil {
+		return r
+	}
+
+	oldCatalog, newCatalog := catalog.FromIR(before, d), catalog.FromIR(to, d)
+	for i, model := range to.Models {
+		zip(&r, oldCatalog.Tables[i], newCatalog.Tables[i])
+		oldModel := before.Models[i]
+		for j, f := range model.Fields {
+			jt, ok := to.JoinTable(model, f)
+			if !ok || jt.Through {
+				continue
+			}
+			oldJT, ok := before.JoinTable(oldModel, oldModel.Fields[j])
+			if !ok || oldJT.Through {
+				continue
+			}
+			if oldJT.Name != jt.Name {
+				r.RenameTable(oldJT.Name, jt.Name)
+			}
+			for _, pair := range [][2]ir.JoinColumn{{oldJT.Source, jt.Source}, {oldJT.Target, jt.Target}} {
+				if pair[0].Name != pair[1].Name {
+					r.RenameColumn(jt.Name, pair[0].Name, pair[1].Name)
+				}
+			}
+		}
+	}
+	return r
+}
+
+// previousNames returns a copy of the schema with the models and fields
+// carrying @renamedFrom under their previous names, nil when there are none
+func previousNames(irData *ir.IR) *ir.IR {
+	models := make(map[string]string)
+	renamed := false
+	for _, model := range irData.Models {
+		if name, ok := renamedFrom(model.Directives); ok {
+			models[model.Name] = name
+			renamed = true
+		}
+		for _, f := range model.Fields {
+			if _, ok := renamedFrom(f.Type.Directives); ok {
+				renamed = true
+			}
+		}
+	}
+	if !renamed {
+		return nil
+	}
+
+	before := &ir.IR{DatabaseDriver: irData.DatabaseDriver}
+	for _, model := range irData.Models {
+		copied := model
+		if name, ok := models[model.Name]; ok {
+			copied.Name = name
+		}
+		copied.Fields = make([]ir.IRField, len(model.Fields))
+		for i, f := range model.Fields {
+			if name, ok := renamedFrom(f.Type.Directives); ok {
+				f.Name = name
+			}
+			if name, ok := models[f.Type.ModelName]; ok {
+				f.Type.ModelName = name
+			}
+			copied.Fields[i] = f
+		}
+		before.Models = append(before.Models, copied)
+	}
+	return before
+}
+
+// renamedFrom returns the argument of the @renamedFrom directive
+func renamedFrom(directives []directive.Directive) (string, bool) {
+	for _, dir := range directives {
+		if dir.Kind == directive.DirRenamedFrom && len(dir.Args) > 0 {
+			return dir.Args[0].Value.String(), true
+		}
+	}
+	return "", false
+}
+
+// zip records the differing names of the same table described before and
+// after the renames
+func zip(r *catalog.Renames, before, after *catalog.Table) {
+	if before.Name != after.Name {
+		r.RenameTable(before.Name, after.Name)
+	}
+	if len(before.Columns) != len(after.Columns) {
+		return
+	}
+	for i, col := range after.Columns {
+		if before.Columns[i].Name != col.Name {
+			r.RenameColumn(after.Name, before.Columns[i].Name, col.Name)
+		}
+	}
+}
+
+// invert returns the renames undoing the renames a plan applied
+func invert(p *Plan) catalog.Renames {
+	var r catalog.Renames
+	for _, c := range p.Changes {
+		switch c.Kind {
+		case catalog.RenameTable:
+			r.RenameTable(c.Table.Name, c.OldName)
+		case catalog.RenameColumn:
+			r.RenameColumn(p.oldTable(c.Table.Name), c.Column.Name, c.OldName)
+		}
+	}
+	return r
+}
+
+// oldTable returns the name the table had before the plan
+func (p *Plan) oldTable(name string) string {
+	if old, ok := p.renamed.Tables[name]; ok {
+		return old
+	}
+	return name
+}
+
+// oldColumn returns the name the column of the table had before the plan;
+// table is the new table name
+func (p *Plan) oldColumn(table, column string) string {
+	if old, ok := p.renamed.Columns[table][column]; ok {
+		return old
+	}
+	return column
+}
+
+// checkedTable returns the name hazard checks query the table by: its
+// name before the plan, unless the renames already ran
+func (p *Plan) checkedTable(name string) string {
+	if p.renamesRan {
+		return name
+	}
+	return p.oldTable(name)
+}
+
+// checkedColumn is checkedTable for the column of the table
+func (p *Plan) checkedColumn(table, column string) string {
+	if p.renamesRan {
+		return column
+	}
+	return p.oldColumn(table, column)
+}
+
+// renameTable renders the renaming of a table. PostgreSQL keeps the names
+// of the constraints, which the generated statements derive from the table
+// name, so they are renamed as well.
+func renameTable(p *Plan, t *catalog.Table, old string, d dialect.Dialect) []string {
+	if d == dialect.MySQL {
+		return []string{fmt.Sprintf("RENAME TABLE %s TO %s", d.QuoteIdent(old), d.QuoteIdent(t.Name))}
+	}
+	stmts := []string{fmt.Sprintf("ALTER TABLE %s RENAME TO %s", d.QuoteIdent(old), d.QuoteIdent(t.Name))}
+	if d != dialect.Postgres {
+		return stmts
+	}
+
+	rename := func(from, to string) {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s", d.QuoteIdent(t.Name), d.QuoteIdent(from), d.QuoteIdent(to)))
+	}
+	if len(t.PrimaryKey) > 0 {
+		rename(old+"_pkey", t.Name+"_pkey")
+	}
+	for _, col := range t.Columns {
+		if col.Unique {
+			name := p.oldColumn(t.Name, col.Name)
+			rename(old+"_"+name+"_key", t.Name+"_"+name+"_key")
+		}
+	}
+	for _, fk := range t.ForeignKeys {
+		if fk.Name == "" {
+			name := p.oldColumn(t.Name, fk.Columns[0])
+			rename(old+"_"+name+"_fkey", t.Name+"_"+name+"_fkey")
+		}
+	}
+	return stmts
+}
+
+// renameColumn renders the renaming of a column, with the unique and
+// foreign key constraints named after it
+func renameColumn(t *catalog.Table, col *catalog.Column, old string, d dialect.Dialect) []string {
+	table := d.QuoteIdent(t.Name)
+	stmts := []string{fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, d.QuoteIdent(old), d.QuoteIdent(col.Name))}
+	switch d {
+	case dialect.MySQL:
+		// An inline UNIQUE index is named after its column
+		if col.Unique {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s RENAME INDEX %s TO %s", table, d.QuoteIdent(old), d.QuoteIdent(col.Name)))
+		}
+	case dialect.Postgres:
+		rename := func(suffix string) {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s", table,
+				d.QuoteIdent(t.Name+"_"+old+suffix), d.QuoteIdent(t.Name+"_"+col.Name+suffix)))
+		}
+		if col.Unique {
+			rename("_key")
+		}
+		for _, fk := range t.ForeignKeys {
+			if fk.Name == "" && fk.Columns[0] == col.Name {
+				rename("_fkey")
+			}
+		}
+	}
+	return stmts
+}
+
+// Suggest returns hints for the dropped and added tables and columns of a
+// plan that look like renames: a column dropped and one added at the same
+// position of a table with the same type, or a table dropped and one added
+// with the same columns. Without @renamedFrom they lose their data.
+func Suggest(p *Plan) []string {
+	var hints []string
+	var addedTables, droppedTables []*catalog.Table
+	for _, c := range p.Changes {
+		switch c.Kind {
+		case catalog.AddTable:
+			addedTables = append(addedTables, c.Table)
+		case catalog.DropTable:
+			droppedTables = append(droppedTables, c.Table)
+		case catalog.AddColumn:
+			old, ok := p.From.Table(c.Table.Name)
+			if !ok {
+				continue
+			}
+			i := columnIndex(c.Table, c.Column.Name)
+			if i >= len(old.Columns) {
+				continue
+			}
+			prev := old.Columns[i]
+			if _, kept := c.Table.Column(prev.Name); kept || prev.Type != c.Column.Type {
+				continue
+			}
+			hints = append(hints, fmt.Sprintf("%s.%s may be a rename of %s; add @renamedFrom(%q) to keep its data",
+				c.Table.Name, c.Column.Name, prev.Name, prev.Name))
+		}
+	}
+
+	for _, added := range addedTables {
+		for _, dropped := range droppedTables {
+			if sameColumns(added, dropped) {
+				hints = append(hints, fmt.Sprintf("table %s may be a rename of %s; add @renamedFrom(%q) to its model to keep its rows",
+					added.Name, dropped.Name, dropped.Name))
+				break
+			}
+		}
+	}
+	return hints
+}
+
+func columnIndex(t *catalog.Table, name string) int {
+	for i, col := range t.Columns {
+		if col.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameColumns reports whether two tables have columns of the same names and types
+func sameColumns(a, b *catalog.Table) bool {
+	if len(a.Columns) != len(b.Columns) {
+		return false
+	}
+	for i, col := range a.Columns {
+		if col.Name != b.Columns[i].Name || col.Type != b.Columns[i].Type {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,279 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pixperk/storm/internal/catalog"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// AcceptDataLoss is the line of an up.sql that lets the migration apply
+// despite its hazards
+const AcceptDataLoss = "-- storm:accept-data-loss"
+
+// Hazard is a change that can lose data or fail on the rows a table holds
+type Hazard struct {
+	Change  catalog.Change
+	Message string
+	// Check is a query returning a row when existing data is affected. A
+	// hazard whose check finds nothing is harmless for that database.
+	Check string
+
+	aspect hazardAspect // Part of an altered column the hazard comes from
+}
+
+// hazardAspect tells apart the hazards of one altered column, which the
+// phases of an online migration run at different times
+type hazardAspect int
+
+const (
+	aspectNone hazardAspect = iota
+	aspectType
+	aspectNotNull
+	aspectUnique
+)
+
+// String describes the hazard
+func (h Hazard) String() string {
+	return h.Message
+}
+
+// DataLossError stops a migration whose hazards were not accepted
+type DataLossError struct {
+	Migration string
+	Hazards   []Hazard
+}
+
+func (e *DataLossError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "migration %s can lose data:", e.Migration)
+	for _, h := range e.Hazards {
+		fmt.Fprintf(&b, "\n  - %s", h)
+	}
+	fmt.Fprintf(&b, "\napply it with -accept-data-loss or add the line %q to its up.sql", AcceptDataLoss)
+	return b.String()
+}
+
+// Analyze returns the hazards of migrating from schema from to schema to:
+// dropped tables and columns, lossy type changes, narrowed @length, NOT NULL
+// without a default on existing rows and unique constraints existing rows
+// may break. The checks run before the migration, so they use the names the
+// tables and columns had before it.
+func Analyze(from, to *ir.IR, p *Plan, d dialect.Dialect) []Hazard {
+	var hazards []Hazard
+	add := func(c catalog.Change, check, format string, args ...interface{}) {
+		hazards = append(hazards, Hazard{Change: c, Message: fmt.Sprintf(format, args...), Check: check})
+	}
+
+	for _, c := range p.Changes {
+		table := d.QuoteIdent(p.checkedTable(c.Table.Name))
+		switch c.Kind {
+		case catalog.DropTable:
+			add(c, fmt.Sprintf("SELECT 1 FROM %s LIMIT 1", table),
+				"dropping table %s deletes its rows", c.Table.Name)
+
+		case catalog.DropColumn:
+			add(c, fmt.Sprintf("SELECT 1 FROM %s WHERE %s IS NOT NULL LIMIT 1", table, d.QuoteIdent(c.Column.Name)),
+				"dropping column %s.%s deletes its values", c.Table.Name, c.Column.Name)
+
+		case catalog.AddColumn:
+			col := c.Column
+			if !col.Nullable && col.Default == "" && !col.AutoIncrement {
+				add(c, fmt.Sprintf("SELECT 1 FROM %s LIMIT 1", table),
+					"adding NOT NULL column %s.%s without a default fails on existing rows", c.Table.Name, col.Name)
+			}
+
+		case catalog.AlterColumn:
+			hazards = append(hazards, columnHazards(from, to, p, c, d)...)
+
+		case catalog.AlterPrimaryKey:
+			// A key covering the previous one, such as a reordered key, holds on existing rows
+			if len(c.Table.PrimaryKey) > 0 && !covers(c.Table.PrimaryKey, c.PreviousKey) {
+				add(c, duplicates(p, c.Table.Name, c.Table.PrimaryKey, d),
+					"the new primary key of %s (%s) fails on duplicate rows", c.Table.Name, strings.Join(c.Table.PrimaryKey, ", "))
+			}
+
+		case catalog.AddIndex:
+			if c.Index.Unique {
+				add(c, duplicates(p, c.Table.Name, c.Index.Columns, d),
+					"unique index %s fails on duplicate values of %s", c.Index.Name, strings.Join(c.Index.Columns, ", "))
+			}
+		}
+	}
+	return hazards
+}
+
+// columnHazards returns the hazards of altering a column
+func columnHazards(from, to *ir.IR, p *Plan, c catalog.Change, d dialect.Dialect) []Hazard {
+	var hazards []Hazard
+	add := func(aspect hazardAspect, check, format string, args ...interface{}) {
+		hazards = append(hazards, Hazard{Change: c, Message: fmt.Sprintf(format, args...), Check: check, aspect: aspect})
+	}
+
+	name := c.Table.Name + "." + c.Column.Name
+	oldTable, oldColumn := p.oldTable(c.Table.Name), p.oldColumn(c.Table.Name, c.Column.Name)
+	table, column := d.QuoteIdent(p.checkedTable(c.Table.Name)), d.QuoteIdent(p.checkedColumn(c.Table.Name, c.Column.Name))
+	hasValues := fmt.Sprintf("SELECT 1 FROM %s WHERE %s IS NOT NULL LIMIT 1", table, column)
+
+	before, okBefore := columnField(from, oldTable, oldColumn)
+	after, okAfter := columnField(to, c.Table.Name, c.Column.Name)
+	switch {
+	case okBefore && okAfter:
+		if before.IsArray != after.IsArray || lossyKind(before.Type.Kind, after.Type.Kind) {
+			add(aspectType, hasValues, "changing %s from %s to %s can lose or reject existing values", name, typeName(before), typeName(after))
+		} else if limit, ok := narrowed(before, after); ok {
+			length := "LENGTH"
+			if d == dialect.MySQL {
+				length = "CHAR_LENGTH"
+			}
+			add(aspectType, fmt.Sprintf("SELECT 1 FROM %s WHERE %s(%s) > %d LIMIT 1", table, length, column, limit),
+				"narrowing %s to %d characters truncates or rejects longer values", name, limit)
+		}
+	case c.Previous.Type != c.Column.Type:
+		add(aspectType, hasValues, "changing %s from %s to %s can lose or reject existing values", name, c.Previous.Type, c.Column.Type)
+	}
+
+	if c.Previous.Nullable && !c.Column.Nullable {
+		add(aspectNotNull, fmt.Sprintf("SELECT 1 FROM %s WHERE %s IS NULL LIMIT 1", table, column),
+			"making %s NOT NULL fails on, or on MySQL overwrites, existing NULL values", name)
+	}
+	if c.Column.Unique && !c.Previous.Unique {
+		add(aspectUnique, duplicates(p, c.Table.Name, []string{c.Column.Name}, d),
+			"making %s unique fails on duplicate values", name)
+	}
+	return hazards
+}
+
+// duplicates returns a query finding rows sharing the values of the columns,
+// named as the checks of the plan find them
+func duplicates(p *Plan, table string, columns []string, d dialect.Dialect) string {
+	quoted := make([]string, len(columns))
+	notNull := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = d.QuoteIdent(p.checkedColumn(table, col))
+		notNull[i] = quoted[i] + " IS NOT NULL"
+	}
+	list := strings.Join(quoted, ", ")
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s GROUP BY %s HAVING COUNT(*) > 1 LIMIT 1",
+		list, d.QuoteIdent(p.checkedTable(table)), strings.Join(notNull, " AND "), list)
+}
+
+// covers reports whether key includes every column of a non-empty previous key
+func covers(key, previous []string) bool {
+	if len(previous) == 0 {
+		return false
+	}
+	for _, col := range previous {
+		if !slices.Contains(key, col) {
+			return false
+		}
+	}
+	return true
+}
+
+// columnField returns the scalar field of the model stored in the table column
+func columnField(irData *ir.IR, table, column string) (ir.IRField, bool) {
+	model, ok := irData.FindModel(table)
+	if !ok {
+		return ir.IRField{}, false
+	}
+	for _, f := range model.Fields {
+		if !irData.IsRelation(f) && f.ColumnName() == column {
+			return f, true
+		}
+	}
+	return ir.IRField{}, false
+}
+
+// widening lists the kinds each kind converts to without losing values;
+// length limits are checked separately
+var widening = map[fld.FieldKind][]fld.FieldKind{
+	fld.KindInt:       {fld.KindBigInt, fld.KindFloat, fld.KindDecimal, fld.KindString, fld.KindText},
+	fld.KindBigInt:    {fld.KindDecimal, fld.KindString, fld.KindText},
+	fld.KindFloat:     {fld.KindString, fld.KindText},
+	fld.KindDecimal:   {fld.KindString, fld.KindText},
+	fld.KindString:    {fld.KindText, fld.KindChar},
+	fld.KindText:      {fld.KindString, fld.KindChar},
+	fld.KindChar:      {fld.KindString, fld.KindText},
+	fld.KindBoolean:   {fld.KindInt, fld.KindBigInt, fld.KindString, fld.KindText},
+	fld.KindDate:      {fld.KindDateTime, fld.KindTimestamp, fld.KindString, fld.KindText},
+	fld.KindDateTime:  {fld.KindTimestamp, fld.KindString, fld.KindText},
+	fld.KindTimestamp: {fld.KindDateTime, fld.KindString, fld.KindText},
+	fld.KindTime:      {fld.KindString, fld.KindText},
+	fld.KindUUID:      {fld.KindString, fld.KindText},
+	fld.KindCUID:      {fld.KindString, fld.KindText},
+	fld.KindJSON:      {fld.KindText},
+}
+
+// lossyKind reports whether converting values of kind from to kind to can lose them
+func lossyKind(from, to fld.FieldKind) bool {
+	return from != to && !slices.Contains(widening[from], to)
+}
+
+// narrowed returns the new length limit of a text column whose limit shrinks
+func narrowed(from, to ir.IRField) (int, bool) {
+	switch from.Type.Kind {
+	case fld.KindString, fld.KindChar, fld.KindText:
+	default:
+		return 0, false
+	}
+	before, after := maxLength(from), maxLength(to)
+	if after == 0 || before != 0 && before <= after {
+		return 0, false
+	}
+	return after, true
+}
+
+// maxLength returns the length limit of a text field, 0 when it has none
+func maxLength(f ir.IRField) int {
+	switch f.Type.Kind {
+	case fld.KindString, fld.KindChar:
+		if args := f.Type.GetDirective(directive.DirLength); len(args) > 0 {
+			if n, err := strconv.Atoi(args[0]); err == nil && n > 0 {
+				return n
+			}
+		}
+		return 255
+	case fld.KindText:
+		return 0
+	}
+	return 0
+}
+
+func typeName(f ir.IRField) string {
+	name := f.Type.Kind.String()
+	if n := maxLength(f); n > 0 {
+		name += "(" + strconv.Itoa(n) + ")"
+	}
+	if f.IsArray {
+		name += "[]"
+	}
+	return name
+}
+
+// Confirm runs the checks of the hazards against the database and returns
+// the hazards its data is affected by
+func Confirm(ctx context.Context, exec Executor, hazards []Hazard) ([]Hazard, error) {
+	var confirmed []Hazard
+	for _, h := range hazards {
+		if h.Check == "" {
+			confirmed = append(confirmed, h)
+			continue
+		}
+		found, err := exec.Exists(ctx, h.Check)
+		if err != nil {
+			return nil, fmt.Errorf("check %q: %w", h.Message, err)
+		}
+		if found {
+			confirmed = append(confirmed, h)
+		}
+	}
+	return confirmed, nil
+}
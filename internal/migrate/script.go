@@ -0,0 +1,130 @@
+package migrate
+
+import (
+	"bufio"
+	"strings"
+)
+
+// Lines of a script changing how it runs
+const (
+	// NoTransaction runs the statements of the script one at a time outside
+	// a transaction, as CREATE INDEX CONCURRENTLY and batched updates need
+	NoTransaction = "-- storm:no-transaction"
+	// Batch marks the statement after it as a batch, run again until it
+	// affects no rows
+	Batch = "-- storm:batch"
+	// Squashed, followed by a migration name, lists a migration a baseline
+	// written by Squash replaces
+	Squashed = "-- storm:squashed"
+	// OnlinePhase, followed by its position and name such as "2 backfill",
+	// marks a migration written for a phase of an online plan
+	OnlinePhase = "-- storm:phase"
+)
+
+// hasLine reports whether a line of the text is exactly the marker
+func hasLine(text, marker string) bool {
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// markedWith returns the values following the marker on the lines of the
+// text starting with it
+func markedWith(text, marker string) []string {
+	var values []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), marker+" "); ok {
+			values = append(values, strings.TrimSpace(value))
+		}
+	}
+	return values
+}
+
+// split splits a script into its statements, each with the comments
+// preceding it. Semicolons inside quotes, comments and PostgreSQL dollar
+// quoted bodies do not end a statement.
+func split(script string) []string {
+	var stmts []string
+	start := 0
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(script, i, c)
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(script)
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(script)
+			}
+		case c == '$':
+			if tag, ok := dollarTag(script[i:]); ok {
+				if end := strings.Index(script[i+len(tag):], tag); end >= 0 {
+					i += len(tag) + end + len(tag) - 1
+				} else {
+					i = len(script)
+				}
+			}
+		case c == ';':
+			stmts = appendStatement(stmts, script[start:i])
+			start = i + 1
+		}
+	}
+	return appendStatement(stmts, script[start:])
+}
+
+// skipQuoted returns the index of the quote closing the one at i; doubled
+// quotes and backslashes escape it
+func skipQuoted(script string, i int, quote byte) int {
+	for j := i + 1; j < len(script); j++ {
+		switch script[j] {
+		case '\\':
+			j++
+		case quote:
+			if j+1 < len(script) && script[j+1] == quote {
+				j++
+				continue
+			}
+			return j
+		}
+	}
+	return len(script)
+}
+
+// dollarTag returns the $tag$ opening a dollar quoted string at the start of s
+func dollarTag(s string) (string, bool) {
+	for j := 1; j < len(s); j++ {
+		c := s[j]
+		switch {
+		case c == '$':
+			return s[:j+1], true
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || j > 1 && c >= '0' && c <= '9':
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// appendStatement appends the statement unless it holds only comments
+func appendStatement(stmts []string, stmt string) []string {
+	stmt = strings.TrimSpace(stmt)
+	scanner := bufio.NewScanner(strings.NewReader(stmt))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return append(stmts, stmt)
+		}
+	}
+	return stmts
+}
@@ -0,0 +1,241 @@
+package migrate
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pixperk/storm/internal/catalog"
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// rebuildPrefix names the table SQLite copies a rebuilt table into
+const rebuildPrefix = "_storm_new_"
+
+// createTable renders the CREATE TABLE statement of a table under the given name
+func createTable(t *catalog.Table, name string, d dialect.Dialect) string {
+	var lines []string
+	for _, col := range t.Columns {
+		lines = append(lines, d.QuoteIdent(col.Name)+" "+columnSpec(t, col, d))
+	}
+	if len(t.PrimaryKey) > 1 {
+		lines = append(lines, fmt.Sprintf("PRIMARY KEY (%s)", quoteList(t.PrimaryKey, d)))
+	}
+	for _, fk := range t.ForeignKeys {
+		lines = append(lines, foreignKeyClause(fk, d))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n  %s\n)", d.QuoteIdent(name), strings.Join(lines, ",\n  "))
+}
+
+// columnSpec renders the type and constraints of a column as CREATE TABLE
+// and ADD COLUMN write them
+func columnSpec(t *catalog.Table, col *catalog.Column, d dialect.Dialect) string {
+	if col.AutoIncrement {
+		switch d {
+		case dialect.Postgres:
+			if col.Type == "BIGINT" {
+				return "BIGSERIAL PRIMARY KEY"
+			}
+			return "SERIAL PRIMARY KEY"
+		case dialect.MySQL:
+			return col.Type + " NOT NULL AUTO_INCREMENT PRIMARY KEY"
+		default:
+			return "INTEGER PRIMARY KEY AUTOINCREMENT"
+		}
+	}
+
+	parts := []string{col.Type}
+	if len(t.PrimaryKey) == 1 && t.PrimaryKey[0] == col.Name {
+		parts = append(parts, "PRIMARY KEY")
+	} else if !col.Nullable {
+		parts = append(parts, "NOT NULL")
+	}
+	if col.Unique {
+		parts = append(parts, "UNIQUE")
+	}
+	if col.Default != "" {
+		parts = append(parts, "DEFAULT "+col.Default)
+	}
+	return strings.Join(parts, " ")
+}
+
+// foreignKeyClause renders a FOREIGN KEY table constraint
+func foreignKeyClause(fk *catalog.ForeignKey, d dialect.Dialect) string {
+	clause := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+		quoteList(fk.Columns, d), d.QuoteIdent(fk.References), quoteList(fk.Referenced, d))
+	if fk.OnDelete != "" {
+		clause += " ON DELETE " + fk.OnDelete
+	}
+	return clause
+}
+
+func addColumn(t *catalog.Table, col *catalog.Column, d dialect.Dialect) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", d.QuoteIdent(t.Name), d.QuoteIdent(col.Name), columnSpec(t, col, d))
+}
+
+func dropColumn(t *catalog.Table, col *catalog.Column, d dialect.Dialect) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", d.QuoteIdent(t.Name), d.QuoteIdent(col.Name))
+}
+
+// alterColumn renders the statements changing a column of PostgreSQL or
+// MySQL; SQLite tables are rebuilt instead
+func alterColumn(t *catalog.Table, from, to *catalog.Column, d dialect.Dialect) []string {
+	table, column := d.QuoteIdent(t.Name), d.QuoteIdent(to.Name)
+	var stmts []string
+
+	if d == dialect.MySQL {
+		if from.Type != to.Type || from.Nullable != to.Nullable || from.Default != to.Default || from.AutoIncrement != to.AutoIncrement || from.OnUpdate != to.OnUpdate {
+			spec := []string{to.Type}
+			if !to.Nullable || slices.Contains(t.PrimaryKey, to.Name) {
+				spec = append(spec, "NOT NULL")
+			}
+			if to.AutoIncrement {
+				spec = append(spec, "AUTO_INCREMENT")
+			}
+			if to.Default != "" {
+				spec = append(spec, "DEFAULT "+to.Default)
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s", table, column, strings.Join(spec, " ")))
+		}
+		// An inline UNIQUE constraint is an index named after the column
+		switch {
+		case to.Unique && !from.Unique:
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD UNIQUE INDEX %s (%s)", table, column, column))
+		case from.Unique && !to.Unique:
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP INDEX %s", table, column))
+		}
+		return stmts
+	}
+
+	alter := func(action string) {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s", table, column, action))
+	}
+	if from.Type != to.Type {
+		alter(fmt.Sprintf("TYPE %s USING %s::%s", to.Type, column, to.Type))
+	}
+	if from.Nullable != to.Nullable {
+		if to.Nullable {
+			alter("DROP NOT NULL")
+		} else {
+			alter("SET NOT NULL")
+		}
+	}
+	if from.AutoIncrement != to.AutoIncrement {
+		// SERIAL is a sequence owned by the column and used as its default
+		sequence := d.QuoteIdent(t.Name + "_" + to.Name + "_seq")
+		if to.AutoIncrement {
+			stmts = append(stmts, fmt.Sprintf("CREATE SEQUENCE IF NOT EXISTS %s OWNED BY %s.%s", sequence, table, column))
+			alter(fmt.Sprintf("SET DEFAULT nextval(%s)", d.QuoteString(sequence)))
+		} else {
+			alter("DROP DEFAULT")
+		}
+	}
+	if from.Default != to.Default && !to.AutoIncrement {
+		if to.Default == "" {
+			alter("DROP DEFAULT")
+		} else {
+			alter("SET DEFAULT " + to.Default)
+		}
+	}
+	// An inline UNIQUE constraint is named <table>_<column>_key
+	constraint := d.QuoteIdent(t.Name + "_" + to.Name + "_key")
+	switch {
+	case to.Unique && !from.Unique:
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s UNIQUE (%s)", table, constraint, column))
+	case from.Unique && !to.Unique:
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, constraint))
+	}
+	return stmts
+}
+
+func alterPrimaryKey(t *catalog.Table, previous []string, d dialect.Dialect) []string {
+	table := d.QuoteIdent(t.Name)
+	var stmts []string
+	if len(previous) > 0 {
+		if d == dialect.MySQL {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP PRIMARY KEY", table))
+		} else {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", table, d.QuoteIdent(t.Name+"_pkey")))
+		}
+	}
+	if len(t.PrimaryKey) > 0 {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s)", table, quoteList(t.PrimaryKey, d)))
+	}
+	return stmts
+}
+
+// createIndex renders an index, with all of its options when known
+func createIndex(t *catalog.Table, idx *catalog.Index, d dialect.Dialect) string {
+	if idx.Definition != "" {
+		return idx.Definition
+	}
+	unique := ""
+	if idx.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, d.QuoteIdent(idx.Name), d.QuoteIdent(t.Name), quoteList(idx.Columns, d))
+}
+
+func dropIndex(t *catalog.Table, idx *catalog.Index, d dialect.Dialect) string {
+	if d == dialect.MySQL {
+		return fmt.Sprintf("DROP INDEX %s ON %s", d.QuoteIdent(idx.Name), d.QuoteIdent(t.Name))
+	}
+	return "DROP INDEX " + d.QuoteIdent(idx.Name)
+}
+
+func addForeignKey(t *catalog.Table, fk *catalog.ForeignKey, d dialect.Dialect) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD %s", d.QuoteIdent(t.Name), foreignKeyClause(fk, d))
+}
+
+// dropForeignKey drops a foreign key of table t. Keys created by the DDL
+// generator are unnamed, so the name is the one the database gave them:
+// <table>_<column>_fkey on PostgreSQL and <table>_ibfk_<n> on MySQL.
+func dropForeignKey(t *catalog.Table, fk *catalog.ForeignKey, d dialect.Dialect) string {
+	name := fk.Name
+	if name == "" && d == dialect.MySQL {
+		name = fmt.Sprintf("%s_ibfk_%d", t.Name, slices.Index(t.ForeignKeys, fk)+1)
+	} else if name == "" {
+		name = t.Name + "_" + fk.Columns[0] + "_fkey"
+	}
+
+	if d == dialect.MySQL {
+		return fmt.Sprintf("ALTER TABLE %s DROP FOREIGN KEY %s", d.QuoteIdent(t.Name), d.QuoteIdent(name))
+	}
+	return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", d.QuoteIdent(t.Name), d.QuoteIdent(name))
+}
+
+// rebuildTable renders the SQLite table rebuild: create the new definition,
+// copy the rows of the columns both definitions have, swap the tables and
+// recreate the indexes
+func rebuildTable(from, to *catalog.Table) []string {
+	d := dialect.SQLite
+	temp := rebuildPrefix + to.Name
+
+	var columns []string
+	for _, col := range to.Columns {
+		if _, ok := from.Column(col.Name); ok {
+			columns = append(columns, d.QuoteIdent(col.Name))
+		}
+	}
+
+	stmts := []string{createTable(to, temp, d)}
+	if len(columns) > 0 {
+		list := strings.Join(columns, ", ")
+		stmts = append(stmts, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", d.QuoteIdent(temp), list, list, d.QuoteIdent(from.Name)))
+	}
+	stmts = append(stmts,
+		"DROP TABLE "+d.QuoteIdent(from.Name),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", d.QuoteIdent(temp), d.QuoteIdent(to.Name)))
+	for _, idx := range to.Indexes {
+		stmts = append(stmts, createIndex(to, idx, d))
+	}
+	return stmts
+}
+
+func quoteList(names []string, d dialect.Dialect) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = d.QuoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
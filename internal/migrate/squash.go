@@ -0,0 +1,74 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pixperk/storm/internal/catalog"
+	"github.com/pixperk/storm/internal/generator/ddl"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// Squash replaces the migrations up to and including the one named to, all
+// of them when to is empty, with a baseline creating the schema snapshot of
+// the last one from scratch. The baseline takes the timestamp of that
+// migration, so it still sorts before the migrations kept, and lists the
+// migrations it replaces on Squashed lines: Up records it without running it
+// on databases that applied them. The replaced directories are removed.
+func Squash(dir, name string, migrations []*Migration, to string) (*Migration, []*Migration, error) {
+	last := len(migrations) - 1
+	if to != "" {
+		last = -1
+		for i, m := range migrations {
+			if m.Name == to {
+				last = i
+			}
+		}
+		if last < 0 {
+			return nil, nil, fmt.Errorf("no migration named %s", to)
+		}
+	}
+	if last < 0 {
+		return nil, nil, fmt.Errorf("no migrations to squash")
+	}
+	squashed := migrations[:last+1]
+
+	snapshot := squashed[last].Schema
+	d := dialect.Parse(snapshot.DatabaseDriver)
+	empty := &ir.IR{DatabaseDriver: snapshot.DatabaseDriver}
+
+	timestamp, _, _ := strings.Cut(squashed[last].Name, "_")
+	m := &Migration{Name: timestamp + "_" + slug(name), Schema: snapshot}
+	m.Dir = filepath.Join(dir, m.Name)
+	for _, old := range squashed {
+		if old.Name == m.Name {
+			return nil, nil, fmt.Errorf("migration %s already exists; squash more migrations or pick another name", m.Name)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(header(m.Name, nil, nil))
+	fmt.Fprintf(&b, "-- Baseline replacing %d migrations\n", len(squashed))
+	for _, old := range squashed {
+		fmt.Fprintf(&b, "%s %s\n", Squashed, old.Name)
+	}
+	b.WriteString("\n")
+	// The snapshot describes the tables as they are; the renames it still
+	// declares have already run
+	b.WriteString(ddl.Render(diff(empty, snapshot, d, catalog.Renames{}).Statements))
+	m.Up = b.String()
+	m.Down = "-- Reverts " + m.Name + "\n\n" + ddl.Render(diff(snapshot, empty, d, catalog.Renames{}).Statements)
+
+	if err := write(m); err != nil {
+		return nil, nil, err
+	}
+	for _, old := range squashed {
+		if err := os.RemoveAll(old.Dir); err != nil {
+			return m, nil, err
+		}
+	}
+	return m, squashed, nil
+}
@@ -0,0 +1,48 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/pixperk/storm/internal/catalog"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+)
+
+// Verify replays the migrations into shadow, an empty throwaway database,
+// and compares the tables they produce with the schema. It returns the
+// changes the result still needs to match irData, none when the migrations
+// produce the schema. The migrations are reverted afterwards, which also
+// runs their down scripts, so the shadow can be used again.
+func Verify(ctx context.Context, shadow *sql.DB, d dialect.Dialect, migrations []*Migration, irData *ir.IR) ([]catalog.Change, error) {
+	existing, err := catalog.Inspect(ctx, shadow, d)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing.Tables) > 0 {
+		return nil, fmt.Errorf("the shadow database is not empty: it has table %s", existing.Tables[0].Name)
+	}
+
+	exec := NewExecutor(shadow, d)
+	ran, err := Up(ctx, exec, d, migrations, UpOptions{AcceptDataLoss: true})
+	if err != nil {
+		return nil, err
+	}
+
+	actual, err := catalog.Inspect(ctx, shadow, d)
+	if err != nil {
+		return nil, err
+	}
+	actual.Tables = slices.DeleteFunc(actual.Tables, func(t *catalog.Table) bool { return t.Name == TableName })
+	changes := catalog.Diff(actual, catalog.FromIR(irData, d))
+
+	if _, err := Down(ctx, exec, migrations, len(ran)); err != nil {
+		return changes, fmt.Errorf("reverting the shadow database: %w", err)
+	}
+	if _, err := shadow.ExecContext(ctx, "DROP TABLE "+d.QuoteIdent(TableName)); err != nil {
+		return changes, fmt.Errorf("reverting the shadow database: %w", err)
+	}
+	return changes, nil
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"strings"
+
+	participleLexer "github.com/alecthomas/participle/v2/lexer"
+)
+
+// Parse parses schema source and attaches doc comments to its models and fields
+func Parse(filename, src string) (*DSLFile, error) {
+	ast, err := Parser.ParseString(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := docLines(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range ast.Models {
+		m.Doc = docAbove(docs, m.Pos.Line)
+		for _, f := range m.Fields {
+			f.Doc = docAbove(docs, f.Pos.Line)
+		}
+	}
+	return ast, nil
+}
+
+// docLines returns the text of every line holding nothing but a /// comment
+func docLines(filename, src string) (map[int]string, error) {
+	lex, err := stormLexer.LexString(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := participleLexer.ConsumeAll(lex)
+	if err != nil {
+		return nil, err
+	}
+
+	docType := stormLexer.Symbols()["DocComment"]
+	whitespace := stormLexer.Symbols()["Whitespace"]
+
+	docs := make(map[int]string)
+	code := make(map[int]bool) // Lines with other tokens; a trailing /// is not a doc comment
+	for _, tok := range tokens {
+		switch tok.Type {
+		case docType:
+			if !code[tok.Pos.Line] {
+				docs[tok.Pos.Line] = strings.TrimPrefix(strings.TrimPrefix(tok.Value, "///"), " ")
+			}
+		case whitespace:
+		default:
+			code[tok.Pos.Line] = true
+		}
+	}
+	return docs, nil
+}
+
+// docAbove joins the doc comment lines directly above line
+func docAbove(docs map[int]string, line int) string {
+	start := line
+	for {
+		if _, ok := docs[start-1]; !ok {
+			break
+		}
+		start--
+	}
+
+	var lines []string
+	for l := start; l < line; l++ {
+		lines = append(lines, strings.TrimRight(docs[l], " \t\r"))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -6,20 +6,38 @@ import (
 )
 
 type DSLFile struct {
-	DatabaseDriver string   `"database" "driver" "=" @String`
-	DatabaseURL    string   `"database" "url" "=" @String`
-	Models         []*Model `@@*`
+	DatabaseDriver string       `"database" "driver" "=" @String`
+	DatabaseURL    string       `"database" "url" "=" @String`
+	Generators     []*Generator `( @@`
+	Models         []*Model     `| @@ )*`
+}
+
+// Generator declares an external code generator plugin
+type Generator struct {
+	Pos     participleLexer.Position
+	Name    string             `"generator" @Ident "{"`
+	Options []*GeneratorOption `@@* "}"`
+}
+
+type GeneratorOption struct {
+	Key   string `@Ident "="`
+	Value *Value `@@`
 }
 
 type Model struct {
-	Model  string   `"model"`
-	Name   string   `@Ident`
-	LBrace string   `"{"`
-	Fields []*Field `@@*`
-	RBrace string   `"}"`
+	Pos        participleLexer.Position
+	Doc        string       // Text of the /// comments preceding the model, set by Parse
+	Model      string       `"model"`
+	Name       string       `@Ident`
+	Directives []*Directive `@@*`
+	LBrace     string       `"{"`
+	Fields     []*Field     `@@*`
+	RBrace     string       `"}"`
 }
 
 type Field struct {
+	Pos        participleLexer.Position
+	Doc        string       // Text of the /// comments preceding the field, set by Parse
 	Name       string       `@Ident`
 	Type       *Type        `@@`
 	Directives []*Directive `@@*`
@@ -31,10 +49,36 @@ type Directive struct {
 }
 
 type DirectiveArg struct {
-	String *string  `  @String`
-	Ident  *string  `| @Ident`
-	Int    *int     `| @Int`
-	Float  *float64 `| @Float`
+	Key   *string `( @Ident ":" )?`
+	Value *Value  `@@`
+}
+
+type Value struct {
+	String *string   `  @String`
+	Float  *float64  `| @Float`
+	Int    *int64    `| @Int`
+	Bool   *Boolean  `| @( "true" | "false" )`
+	Call   *Call     `| @@`
+	Ident  *string   `| @Ident`
+	Array  *ArrayLit `| @@`
+}
+
+// Call is a function call used as an argument value, e.g. now() or fn(a, key: b)
+type Call struct {
+	Name string          `@Ident "("`
+	Args []*DirectiveArg `( @@ ( "," @@ )* )? ")"`
+}
+
+type ArrayLit struct {
+	Values []*Value `"[" ( @@ ( "," @@ )* )? "]"`
+}
+
+// Boolean captures the true and false keywords
+type Boolean bool
+
+func (b *Boolean) Capture(values []string) error {
+	*b = values[0] == "true"
+	return nil
 }
 
 type Type struct {
@@ -44,13 +88,14 @@ type Type struct {
 
 // Configure the lexer to handle @ symbols and other tokens
 var lexerRules = []participleLexer.SimpleRule{
+	{Name: "DocComment", Pattern: `///.*`},
 	{Name: "Comment", Pattern: `//.*|/\*(.|\n)*?\*/`},
 	{Name: "Whitespace", Pattern: `\s+`},
 	{Name: "String", Pattern: `"[^"]*"`},
 	{Name: "Float", Pattern: `[-+]?\d*\.\d+([eE][-+]?\d+)?`},
 	{Name: "Int", Pattern: `[-+]?\d+`},
 	{Name: "Ident", Pattern: `[a-zA-Z_]\w*`},
-	{Name: "Punct", Pattern: `[@=(){}\[\],]`},
+	{Name: "Punct", Pattern: `[@=(){}\[\],:]`},
 }
 
 var stormLexer = participleLexer.MustSimple(lexerRules)
@@ -58,5 +103,8 @@ var stormLexer = participleLexer.MustSimple(lexerRules)
 // Configure the parser with options to handle numeric values better
 var Parser = participle.MustBuild[DSLFile](
 	participle.Lexer(stormLexer),
-	participle.Elide("Comment", "Whitespace"),
+	// Doc comments are attached to models and fields by Parse, not the grammar
+	participle.Elide("DocComment", "Comment", "Whitespace"),
+	// A second token is needed to tell "key:" and calls from a bare identifier
+	participle.UseLookahead(2),
 )
@@ -10,7 +10,7 @@ func ParseDSL(path string) (*DSLFile, error) {
 	if err != nil {
 		return nil, err
 	}
-	return Parser.ParseString("", string(data))
+	return Parse(path, string(data))
 }
 
 func DebugPrint(ast *DSLFile) {
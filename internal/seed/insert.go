@@ -0,0 +1,292 @@
+package seed
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pixperk/storm/internal/database"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// Insert validates a seed file and inserts its rows inside one transaction,
+// models in dependency order and rows in the order they are listed. Nothing
+// is written when any row fails.
+func Insert(ctx context.Context, db *sql.DB, d dialect.Dialect, irData *ir.IR, file File) error {
+	if err := Validate(irData, file); err != nil {
+		return err
+	}
+	order, err := Order(irData)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	s := &seeder{tx: tx, dialect: d, ir: irData, rows: make(map[string]map[string]map[string]interface{})}
+	for _, name := range order {
+		model, _ := irData.FindModel(name)
+		s.rows[name] = make(map[string]map[string]interface{})
+		for i, row := range file[name] {
+			if err := s.insertRow(ctx, *model, row); err != nil {
+				return fmt.Errorf("%s: %w", rowName(name, i, row), err)
+			}
+		}
+	}
+	if err := s.insertLinks(ctx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// seeder inserts the rows of one seed file
+type seeder struct {
+	tx      *sql.Tx
+	dialect dialect.Dialect
+	ir      *ir.IR
+	rows    map[string]map[string]map[string]interface{} // Values of labelled rows by model, label and field
+	links   []link
+}
+
+// link is a row of an implicit join table waiting for both sides to exist
+type link struct {
+	Table   *ir.JoinTable
+	Source  interface{} // Key of the row declaring the many-to-many field
+	Targets []string    // Labels of the related rows
+	Model   string      // Related model
+	Field   string      // Field listing the related rows, for error messages
+}
+
+// insertRow inserts a single row and records the values later rows may refer to
+func (s *seeder) insertRow(ctx context.Context, model ir.IRModel, row Row) error {
+	var columns []string
+	var args []interface{}
+	values := make(map[string]interface{})
+	var links []link
+
+	for _, key := range fieldNames(row) {
+		raw := row.Values[key]
+		f, ok := model.FindField(key)
+
+		switch {
+		case !ok:
+			rel, _ := implicitKey(s.ir, model, key)
+			v, err := sqlValue(s.dialect, keyField(s.ir, model, rel), raw)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", key, err)
+			}
+			columns = append(columns, model.ForeignKeyColumn(rel))
+			args = append(args, v)
+			values[key] = v
+		case f.Type.HasDirective(directive.DirBelongsTo):
+			v, err := s.reference(f, raw)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", key, err)
+			}
+			columns = append(columns, model.ForeignKeyColumn(f))
+			args = append(args, v)
+			values[ir.ForeignKeyName(f)] = v
+		case s.ir.IsRelation(f):
+			jt, _ := s.ir.JoinTable(model, f)
+			var targets []string
+			_ = json.Unmarshal(raw, &targets)
+			links = append(links, link{Table: jt, Targets: targets, Model: f.Type.ModelName, Field: f.Name})
+		default:
+			v, err := sqlValue(s.dialect, f, raw)
+			if err != nil {
+				return fmt.Errorf("field %s: %w", key, err)
+			}
+			columns = append(columns, f.ColumnName())
+			args = append(args, v)
+			values[f.Name] = v
+		}
+	}
+
+	pk, hasPK := model.PrimaryKey()
+	_, pkSet := values[pk.Name]
+	id, err := s.insert(ctx, model, columns, args, hasPK && !pkSet, pk)
+	if err != nil {
+		return err
+	}
+	if hasPK && !pkSet && id != nil {
+		values[pk.Name] = id
+	}
+
+	if len(links) > 0 {
+		key, ok := values[pk.Name]
+		if !ok {
+			return fmt.Errorf("set %s explicitly to link the row to other rows", pk.Name)
+		}
+		for _, l := range links {
+			l.Source = key
+			s.links = append(s.links, l)
+		}
+	}
+	if row.Label != "" {
+		s.rows[model.Name][row.Label] = values
+	}
+	return nil
+}
+
+// insert runs the INSERT statement of a row. When returnKey is set it also
+// returns the primary key the database assigned, or nil if it cannot tell.
+func (s *seeder) insert(ctx context.Context, model ir.IRModel, columns []string, args []interface{}, returnKey bool, pk ir.IRField) (interface{}, error) {
+	d := s.dialect
+	table := d.QuoteIdent(model.Name)
+
+	var stmt string
+	switch {
+	case len(columns) > 0:
+		quoted := make([]string, len(columns))
+		placeholders := make([]string, len(columns))
+		for i, col := range columns {
+			quoted[i] = d.QuoteIdent(col)
+			placeholders[i] = database.Placeholder(d, i+1)
+		}
+		stmt = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(quoted, ", "), strings.Join(placeholders, ", "))
+	case d == dialect.MySQL:
+		stmt = fmt.Sprintf("INSERT INTO %s () VALUES ()", table)
+	default:
+		stmt = fmt.Sprintf("INSERT INTO %s DEFAULT VALUES", table)
+	}
+
+	if !returnKey {
+		_, err := s.tx.ExecContext(ctx, stmt, args...)
+		return nil, err
+	}
+
+	if d == dialect.MySQL {
+		res, err := s.tx.ExecContext(ctx, stmt, args...)
+		if err != nil || !pk.Type.HasDirective(directive.DirAuto) {
+			return nil, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		return id, nil
+	}
+
+	var id interface{}
+	if err := s.tx.QueryRowContext(ctx, stmt+" RETURNING "+d.QuoteIdent(pk.ColumnName()), args...).Scan(&id); err != nil {
+		return nil, err
+	}
+	if b, ok := id.([]byte); ok {
+		// Text keys such as UUIDs come back as bytes
+		return string(b), nil
+	}
+	return id, nil
+}
+
+// reference resolves the $id given to a @belongsTo field into the referenced key
+func (s *seeder) reference(f ir.IRField, raw json.RawMessage) (interface{}, error) {
+	var label *string
+	if err := json.Unmarshal(raw, &label); err != nil {
+		return nil, err
+	}
+	if label == nil {
+		return nil, nil
+	}
+
+	referenced, _ := referencedField(s.ir, f)
+	v, ok := s.rows[f.Type.ModelName][*label][referenced.Name]
+	if !ok {
+		return nil, fmt.Errorf("%s row %q has no known %s; set it in the row", f.Type.ModelName, *label, referenced.Name)
+	}
+	return v, nil
+}
+
+// insertLinks fills the implicit join tables of many-to-many fields, once per pair
+func (s *seeder) insertLinks(ctx context.Context) error {
+	d := s.dialect
+	done := make(map[string]bool)
+
+	for _, l := range s.links {
+		target, _ := s.ir.FindModel(l.Model)
+		pk, _ := target.PrimaryKey()
+
+		for _, label := range l.Targets {
+			key, ok := s.rows[l.Model][label][pk.Name]
+			if !ok {
+				return fmt.Errorf("field %s: %s row %q has no known %s; set it in the row", l.Field, l.Model, label, pk.Name)
+			}
+
+			pair := []string{
+				fmt.Sprintf("%s=%v", l.Table.Source.Name, l.Source),
+				fmt.Sprintf("%s=%v", l.Table.Target.Name, key),
+			}
+			sort.Strings(pair)
+			id := l.Table.Name + ":" + strings.Join(pair, ",")
+			if done[id] {
+				continue
+			}
+			done[id] = true
+
+			stmt := fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (%s, %s)", d.QuoteIdent(l.Table.Name),
+				d.QuoteIdent(l.Table.Source.Name), d.QuoteIdent(l.Table.Target.Name),
+				database.Placeholder(d, 1), database.Placeholder(d, 2))
+			if _, err := s.tx.ExecContext(ctx, stmt, l.Source, key); err != nil {
+				return fmt.Errorf("join table %s: %w", l.Table.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sqlValue converts a validated JSON value into a query argument for the column of f
+func sqlValue(d dialect.Dialect, f ir.IRField, raw json.RawMessage) (interface{}, error) {
+	v, err := decode(raw)
+	if err != nil || v == nil {
+		return nil, err
+	}
+
+	if f.IsArray {
+		items, _ := v.([]interface{})
+		if d == dialect.Postgres {
+			return database.ArrayLiteral(items), nil
+		}
+		// MySQL and SQLite keep scalar lists as JSON
+		return compact(raw)
+	}
+
+	switch f.Type.Kind {
+	case fld.KindInt, fld.KindBigInt:
+		return v.(json.Number).Int64()
+	case fld.KindFloat:
+		return v.(json.Number).Float64()
+	case fld.KindDecimal:
+		// Passed as text to keep its precision
+		return v.(json.Number).String(), nil
+	case fld.KindBoolean:
+		return v, nil
+	case fld.KindDateTime, fld.KindTimestamp:
+		return parseTime(v.(string))
+	case fld.KindBinary:
+		return base64.StdEncoding.DecodeString(v.(string))
+	case fld.KindJSON:
+		return compact(raw)
+	default:
+		return v, nil
+	}
+}
+
+func compact(raw json.RawMessage) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
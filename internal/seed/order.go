@@ -0,0 +1,60 @@
+package seed
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/directive"
+)
+
+// Order returns the model names of the IR sorted so every model comes after
+// the models its @belongsTo relations point at. Models keep their declaration
+// order where the relations leave it free. Self-relations do not constrain the
+// order; rows of such models are inserted in the order they are listed.
+func Order(irData *ir.IR) ([]string, error) {
+	deps := make(map[string]map[string]bool)
+	for _, model := range irData.Models {
+		deps[model.Name] = make(map[string]bool)
+		for _, f := range model.Fields {
+			if f.Type.HasDirective(directive.DirBelongsTo) && irData.IsRelation(f) && f.Type.ModelName != model.Name {
+				deps[model.Name][f.Type.ModelName] = true
+			}
+		}
+	}
+
+	var order []string
+	placed := make(map[string]bool)
+	for len(order) < len(irData.Models) {
+		progress := false
+		for _, model := range irData.Models {
+			if placed[model.Name] || !ready(deps[model.Name], placed) {
+				continue
+			}
+			order = append(order, model.Name)
+			placed[model.Name] = true
+			progress = true
+			// Restart so earlier models that just became ready keep their place
+			break
+		}
+		if !progress {
+			var cycle []string
+			for _, model := range irData.Models {
+				if !placed[model.Name] {
+					cycle = append(cycle, model.Name)
+				}
+			}
+			return nil, fmt.Errorf("relations between %s form a cycle", strings.Join(cycle, ", "))
+		}
+	}
+	return order, nil
+}
+
+func ready(deps map[string]bool, placed map[string]bool) bool {
+	for dep := range deps {
+		if !placed[dep] {
+			return false
+		}
+	}
+	return true
+}
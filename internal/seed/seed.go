@@ -0,0 +1,96 @@
+// Package seed loads fixture rows for a Storm schema, validates them against
+// the IR and inserts them in dependency order.
+//
+// A seed file is a JSON object keyed by model name. Each model holds a list of
+// rows; a row maps field names to values and may name itself with "$id" so
+// other rows can refer to it:
+//
+//	{
+//	  "User": [
+//	    {"$id": "alice", "email": "alice@example.com", "role": "admin"}
+//	  ],
+//	  "Post": [
+//	    {"title": "Hello", "author": "alice", "tags": ["welcome"]}
+//	  ]
+//	}
+//
+// A @belongsTo field takes the $id of the related row, a many-to-many field a
+// list of them. Key columns may also be given directly, as in "authorId": 1.
+package seed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// LabelKey is the key naming a row for references from other rows
+const LabelKey = "$id"
+
+// File holds the rows to insert, by model name
+type File map[string][]Row
+
+// Row is a single row of fixture data
+type Row struct {
+	Label  string                     // Name other rows refer to it by, empty when unnamed
+	Values map[string]json.RawMessage // Field values by field name
+}
+
+// UnmarshalJSON reads a row object, taking its label out of the values
+func (r *Row) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.Values); err != nil {
+		return err
+	}
+	if r.Values == nil {
+		return fmt.Errorf("row must be an object")
+	}
+
+	if raw, ok := r.Values[LabelKey]; ok {
+		if err := json.Unmarshal(raw, &r.Label); err != nil || r.Label == "" {
+			return fmt.Errorf("%s must be a non-empty string", LabelKey)
+		}
+		delete(r.Values, LabelKey)
+	}
+	return nil
+}
+
+// Parse reads a seed file from r
+func Parse(r io.Reader) (File, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Load reads the seed file at path
+func Load(path string) (File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// decode unmarshals a JSON value keeping numbers as json.Number
+func decode(raw json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
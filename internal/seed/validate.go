@@ -0,0 +1,413 @@
+package seed
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/directive"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Validate checks the rows of a seed file against the IR: that models and
+// fields exist, values fit their types and constraints, required fields are
+// set and references name rows of the related model
+func Validate(irData *ir.IR, file File) error {
+	errList := new(multierror.Error)
+
+	labels := make(map[string]map[string]bool)
+	for name, rows := range file {
+		labels[name] = make(map[string]bool)
+		for _, row := range rows {
+			if row.Label != "" {
+				labels[name][row.Label] = true
+			}
+		}
+	}
+
+	for _, name := range modelNames(file) {
+		model, ok := irData.FindModel(name)
+		if !ok {
+			errList = multierror.Append(errList, fmt.Errorf("unknown model %s", name))
+			continue
+		}
+
+		// Rows of self-relations may only refer to rows listed before them
+		seen := make(map[string]bool)
+		for i, row := range file[name] {
+			if err := validateRow(irData, *model, row, labels, seen); err != nil {
+				errList = multierror.Append(errList, fmt.Errorf("%s: %w", rowName(name, i, row), err))
+			}
+			if row.Label != "" {
+				if seen[row.Label] {
+					errList = multierror.Append(errList, fmt.Errorf("%s: duplicate %s %q", rowName(name, i, row), LabelKey, row.Label))
+				}
+				seen[row.Label] = true
+			}
+		}
+	}
+
+	return errList.ErrorOrNil()
+}
+
+// validateRow checks the values of a row and that its required fields are set
+func validateRow(irData *ir.IR, model ir.IRModel, row Row, labels map[string]map[string]bool, seen map[string]bool) error {
+	errList := new(multierror.Error)
+
+	for _, key := range fieldNames(row) {
+		raw := row.Values[key]
+		f, ok := model.FindField(key)
+		if !ok {
+			rel, ok := implicitKey(irData, model, key)
+			if !ok {
+				errList = multierror.Append(errList, fmt.Errorf("unknown field %s", key))
+				continue
+			}
+			if err := checkValue(keyField(irData, model, rel), raw); err != nil {
+				errList = multierror.Append(errList, fmt.Errorf("field %s: %w", key, err))
+			}
+			continue
+		}
+
+		var err error
+		switch {
+		case !irData.IsRelation(f):
+			err = checkValue(f, raw)
+		case f.Type.HasDirective(directive.DirBelongsTo):
+			err = checkReference(model, f, raw, labels, seen)
+		case irData.IsManyToMany(model, f) && ir.RelationThrough(f) == "":
+			err = checkReferences(f, raw, labels)
+		default:
+			err = fmt.Errorf("set the relation from the @belongsTo side in %s", f.Type.ModelName)
+		}
+		if err != nil {
+			errList = multierror.Append(errList, fmt.Errorf("field %s: %w", key, err))
+		}
+	}
+
+	for _, f := range model.Fields {
+		if err := checkRequired(irData, model, f, row); err != nil {
+			errList = multierror.Append(errList, err)
+		}
+	}
+
+	return errList.ErrorOrNil()
+}
+
+// checkRequired reports a field the row must set but does not
+func checkRequired(irData *ir.IR, model ir.IRModel, f ir.IRField, row Row) error {
+	_, set := row.Values[f.Name]
+
+	if !irData.IsRelation(f) {
+		if set || !required(f) || isRelationKey(irData, model, f) {
+			return nil
+		}
+		return fmt.Errorf("field %s is required", f.Name)
+	}
+
+	if !f.Type.HasDirective(directive.DirBelongsTo) {
+		return nil
+	}
+	key := ir.ForeignKeyName(f)
+	_, keySet := row.Values[key]
+	switch {
+	case set && keySet:
+		return fmt.Errorf("set either %s or %s, not both", f.Name, key)
+	case !set && !keySet && !f.IsNullable():
+		return fmt.Errorf("field %s is required", f.Name)
+	}
+	return nil
+}
+
+// required reports whether a scalar field has no value unless the row sets one
+func required(f ir.IRField) bool {
+	return !f.IsNullable() && !f.Type.HasDirective(directive.DirDefault) && !generated(f)
+}
+
+// generated reports whether the database assigns the field's value
+func generated(f ir.IRField) bool {
+	t := f.Type
+	return (t.HasDirective(directive.DirID) && t.HasDirective(directive.DirAuto)) ||
+		t.HasDirective(directive.DirCreatedAt) || t.HasDirective(directive.DirUpdatedAt) ||
+		t.HasDirective(directive.DirDefaultNow)
+}
+
+// isRelationKey reports whether a scalar field is the declared key of a
+// @belongsTo relation, which a row may set through the relation instead
+func isRelationKey(irData *ir.IR, model ir.IRModel, f ir.IRField) bool {
+	for _, rel := range model.Fields {
+		if rel.Type.HasDirective(directive.DirBelongsTo) && irData.IsRelation(rel) && ir.ForeignKeyName(rel) == f.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// implicitKey returns the @belongsTo relation whose undeclared key column is named key
+func implicitKey(irData *ir.IR, model ir.IRModel, key string) (ir.IRField, bool) {
+	for _, f := range model.Fields {
+		if f.Type.HasDirective(directive.DirBelongsTo) && irData.IsRelation(f) && ir.ForeignKeyName(f) == key {
+			return f, true
+		}
+	}
+	return ir.IRField{}, false
+}
+
+// keyField returns the field holding the key of a @belongsTo relation: the
+// declared one, or a field typed like the referenced key
+func keyField(irData *ir.IR, model ir.IRModel, rel ir.IRField) ir.IRField {
+	name := ir.ForeignKeyName(rel)
+	if f, ok := model.FindField(name); ok {
+		return f
+	}
+
+	key := ir.IRField{Name: name, Type: *fld.NewFieldType(fld.KindInt, "", nil)}
+	if referenced, ok := referencedField(irData, rel); ok {
+		key.Type = *fld.NewFieldType(referenced.Type.Kind, "", nil)
+	}
+	if rel.IsNullable() {
+		key.Type.Directives = []directive.Directive{{Kind: directive.DirNullable}}
+	}
+	return key
+}
+
+// referencedField returns the field of the related model a @belongsTo key points at
+func referencedField(irData *ir.IR, rel ir.IRField) (ir.IRField, bool) {
+	target, ok := irData.FindModel(rel.Type.ModelName)
+	if !ok {
+		return ir.IRField{}, false
+	}
+	if ref := ir.ForeignKeyReference(rel); ref != "" {
+		return target.FindField(ref)
+	}
+	return target.PrimaryKey()
+}
+
+// checkReference checks the $id given to a @belongsTo field
+func checkReference(model ir.IRModel, f ir.IRField, raw json.RawMessage, labels map[string]map[string]bool, seen map[string]bool) error {
+	var label *string
+	if err := json.Unmarshal(raw, &label); err != nil {
+		return fmt.Errorf("must be the %s of a %s row", LabelKey, f.Type.ModelName)
+	}
+	switch {
+	case label == nil && f.IsNullable():
+		return nil
+	case label == nil:
+		return errors.New("must not be null")
+	case f.Type.ModelName == model.Name && !seen[*label]:
+		if labels[model.Name][*label] {
+			return fmt.Errorf("%s row %q must be listed before the rows referring to it", model.Name, *label)
+		}
+		return fmt.Errorf("no %s row has %s %q", f.Type.ModelName, LabelKey, *label)
+	case !labels[f.Type.ModelName][*label]:
+		return fmt.Errorf("no %s row has %s %q", f.Type.ModelName, LabelKey, *label)
+	}
+	return nil
+}
+
+// checkReferences checks the list of $ids given to a many-to-many field
+func checkReferences(f ir.IRField, raw json.RawMessage, labels map[string]map[string]bool) error {
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return fmt.Errorf("must be a list of %s row %ss", f.Type.ModelName, LabelKey)
+	}
+	for _, label := range list {
+		if !labels[f.Type.ModelName][label] {
+			return fmt.Errorf("no %s row has %s %q", f.Type.ModelName, LabelKey, label)
+		}
+	}
+	return nil
+}
+
+// checkValue checks a value against the type and constraints of a scalar field
+func checkValue(f ir.IRField, raw json.RawMessage) error {
+	v, err := decode(raw)
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		if f.IsNullable() {
+			return nil
+		}
+		return errors.New("must not be null")
+	}
+
+	if !f.IsArray {
+		return checkScalar(f, v)
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return errors.New("must be a list")
+	}
+	for i, item := range items {
+		if item == nil {
+			return fmt.Errorf("item %d must not be null", i+1)
+		}
+		if err := checkScalar(f, item); err != nil {
+			return fmt.Errorf("item %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// checkScalar checks a single decoded value against the kind and directives of a field
+func checkScalar(f ir.IRField, v interface{}) error {
+	t := f.Type
+
+	switch t.Kind {
+	case fld.KindInt, fld.KindBigInt:
+		n, ok := v.(json.Number)
+		if !ok {
+			return errors.New("must be an integer")
+		}
+		i, err := n.Int64()
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		if t.Kind == fld.KindInt && (i < math.MinInt32 || i > math.MaxInt32) {
+			return errors.New("is out of range for Int")
+		}
+	case fld.KindFloat, fld.KindDecimal:
+		n, ok := v.(json.Number)
+		if !ok {
+			return errors.New("must be a number")
+		}
+		if _, err := n.Float64(); err != nil {
+			return errors.New("must be a number")
+		}
+	case fld.KindBoolean:
+		if _, ok := v.(bool); !ok {
+			return errors.New("must be true or false")
+		}
+	case fld.KindJSON:
+		return nil
+	default:
+		s, ok := v.(string)
+		if !ok {
+			return errors.New("must be a string")
+		}
+		if err := checkString(t.Kind, s); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range t.Directives {
+		args := d.Positional()
+		if len(args) == 0 {
+			continue
+		}
+		switch d.Kind {
+		case directive.DirLength:
+			if s, ok := v.(string); ok && utf8.RuneCountInString(s) > int(args[0].Int) {
+				return fmt.Errorf("is longer than %d characters", args[0].Int)
+			}
+		case directive.DirMin:
+			if n, ok := v.(json.Number); ok {
+				if x, _ := n.Float64(); x < bound(args[0]) {
+					return fmt.Errorf("must be at least %s", args[0].Source())
+				}
+			}
+		case directive.DirMax:
+			if n, ok := v.(json.Number); ok {
+				if x, _ := n.Float64(); x > bound(args[0]) {
+					return fmt.Errorf("must be at most %s", args[0].Source())
+				}
+			}
+		case directive.DirEnum:
+			s, _ := v.(string)
+			if !contains(d.StringArgs(), s) {
+				return fmt.Errorf("must be one of %v", d.StringArgs())
+			}
+		}
+	}
+	return nil
+}
+
+// checkString checks the format of string-encoded kinds
+func checkString(kind fld.FieldKind, s string) error {
+	switch kind {
+	case fld.KindDateTime, fld.KindTimestamp:
+		if _, err := parseTime(s); err != nil {
+			return errors.New("must be an RFC 3339 date and time")
+		}
+	case fld.KindDate:
+		if _, err := time.Parse(time.DateOnly, s); err != nil {
+			return errors.New("must be a date (2006-01-02)")
+		}
+	case fld.KindTime:
+		if _, err := time.Parse(time.TimeOnly, s); err != nil {
+			return errors.New("must be a time of day (15:04:05)")
+		}
+	case fld.KindUUID:
+		if !uuidPattern.MatchString(s) {
+			return errors.New("must be a UUID")
+		}
+	case fld.KindBinary:
+		if _, err := base64.StdEncoding.DecodeString(s); err != nil {
+			return errors.New("must be base64 encoded")
+		}
+	}
+	return nil
+}
+
+// parseTime accepts RFC 3339 timestamps and plain dates at midnight UTC
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+func bound(v directive.Value) float64 {
+	if v.Kind == directive.ValueInt {
+		return float64(v.Int)
+	}
+	return v.Float
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// rowName identifies a row in error messages
+func rowName(model string, i int, row Row) string {
+	if row.Label != "" {
+		return fmt.Sprintf("%s row %d (%s)", model, i+1, row.Label)
+	}
+	return fmt.Sprintf("%s row %d", model, i+1)
+}
+
+// modelNames returns the models of a seed file in a stable order
+func modelNames(file File) []string {
+	names := make([]string, 0, len(file))
+	for name := range file {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fieldNames returns the fields set by a row in a stable order
+func fieldNames(row Row) []string {
+	names := make([]string, 0, len(row.Values))
+	for name := range row.Values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,82 @@
+package ir
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pixperk/storm/internal/types/directive"
+)
+
+// Format renders the IR as schema source that parses back into the same IR
+func Format(irData *IR) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "database driver = %s\n", strconv.Quote(irData.DatabaseDriver))
+	fmt.Fprintf(&b, "database url = %s\n", strconv.Quote(irData.DatabaseURL))
+
+	for _, gen := range irData.Generators {
+		fmt.Fprintf(&b, "\ngenerator %s {\n", gen.Name)
+		fmt.Fprintf(&b, "  provider = %s\n", strconv.Quote(gen.Provider))
+		if gen.Output != "" {
+			fmt.Fprintf(&b, "  output = %s\n", strconv.Quote(gen.Output))
+		}
+		for _, opt := range gen.Config {
+			fmt.Fprintf(&b, "  %s = %s\n", opt.Name, directive.FormatArgs([]directive.Arg{{Value: opt.Value}}))
+		}
+		b.WriteString("}\n")
+	}
+
+	for _, model := range irData.Models {
+		b.WriteString("\n")
+		writeDoc(&b, "", model.Doc)
+		line := "model " + model.Name
+		for _, d := range model.Directives {
+			line += " @" + d.Kind.SourceName()
+			if len(d.Args) > 0 {
+				line += "(" + directive.FormatArgs(d.Args) + ")"
+			}
+		}
+		fmt.Fprintf(&b, "%s {\n", line)
+
+		// Align the types and directives of the fields
+		nameWidth, typeWidth := 0, 0
+		for _, f := range model.Fields {
+			nameWidth = max(nameWidth, len(f.Name))
+			typeWidth = max(typeWidth, len(typeName(f)))
+		}
+
+		for _, f := range model.Fields {
+			writeDoc(&b, "  ", f.Doc)
+			line := fmt.Sprintf("  %-*s %-*s", nameWidth, f.Name, typeWidth, typeName(f))
+			for _, d := range f.Type.Directives {
+				line += " @" + d.Kind.SourceName()
+				if len(d.Args) > 0 {
+					line += "(" + directive.FormatArgs(d.Args) + ")"
+				}
+			}
+			b.WriteString(strings.TrimRight(line, " "))
+			b.WriteString("\n")
+		}
+		b.WriteString("}\n")
+	}
+
+	return b.String()
+}
+
+// typeName returns the type of a field as written in schema source
+func typeName(f IRField) string {
+	if f.IsArray {
+		return f.Type.String() + "[]"
+	}
+	return f.Type.String()
+}
+
+// writeDoc writes documentation as /// comment lines
+func writeDoc(b *strings.Builder, indent, doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		fmt.Fprintf(b, "%s%s\n", indent, strings.TrimRight("/// "+line, " "))
+	}
+}
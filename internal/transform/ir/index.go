@@ -0,0 +1,71 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/pixperk/storm/internal/types/directive"
+)
+
+// IndexSpec describes the index requested by an @index directive
+type IndexSpec struct {
+	Name    string   // Explicit index name, empty to derive one
+	Column  string   // Indexed column
+	Desc    bool     // Sort the key in descending order
+	Where   string   // Predicate of a partial index
+	Method  string   // Index access method, e.g. Hash, Gin, Gist, Brin
+	Include []string // Covering columns stored in the index
+	Length  int      // Key prefix length (MySQL text indexes)
+}
+
+// Index returns the index declared on the field with @index, if any
+func (f IRField) Index() (*IndexSpec, bool) {
+	for _, dir := range f.Type.Directives {
+		if dir.Kind == directive.DirIndex {
+			return newIndexSpec(f, dir), true
+		}
+	}
+	return nil, false
+}
+
+func newIndexSpec(f IRField, dir directive.Directive) *IndexSpec {
+	spec := &IndexSpec{Column: f.ColumnName()}
+
+	if positional := dir.Positional(); len(positional) > 0 {
+		spec.Name = positional[0].String()
+	}
+	if name, ok := dir.Option("name"); ok {
+		spec.Name = name.String()
+	}
+	if sort, ok := dir.Option("sort"); ok {
+		spec.Desc = strings.EqualFold(sort.String(), "desc")
+	}
+	if where, ok := dir.Option("where"); ok {
+		spec.Where = where.String()
+	}
+	if method, ok := dir.Option("type"); ok {
+		spec.Method = method.String()
+	}
+	if include, ok := dir.Option("include"); ok {
+		// A single string may still list several comma separated columns
+		for _, item := range include.Strings() {
+			for _, col := range strings.Split(item, ",") {
+				if col = strings.TrimSpace(col); col != "" {
+					spec.Include = append(spec.Include, col)
+				}
+			}
+		}
+	}
+	if length, ok := dir.Option("length"); ok && length.Kind == directive.ValueInt {
+		spec.Length = int(length.Int)
+	}
+
+	return spec
+}
+
+// IndexName returns the explicit name of the index or derives one from the table and column
+func (s *IndexSpec) IndexName(table string) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return table + "_" + s.Column + "_idx"
+}
@@ -2,7 +2,6 @@ package ir
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/pixperk/storm/internal/parser"
@@ -11,33 +10,114 @@ import (
 )
 
 type IRModel struct {
-	Name   string
-	Fields []IRField
+	Name       string
+	Fields     []IRField
+	Directives []directive.Directive // Model-level directives, written after the model name
+	Pos        Position
+	Doc        string // Documentation of the model
 }
 
 type IRField struct {
 	Name    string
 	Type    field.FieldType
 	IsArray bool
+	Pos     Position
+	Doc     string // Documentation of the field
+}
+
+// Position locates a model or field in the schema source
+type Position struct {
+	Filename string `json:"filename,omitempty"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
 }
 
 type IR struct {
 	DatabaseDriver string
 	DatabaseURL    string
 	Models         []IRModel
+	Generators     []IRGenerator
+}
+
+// IRGenerator is an external code generator declared with a generator block
+type IRGenerator struct {
+	Name     string          `json:"name"`
+	Provider string          `json:"provider"`         // Executable receiving the serialized IR on stdin
+	Output   string          `json:"output,omitempty"` // Directory receiving the generated files
+	Config   []directive.Arg `json:"config,omitempty"` // Remaining options, passed through to the plugin
+	Pos      Position        `json:"position"`
+}
+
+// ColumnName returns the database column name for the field, honouring @map
+func (f IRField) ColumnName() string {
+	if args := f.Type.GetDirective(directive.DirMap); len(args) > 0 {
+		return args[0]
+	}
+	return f.Name
+}
+
+// IsNullable reports whether the field was declared with @nullable, or with
+// @deletedAt, which implies it
+func (f IRField) IsNullable() bool {
+	return f.Type.HasDirective(directive.DirNullable) || f.Type.HasDirective(directive.DirDeletedAt)
+}
+
+// FindModel looks up a model by name
+func (ir *IR) FindModel(name string) (*IRModel, bool) {
+	for i := range ir.Models {
+		if ir.Models[i].Name == name {
+			return &ir.Models[i], true
+		}
+	}
+	return nil, false
+}
+
+// IsRelation reports whether the field references another model of the IR
+func (ir *IR) IsRelation(f IRField) bool {
+	if f.Type.Kind != field.KindCustom || f.Type.ModelName == "" {
+		return false
+	}
+	_, ok := ir.FindModel(f.Type.ModelName)
+	return ok
 }
 
 func ToIR(ast *parser.DSLFile) (*IR, error) {
 	ir := &IR{
-		DatabaseDriver: ast.DatabaseDriver,
-		DatabaseURL:    ast.DatabaseURL,
+		DatabaseDriver: strings.Trim(ast.DatabaseDriver, "\""),
+		DatabaseURL:    strings.Trim(ast.DatabaseURL, "\""),
 		Models:         make([]IRModel, 0, len(ast.Models)),
 	}
 
+	for _, g := range ast.Generators {
+		gen := IRGenerator{
+			Name: g.Name,
+			Pos:  Position{Filename: g.Pos.Filename, Line: g.Pos.Line, Column: g.Pos.Column},
+		}
+		for _, opt := range g.Options {
+			value := mapValue(opt.Value)
+			switch opt.Key {
+			case "provider":
+				gen.Provider = value.String()
+			case "output":
+				gen.Output = value.String()
+			default:
+				gen.Config = append(gen.Config, directive.Arg{Name: opt.Key, Value: value})
+			}
+		}
+		ir.Generators = append(ir.Generators, gen)
+	}
+
 	for _, m := range ast.Models {
 		model := IRModel{
 			Name:   m.Name,
 			Fields: make([]IRField, 0, len(m.Fields)),
+			Pos:    Position{Filename: m.Pos.Filename, Line: m.Pos.Line, Column: m.Pos.Column},
+			Doc:    m.Doc,
+		}
+		for _, rawDir := range m.Directives {
+			if dirObj := MapDirective(rawDir.Name, mapArgs(rawDir.Args)); dirObj != nil {
+				model.Directives = append(model.Directives, *dirObj)
+			}
 		}
 
 		for _, f := range m.Fields {
@@ -51,21 +131,7 @@ func ToIR(ast *parser.DSLFile) (*IR, error) {
 
 			directives := make([]directive.Directive, 0, len(f.Directives))
 			for _, rawDir := range f.Directives {
-				args := make([]string, 0, len(rawDir.Args))
-				for _, arg := range rawDir.Args {
-					switch {
-					case arg.String != nil:
-						args = append(args, *arg.String)
-					case arg.Ident != nil:
-						args = append(args, *arg.Ident)
-					case arg.Int != nil:
-						args = append(args, strconv.Itoa(*arg.Int))
-					case arg.Float != nil:
-						args = append(args, fmt.Sprintf("%f", *arg.Float))
-					}
-				}
-
-				if dirObj := MapDirective(rawDir.Name, args); dirObj != nil {
+				if dirObj := MapDirective(rawDir.Name, mapArgs(rawDir.Args)); dirObj != nil {
 					directives = append(directives, *dirObj)
 				}
 			}
@@ -75,6 +141,8 @@ func ToIR(ast *parser.DSLFile) (*IR, error) {
 				Name:    f.Name,
 				Type:    *field,
 				IsArray: f.Type.IsArray,
+				Pos:     Position{Filename: f.Pos.Filename, Line: f.Pos.Line, Column: f.Pos.Column},
+				Doc:     f.Doc,
 			})
 		}
 
@@ -84,6 +152,46 @@ func ToIR(ast *parser.DSLFile) (*IR, error) {
 	return ir, nil
 }
 
+// mapArgs converts parsed directive arguments into typed directive arguments
+func mapArgs(rawArgs []*parser.DirectiveArg) []directive.Arg {
+	args := make([]directive.Arg, 0, len(rawArgs))
+	for _, arg := range rawArgs {
+		mapped := directive.Arg{Value: mapValue(arg.Value)}
+		if arg.Key != nil {
+			mapped.Name = *arg.Key
+		}
+		args = append(args, mapped)
+	}
+	return args
+}
+
+// mapValue converts a parsed argument value into a typed directive value
+func mapValue(v *parser.Value) directive.Value {
+	switch {
+	case v.String != nil:
+		// The lexer keeps the surrounding quotes on string literals
+		return directive.Value{Kind: directive.ValueString, Text: strings.Trim(*v.String, "\"")}
+	case v.Float != nil:
+		return directive.Value{Kind: directive.ValueFloat, Float: *v.Float}
+	case v.Int != nil:
+		return directive.Value{Kind: directive.ValueInt, Int: *v.Int}
+	case v.Bool != nil:
+		return directive.Value{Kind: directive.ValueBool, Bool: bool(*v.Bool)}
+	case v.Call != nil:
+		return directive.Value{Kind: directive.ValueCall, Call: &directive.Call{Name: v.Call.Name, Args: mapArgs(v.Call.Args)}}
+	case v.Ident != nil:
+		return directive.Value{Kind: directive.ValueIdent, Text: *v.Ident}
+	case v.Array != nil:
+		items := make([]directive.Value, 0, len(v.Array.Values))
+		for _, item := range v.Array.Values {
+			items = append(items, mapValue(item))
+		}
+		return directive.Value{Kind: directive.ValueArray, Items: items}
+	default:
+		return directive.Value{}
+	}
+}
+
 // PrintIR prints the IR representation of the DSL file with database type information.
 func PrintIR(ir *IR) {
 	fmt.Println("=============== IR Models ===============")
@@ -119,7 +227,7 @@ func PrintIR(ir *IR) {
 						}
 
 						if len(dir.Args) > 0 {
-							fmt.Printf("%s @%-10s(%s) │\n", prefix, dir.Kind.String(), strings.Join(dir.Args, ", "))
+							fmt.Printf("%s @%-10s(%s) │\n", prefix, dir.Kind.String(), directive.FormatArgs(dir.Args))
 						} else {
 							fmt.Printf("%s @%-10s       │\n", prefix, dir.Kind.String())
 						}
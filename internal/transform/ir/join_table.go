@@ -0,0 +1,126 @@
+package ir
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pixperk/storm/internal/types/directive"
+	"github.com/pixperk/storm/internal/types/field"
+)
+
+// JoinColumn is one side of a many-to-many join table
+type JoinColumn struct {
+	Name       string          `json:"name"`       // Column in the join table
+	Table      string          `json:"table"`      // Referenced table
+	References string          `json:"references"` // Referenced primary key column
+	Type       field.FieldType `json:"type"`       // Type of the referenced primary key
+}
+
+// JoinTable links the two sides of a many-to-many relation
+type JoinTable struct {
+	Name    string     `json:"name"`
+	Through bool       `json:"through"` // Declared as a model with @relation(through: Model)
+	Source  JoinColumn `json:"source"`  // Side of the model declaring the field
+	Target  JoinColumn `json:"target"`  // Side of the related model
+}
+
+// JoinTable returns the join table backing a many-to-many field
+func (ir *IR) JoinTable(model IRModel, f IRField) (*JoinTable, bool) {
+	if !ir.IsManyToMany(model, f) {
+		return nil, false
+	}
+	paired, _ := ir.PairedField(model, f)
+	target, _ := ir.FindModel(f.Type.ModelName)
+
+	source := joinColumn(model)
+	dest := joinColumn(*target)
+
+	// Self-relations tell the two sides apart by field name order
+	sourceFirst := model.Name != target.Name || f.Name < paired.Name
+
+	if through := RelationThrough(f); through != "" {
+		joinModel, ok := ir.FindModel(through)
+		if !ok {
+			return nil, false
+		}
+		sourceKeys := belongsToColumns(*joinModel, model.Name)
+		targetKeys := belongsToColumns(*joinModel, target.Name)
+		if len(sourceKeys) == 0 || len(targetKeys) == 0 {
+			return nil, false
+		}
+
+		source.Name, dest.Name = sourceKeys[0], targetKeys[0]
+		if model.Name == target.Name {
+			if len(sourceKeys) < 2 {
+				return nil, false
+			}
+			source.Name, dest.Name = sourceKeys[0], sourceKeys[1]
+			if !sourceFirst {
+				source.Name, dest.Name = dest.Name, source.Name
+			}
+		}
+		return &JoinTable{Name: joinModel.Name, Through: true, Source: source, Target: dest}, true
+	}
+
+	if model.Name == target.Name {
+		source.Name, dest.Name = "A", "B"
+		if !sourceFirst {
+			source.Name, dest.Name = dest.Name, source.Name
+		}
+	} else {
+		source.Name, dest.Name = lowerFirst(model.Name)+"Id", lowerFirst(target.Name)+"Id"
+	}
+
+	name := RelationName(f)
+	if name == "" {
+		names := []string{model.Name, target.Name}
+		sort.Strings(names)
+		name = strings.Join(names, "To")
+	}
+
+	return &JoinTable{Name: "_" + name, Source: source, Target: dest}, true
+}
+
+// joinColumn returns a join column referencing the primary key of the model
+func joinColumn(model IRModel) JoinColumn {
+	col := JoinColumn{
+		Table:      model.Name,
+		References: "id",
+		Type:       *field.NewFieldType(field.KindInt, "", nil),
+	}
+	if pk, ok := model.PrimaryKey(); ok {
+		col.References = pk.ColumnName()
+		col.Type = *field.NewFieldType(pk.Type.Kind, "", nil)
+	}
+	return col
+}
+
+// belongsToColumns returns the foreign key columns of the model's @belongsTo fields pointing at target
+func belongsToColumns(model IRModel, target string) []string {
+	var cols []string
+	for _, f := range model.Fields {
+		if f.Type.HasDirective(directive.DirBelongsTo) && f.Type.ModelName == target {
+			cols = append(cols, model.ForeignKeyColumn(f))
+		}
+	}
+	return cols
+}
+
+// ForeignKeyColumn returns the column holding the key of a @belongsTo field,
+// using the scalar field the model declares for it when present
+func (m IRModel) ForeignKeyColumn(f IRField) string {
+	if existing, ok := m.FindField(ForeignKeyName(f)); ok {
+		return existing.ColumnName()
+	}
+	return ForeignKeyName(f)
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	runes := []rune(s)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,171 @@
+package ir
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pixperk/storm/internal/types/directive"
+	"github.com/pixperk/storm/internal/types/field"
+)
+
+// JSONVersion is the version of the JSON form of the IR. It is bumped whenever
+// a field is removed or changes meaning; new fields may appear in any version,
+// so consumers should ignore keys they do not know.
+//
+// The document has the following shape:
+//
+//	{
+//	  "version": 1,
+//	  "database": {"driver": "postgres", "url": "postgres://..."},
+//	  "models": [{
+//	    "name": "Post",
+//	    "position": {"filename": "schema.storm", "line": 7, "column": 1},
+//	    "fields": [{
+//	      "name": "title",
+//	      "type": {"kind": "String", "directives": [{"kind": "length", "args": [{"value": {"kind": "int", "int": 200}}]}]},
+//	      "array": false,
+//	      "nullable": false,
+//	      "relation": false,
+//	      "column": "title",
+//	      "position": {"filename": "schema.storm", "line": 9, "column": 3}
+//	    }]
+//	  }],
+//	  "relations": [{
+//	    "name": "authored",
+//	    "kind": "one-to-many",
+//	    "from": {"model": "User", "field": "posts"},
+//	    "to": {"model": "Post", "field": "author"},
+//	    "foreignKey": {"model": "Post", "column": "authorId", "references": "User", "referenced": "id"}
+//	  }]
+//	}
+//
+// Field kinds use the names of field.FieldKind ("Int", "DateTime", ...),
+// directive kinds the lower-case directive names ("belongsto", "index", ...)
+// and argument values the names of directive.ValueKind ("string", "int", ...).
+// Relation fields carry the related model in type.model and have no column.
+// Models and fields documented with /// comments carry the text in doc.
+// Models declaring directives after their name, such as @renamedFrom, list
+// them in directives.
+// Relations lists every relation once with both of its declared fields paired;
+// many-to-many relations carry a joinTable instead of a foreignKey.
+// Generators, when the schema declares any, lists its generator blocks with
+// their provider, output directory and remaining options as named arguments.
+const JSONVersion = 1
+
+// Document is the JSON form of the IR
+type Document struct {
+	Version   int         `json:"version"`
+	Database  DatabaseDoc `json:"database"`
+	Models    []ModelDoc  `json:"models"`
+	Relations []Relation  `json:"relations"`
+	// Generators lists the generator blocks of the schema
+	Generators []IRGenerator `json:"generators,omitempty"`
+}
+
+// DatabaseDoc is the database configuration of a Document
+type DatabaseDoc struct {
+	Driver string `json:"driver"`
+	URL    string `json:"url"`
+}
+
+// ModelDoc is a model of a Document
+type ModelDoc struct {
+	Name     string     `json:"name"`
+	Doc      string     `json:"doc,omitempty"`
+	Fields   []FieldDoc `json:"fields"`
+	Position Position   `json:"position"`
+	// Directives lists the model-level directives
+	Directives []directive.Directive `json:"directives,omitempty"`
+}
+
+// FieldDoc is a field of a ModelDoc
+type FieldDoc struct {
+	Name     string          `json:"name"`
+	Doc      string          `json:"doc,omitempty"`
+	Type     field.FieldType `json:"type"`
+	Array    bool            `json:"array"`
+	Nullable bool            `json:"nullable"`
+	Relation bool            `json:"relation"`
+	Column   string          `json:"column,omitempty"`
+	Position Position        `json:"position"`
+}
+
+// ToDocument converts the IR into its JSON form
+func ToDocument(irData *IR) *Document {
+	doc := &Document{
+		Version:    JSONVersion,
+		Database:   DatabaseDoc{Driver: irData.DatabaseDriver, URL: irData.DatabaseURL},
+		Models:     make([]ModelDoc, 0, len(irData.Models)),
+		Relations:  irData.Relations(),
+		Generators: irData.Generators,
+	}
+	if doc.Relations == nil {
+		doc.Relations = []Relation{}
+	}
+
+	for _, m := range irData.Models {
+		model := ModelDoc{Name: m.Name, Doc: m.Doc, Fields: make([]FieldDoc, 0, len(m.Fields)), Position: m.Pos, Directives: m.Directives}
+		for _, f := range m.Fields {
+			fieldDoc := FieldDoc{
+				Name:     f.Name,
+				Doc:      f.Doc,
+				Type:     f.Type,
+				Array:    f.IsArray,
+				Nullable: f.IsNullable(),
+				Relation: irData.IsRelation(f),
+				Position: f.Pos,
+			}
+			if fieldDoc.Type.Directives == nil {
+				fieldDoc.Type.Directives = []directive.Directive{}
+			}
+			if !fieldDoc.Relation {
+				fieldDoc.Column = f.ColumnName()
+			}
+			model.Fields = append(model.Fields, fieldDoc)
+		}
+		doc.Models = append(doc.Models, model)
+	}
+
+	return doc
+}
+
+// FromDocument rebuilds the IR from its JSON form. Derived data such as
+// relations and columns is recomputed from the models.
+func FromDocument(doc *Document) (*IR, error) {
+	if doc.Version != JSONVersion {
+		return nil, fmt.Errorf("unsupported IR JSON version %d (expected %d)", doc.Version, JSONVersion)
+	}
+
+	irData := &IR{
+		DatabaseDriver: doc.Database.Driver,
+		DatabaseURL:    doc.Database.URL,
+		Models:         make([]IRModel, 0, len(doc.Models)),
+		Generators:     doc.Generators,
+	}
+	for _, m := range doc.Models {
+		model := IRModel{Name: m.Name, Fields: make([]IRField, 0, len(m.Fields)), Directives: m.Directives, Pos: m.Position, Doc: m.Doc}
+		for _, f := range m.Fields {
+			model.Fields = append(model.Fields, IRField{Name: f.Name, Type: f.Type, IsArray: f.Array, Pos: f.Position, Doc: f.Doc})
+		}
+		irData.Models = append(irData.Models, model)
+	}
+
+	return irData, nil
+}
+
+// EncodeJSON writes the JSON form of the IR
+func EncodeJSON(w io.Writer, irData *IR) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ToDocument(irData))
+}
+
+// DecodeJSON reads an IR written by EncodeJSON
+func DecodeJSON(r io.Reader) (*IR, error) {
+	var doc Document
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode IR JSON: %w", err)
+	}
+	return FromDocument(&doc)
+}
@@ -55,6 +55,12 @@ func MapFieldType(s string) field.FieldKind {
 	case "point":
 		return field.KindPoint
 
+	// Identifiers
+	case "uuid":
+		return field.KindUUID
+	case "cuid":
+		return field.KindCUID
+
 	default:
 		// Handle as a custom type or return a default
 		return field.KindCustom
@@ -62,7 +68,7 @@ func MapFieldType(s string) field.FieldKind {
 }
 
 // MapDirective maps a string representation of a directive to a Directive
-func MapDirective(name string, args []string) *directive.Directive {
+func MapDirective(name string, args []directive.Arg) *directive.Directive {
 	name = strings.ToLower(strings.TrimSpace(name))
 
 	var kind directive.DirectiveKind
@@ -107,6 +113,10 @@ func MapDirective(name string, args []string) *directive.Directive {
 		kind = directive.DirMap
 	case "relation":
 		kind = directive.DirRelation
+	case "renamedfrom":
+		kind = directive.DirRenamedFrom
+	case "deletedat":
+		kind = directive.DirDeletedAt
 	default:
 		// Handle unknown directive - could return error instead
 		return nil
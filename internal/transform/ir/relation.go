@@ -0,0 +1,261 @@
+package ir
+
+import (
+	"fmt"
+
+	"github.com/pixperk/storm/internal/types/directive"
+)
+
+// ForeignKeyName returns the name of the scalar field holding the foreign key of a
+// @belongsTo field: the first of @relation(fields: [...]) or the field name suffixed with Id
+func ForeignKeyName(f IRField) string {
+	if dir, ok := f.Type.Directive(directive.DirRelation); ok {
+		if fields, ok := dir.Option("fields"); ok && len(fields.Items) > 0 {
+			return fields.Items[0].String()
+		}
+	}
+	return f.Name + "Id"
+}
+
+// ForeignKeyReference returns the field of the related model a @belongsTo key
+// points at, named with @relation(references: [...]), or "" for its primary key
+func ForeignKeyReference(f IRField) string {
+	if dir, ok := f.Type.Directive(directive.DirRelation); ok {
+		if refs, ok := dir.Option("references"); ok && len(refs.Items) > 0 {
+			return refs.Items[0].String()
+		}
+	}
+	return ""
+}
+
+// PrimaryKey returns the @id field of the model
+func (m IRModel) PrimaryKey() (IRField, bool) {
+	for _, f := range m.Fields {
+		if f.Type.HasDirective(directive.DirID) {
+			return f, true
+		}
+	}
+	return IRField{}, false
+}
+
+// FindField looks up a field of the model by name
+func (m IRModel) FindField(name string) (IRField, bool) {
+	for _, f := range m.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return IRField{}, false
+}
+
+// RelationName returns the name given to a relation field with @relation, or ""
+func RelationName(f IRField) string {
+	dir, ok := f.Type.Directive(directive.DirRelation)
+	if !ok {
+		return ""
+	}
+	if name, ok := dir.Option("name"); ok {
+		return name.String()
+	}
+	if positional := dir.Positional(); len(positional) > 0 && positional[0].IsText() {
+		return positional[0].String()
+	}
+	return ""
+}
+
+// RelationThrough returns the explicit join model named with @relation(through: Model), or ""
+func RelationThrough(f IRField) string {
+	dir, ok := f.Type.Directive(directive.DirRelation)
+	if !ok {
+		return ""
+	}
+	if through, ok := dir.Option("through"); ok {
+		return through.String()
+	}
+	return ""
+}
+
+// IsRelationField reports whether the field carries one of the relation directives
+func IsRelationField(f IRField) bool {
+	return f.Type.HasDirective(directive.DirHasMany) ||
+		f.Type.HasDirective(directive.DirBelongsTo) ||
+		f.Type.HasDirective(directive.DirHasOne)
+}
+
+// PairedField returns the field on the related model forming the other side of
+// the relation. Fields named with @relation only pair with the same name, and a
+// self-relation never pairs a field with itself.
+func (ir *IR) PairedField(model IRModel, f IRField) (IRField, bool) {
+	target, ok := ir.FindModel(f.Type.ModelName)
+	if !ok {
+		return IRField{}, false
+	}
+	return PairField(model, *target, f)
+}
+
+// PairField looks up the counterpart of the relation field f of model on target
+func PairField(model IRModel, target IRModel, f IRField) (IRField, bool) {
+	name := RelationName(f)
+	for _, candidate := range target.Fields {
+		if target.Name == model.Name && candidate.Name == f.Name {
+			continue
+		}
+		if !IsRelationField(candidate) || candidate.Type.ModelName != model.Name {
+			continue
+		}
+		if RelationName(candidate) == name {
+			return candidate, true
+		}
+	}
+	return IRField{}, false
+}
+
+// IsManyToMany reports whether the field is a @hasMany whose paired field is also a @hasMany
+func (ir *IR) IsManyToMany(model IRModel, f IRField) bool {
+	if !f.Type.HasDirective(directive.DirHasMany) {
+		return false
+	}
+	paired, ok := ir.PairedField(model, f)
+	return ok && paired.Type.HasDirective(directive.DirHasMany)
+}
+
+// RelationKind classifies a resolved relation
+type RelationKind int
+
+const (
+	OneToOne RelationKind = iota
+	OneToMany
+	ManyToMany
+)
+
+// String returns the name of the relation kind
+func (k RelationKind) String() string {
+	switch k {
+	case OneToOne:
+		return "one-to-one"
+	case OneToMany:
+		return "one-to-many"
+	case ManyToMany:
+		return "many-to-many"
+	default:
+		return ""
+	}
+}
+
+// MarshalText encodes the kind by name, e.g. "one-to-many"
+func (k RelationKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes a kind encoded by MarshalText
+func (k *RelationKind) UnmarshalText(text []byte) error {
+	for _, kind := range []RelationKind{OneToOne, OneToMany, ManyToMany} {
+		if kind.String() == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown relation kind: %s", text)
+}
+
+// RelationEnd is one side of a relation; Field is empty when that side declares no field
+type RelationEnd struct {
+	Model string `json:"model"`
+	Field string `json:"field,omitempty"`
+}
+
+// ForeignKey is the column implementing a to-one relation
+type ForeignKey struct {
+	Model      string `json:"model"`      // Model whose table holds the key
+	Column     string `json:"column"`     // Key column
+	References string `json:"references"` // Referenced model
+	Referenced string `json:"referenced"` // Referenced column
+}
+
+// Relation is a relation between two models, with its declared fields paired
+type Relation struct {
+	Name       string       `json:"name,omitempty"`
+	Kind       RelationKind `json:"kind"`
+	From       RelationEnd  `json:"from"` // The parent side: @hasMany, @hasOne, or either side of many-to-many
+	To         RelationEnd  `json:"to"`   // The child side, holding the foreign key of to-one relations
+	ForeignKey *ForeignKey  `json:"foreignKey,omitempty"`
+	JoinTable  *JoinTable   `json:"joinTable,omitempty"`
+}
+
+// Relations resolves every relation of the IR, once per pair of fields
+func (ir *IR) Relations() []Relation {
+	var relations []Relation
+	seen := make(map[RelationEnd]bool)
+
+	for _, model := range ir.Models {
+		for _, f := range model.Fields {
+			self := RelationEnd{Model: model.Name, Field: f.Name}
+			if !IsRelationField(f) || !ir.IsRelation(f) || seen[self] {
+				continue
+			}
+			seen[self] = true
+
+			target, _ := ir.FindModel(f.Type.ModelName)
+			other := RelationEnd{Model: target.Name}
+			paired, ok := ir.PairedField(model, f)
+			if ok {
+				other.Field = paired.Name
+				seen[other] = true
+			}
+
+			rel := Relation{Name: RelationName(f)}
+			switch {
+			case f.Type.HasDirective(directive.DirBelongsTo):
+				rel.Kind = OneToMany
+				if ok && paired.Type.HasDirective(directive.DirHasOne) {
+					rel.Kind = OneToOne
+				}
+				rel.From, rel.To = other, self
+				rel.ForeignKey = ir.foreignKey(model, f)
+			case ok && paired.Type.HasDirective(directive.DirBelongsTo):
+				rel.Kind = OneToMany
+				if f.Type.HasDirective(directive.DirHasOne) {
+					rel.Kind = OneToOne
+				}
+				rel.From, rel.To = self, other
+				rel.ForeignKey = ir.foreignKey(*target, paired)
+			case f.Type.HasDirective(directive.DirHasMany) && ok && paired.Type.HasDirective(directive.DirHasMany):
+				rel.Kind = ManyToMany
+				rel.From, rel.To = self, other
+				rel.JoinTable, _ = ir.JoinTable(model, f)
+			case f.Type.HasDirective(directive.DirHasOne):
+				rel.Kind = OneToOne
+				rel.From, rel.To = self, other
+			default:
+				rel.Kind = OneToMany
+				rel.From, rel.To = self, other
+			}
+			if rel.Name == "" && ok {
+				rel.Name = RelationName(paired)
+			}
+
+			relations = append(relations, rel)
+		}
+	}
+	return relations
+}
+
+// foreignKey returns the key column of a @belongsTo field of model
+func (ir *IR) foreignKey(model IRModel, f IRField) *ForeignKey {
+	fk := &ForeignKey{
+		Model:      model.Name,
+		Column:     model.ForeignKeyColumn(f),
+		References: f.Type.ModelName,
+		Referenced: "id",
+	}
+	if target, ok := ir.FindModel(f.Type.ModelName); ok {
+		referenced, ok := target.PrimaryKey()
+		if ref := ForeignKeyReference(f); ref != "" {
+			referenced, ok = target.FindField(ref)
+		}
+		if ok {
+			fk.Referenced = referenced.ColumnName()
+		}
+	}
+	return fk
+}
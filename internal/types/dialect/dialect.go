@@ -0,0 +1,58 @@
+package dialect
+
+import "strings"
+
+// Dialect identifies the SQL flavour of the target database
+type Dialect int
+
+const (
+	Unknown Dialect = iota
+	MySQL
+	Postgres
+	SQLite
+)
+
+// Parse maps a database driver name to its dialect
+func Parse(driver string) Dialect {
+	switch strings.ToLower(strings.Trim(driver, "\"'")) {
+	case "mysql":
+		return MySQL
+	case "postgres", "postgresql":
+		return Postgres
+	case "sqlite", "sqlite3":
+		return SQLite
+	default:
+		return Unknown
+	}
+}
+
+// String returns the canonical driver name of the dialect
+func (d Dialect) String() string {
+	switch d {
+	case MySQL:
+		return "mysql"
+	case Postgres:
+		return "postgres"
+	case SQLite:
+		return "sqlite"
+	default:
+		return "unknown"
+	}
+}
+
+// QuoteIdent quotes a table or column name for the dialect
+func (d Dialect) QuoteIdent(name string) string {
+	if d == MySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteString renders a string literal for the dialect
+func (d Dialect) QuoteString(s string) string {
+	if d == MySQL {
+		// MySQL treats backslashes in string literals as escapes
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -1,13 +1,55 @@
 package directive
 
 type Directive struct {
-	Kind DirectiveKind
-	Args []string
+	Kind DirectiveKind `json:"kind"`
+	Args []Arg         `json:"args,omitempty"` // Positional and named arguments in source order
 }
 
-func NewDirective(kind DirectiveKind, args []string) *Directive {
+func NewDirective(kind DirectiveKind, args []Arg) *Directive {
 	return &Directive{
 		Kind: kind,
 		Args: args,
 	}
 }
+
+// Positional returns the arguments written without a name
+func (d Directive) Positional() []Value {
+	var values []Value
+	for _, arg := range d.Args {
+		if arg.Name == "" {
+			values = append(values, arg.Value)
+		}
+	}
+	return values
+}
+
+// Named returns the arguments written as key: value
+func (d Directive) Named() []Arg {
+	var named []Arg
+	for _, arg := range d.Args {
+		if arg.Name != "" {
+			named = append(named, arg)
+		}
+	}
+	return named
+}
+
+// Option returns the value of a named argument
+func (d Directive) Option(key string) (Value, bool) {
+	for _, arg := range d.Args {
+		if arg.Name == key {
+			return arg.Value, true
+		}
+	}
+	return Value{}, false
+}
+
+// StringArgs returns the positional arguments in their string form
+func (d Directive) StringArgs() []string {
+	positional := d.Positional()
+	args := make([]string, 0, len(positional))
+	for _, value := range positional {
+		args = append(args, value.String())
+	}
+	return args
+}
@@ -1,5 +1,7 @@
 package directive
 
+import "fmt"
+
 type DirectiveKind int
 
 const (
@@ -22,6 +24,8 @@ const (
 	DirDefaultNow
 	DirMap
 	DirRelation
+	DirRenamedFrom
+	DirDeletedAt
 )
 
 // String returns the string representation of the directive kind
@@ -65,7 +69,56 @@ func (d DirectiveKind) String() string {
 		return "map"
 	case DirRelation:
 		return "relation"
+	case DirRenamedFrom:
+		return "renamedfrom"
+	case DirDeletedAt:
+		return "deletedat"
 	default:
 		return ""
 	}
 }
+
+// sourceNames holds the directives spelled in camel case in schemas
+var sourceNames = map[DirectiveKind]string{
+	DirHasMany:     "hasMany",
+	DirBelongsTo:   "belongsTo",
+	DirHasOne:      "hasOne",
+	DirUpdatedAt:   "updatedAt",
+	DirCreatedAt:   "createdAt",
+	DirDefaultNow:  "defaultNow",
+	DirRenamedFrom: "renamedFrom",
+	DirDeletedAt:   "deletedAt",
+}
+
+// SourceName returns the directive name as written in schemas, e.g. "belongsTo"
+func (d DirectiveKind) SourceName() string {
+	if name, ok := sourceNames[d]; ok {
+		return name
+	}
+	return d.String()
+}
+
+// ParseDirectiveKind returns the kind whose String form is s
+func ParseDirectiveKind(s string) (DirectiveKind, bool) {
+	for kind := DirID; kind <= DirDeletedAt; kind++ {
+		if kind.String() == s {
+			return kind, true
+		}
+	}
+	return 0, false
+}
+
+// MarshalText encodes the kind by name, e.g. "belongsto"
+func (d DirectiveKind) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes a kind encoded by MarshalText
+func (d *DirectiveKind) UnmarshalText(text []byte) error {
+	kind, ok := ParseDirectiveKind(string(text))
+	if !ok {
+		return fmt.Errorf("unknown directive: %s", text)
+	}
+	*d = kind
+	return nil
+}
@@ -0,0 +1,203 @@
+package directive
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ValueKind identifies the type of a directive argument value
+type ValueKind int
+
+const (
+	ValueString ValueKind = iota // "quoted text"
+	ValueIdent                   // bare identifier, e.g. Desc
+	ValueInt                     // 42
+	ValueFloat                   // 3.14
+	ValueBool                    // true, false
+	ValueArray                   // [a, b]
+	ValueCall                    // now(), fn(a, key: b)
+)
+
+// Value is a typed directive argument value
+type Value struct {
+	Kind  ValueKind `json:"kind"`
+	Text  string    `json:"text,omitempty"`  // String literal contents or identifier name
+	Int   int64     `json:"int,omitempty"`   // Set for ValueInt
+	Float float64   `json:"float,omitempty"` // Set for ValueFloat
+	Bool  bool      `json:"bool,omitempty"`  // Set for ValueBool
+	Items []Value   `json:"items,omitempty"` // Elements of a ValueArray
+	Call  *Call     `json:"call,omitempty"`  // Set for ValueCall
+}
+
+// Call is a function call used as a directive argument value
+type Call struct {
+	Name string `json:"name"`
+	Args []Arg  `json:"args,omitempty"`
+}
+
+// Arg is a directive or call argument, positional when Name is empty
+type Arg struct {
+	Name  string `json:"name,omitempty"`
+	Value Value  `json:"value"`
+}
+
+var valueKindNames = map[ValueKind]string{
+	ValueString: "string",
+	ValueIdent:  "ident",
+	ValueInt:    "int",
+	ValueFloat:  "float",
+	ValueBool:   "bool",
+	ValueArray:  "array",
+	ValueCall:   "call",
+}
+
+// String returns the name of the value kind
+func (k ValueKind) String() string {
+	return valueKindNames[k]
+}
+
+// MarshalText encodes the kind by name, e.g. "int"
+func (k ValueKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText decodes a kind encoded by MarshalText
+func (k *ValueKind) UnmarshalText(text []byte) error {
+	for kind, name := range valueKindNames {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown value kind: %s", text)
+}
+
+// MarshalJSON encodes the kind and the field it sets, even when that holds
+// the zero value, so @default(0), @default(false) and @default("") keep it
+func (v Value) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Kind  ValueKind `json:"kind"`
+		Text  *string   `json:"text,omitempty"`
+		Int   *int64    `json:"int,omitempty"`
+		Float *float64  `json:"float,omitempty"`
+		Bool  *bool     `json:"bool,omitempty"`
+		Items *[]Value  `json:"items,omitempty"`
+		Call  *Call     `json:"call,omitempty"`
+	}{Kind: v.Kind, Call: v.Call}
+
+	switch v.Kind {
+	case ValueString, ValueIdent:
+		out.Text = &v.Text
+	case ValueInt:
+		out.Int = &v.Int
+	case ValueFloat:
+		out.Float = &v.Float
+	case ValueBool:
+		out.Bool = &v.Bool
+	case ValueArray:
+		items := v.Items
+		if items == nil {
+			items = []Value{}
+		}
+		out.Items = &items
+	}
+	return json.Marshal(out)
+}
+
+// StringValue creates a string literal value
+func StringValue(s string) Value {
+	return Value{Kind: ValueString, Text: s}
+}
+
+// IntValue creates an integer value
+func IntValue(i int64) Value {
+	return Value{Kind: ValueInt, Int: i}
+}
+
+// IsNumber reports whether the value is an integer or float literal
+func (v Value) IsNumber() bool {
+	return v.Kind == ValueInt || v.Kind == ValueFloat
+}
+
+// IsText reports whether the value is a string literal or an identifier
+func (v Value) IsText() bool {
+	return v.Kind == ValueString || v.Kind == ValueIdent
+}
+
+// Number returns the numeric value of an integer or float literal
+func (v Value) Number() (float64, bool) {
+	switch v.Kind {
+	case ValueInt:
+		return float64(v.Int), true
+	case ValueFloat:
+		return v.Float, true
+	default:
+		return 0, false
+	}
+}
+
+// Strings returns the elements of an array as strings, or the value itself as a single element
+func (v Value) Strings() []string {
+	if v.Kind != ValueArray {
+		return []string{v.String()}
+	}
+	items := make([]string, 0, len(v.Items))
+	for _, item := range v.Items {
+		items = append(items, item.String())
+	}
+	return items
+}
+
+// String returns the value as written in the schema, without quotes around string literals
+func (v Value) String() string {
+	switch v.Kind {
+	case ValueString, ValueIdent:
+		return v.Text
+	case ValueInt:
+		return strconv.FormatInt(v.Int, 10)
+	case ValueFloat:
+		return strconv.FormatFloat(v.Float, 'g', -1, 64)
+	case ValueBool:
+		return strconv.FormatBool(v.Bool)
+	case ValueArray:
+		return "[" + strings.Join(v.Strings(), ", ") + "]"
+	case ValueCall:
+		if v.Call == nil {
+			return ""
+		}
+		return v.Call.Name + "(" + FormatArgs(v.Call.Args) + ")"
+	default:
+		return ""
+	}
+}
+
+// Source returns the value as written in the schema, quoting string literals
+func (v Value) Source() string {
+	switch v.Kind {
+	case ValueString:
+		return strconv.Quote(v.Text)
+	case ValueArray:
+		items := make([]string, 0, len(v.Items))
+		for _, item := range v.Items {
+			items = append(items, item.Source())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return v.String()
+	}
+}
+
+// FormatArgs renders arguments the way they are written in the schema
+func FormatArgs(args []Arg) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		value := arg.Value.Source()
+		if arg.Name != "" {
+			value = arg.Name + ": " + value
+		}
+		parts = append(parts, value)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -1,5 +1,7 @@
 package field
 
+import "fmt"
+
 type FieldKind int
 
 const (
@@ -80,3 +82,28 @@ func (f FieldKind) String() string {
 		return "Unknown"
 	}
 }
+
+// ParseFieldKind returns the kind whose String form is s
+func ParseFieldKind(s string) (FieldKind, bool) {
+	for kind := KindInt; kind <= KindCustom; kind++ {
+		if kind.String() == s {
+			return kind, true
+		}
+	}
+	return KindCustom, false
+}
+
+// MarshalText encodes the kind by name, e.g. "DateTime"
+func (f FieldKind) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText decodes a kind encoded by MarshalText
+func (f *FieldKind) UnmarshalText(text []byte) error {
+	kind, ok := ParseFieldKind(string(text))
+	if !ok {
+		return fmt.Errorf("unknown field kind: %s", text)
+	}
+	*f = kind
+	return nil
+}
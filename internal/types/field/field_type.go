@@ -8,9 +8,9 @@ import (
 
 // FieldType represents a complete field type with options
 type FieldType struct {
-	Kind       FieldKind
-	ModelName  string // Used for custom/relation types to store the model name
-	Directives []directive.Directive
+	Kind       FieldKind             `json:"kind"`
+	ModelName  string                `json:"model,omitempty"` // Used for custom/relation types to store the model name
+	Directives []directive.Directive `json:"directives,omitempty"`
 }
 
 // NewFieldType creates a new FieldType with the given kind
@@ -22,13 +22,32 @@ func NewFieldType(kind FieldKind, modelName string, dirs []directive.Directive)
 	}
 }
 
+// GetDirective returns the positional arguments of the directive in their string form
 func (ft *FieldType) GetDirective(kind directive.DirectiveKind) []string {
+	if dir, ok := ft.Directive(kind); ok {
+		return dir.StringArgs()
+	}
+	return nil
+}
+
+// Directive returns the first directive of the given kind
+func (ft FieldType) Directive(kind directive.DirectiveKind) (directive.Directive, bool) {
 	for _, dir := range ft.Directives {
 		if dir.Kind == kind {
-			return dir.Args
+			return dir, true
 		}
 	}
-	return nil
+	return directive.Directive{}, false
+}
+
+// HasDirective reports whether the field type carries a directive of the given kind
+func (ft FieldType) HasDirective(kind directive.DirectiveKind) bool {
+	for _, dir := range ft.Directives {
+		if dir.Kind == kind {
+			return true
+		}
+	}
+	return false
 }
 
 // GetLength returns the length of the field type, defaulting to 255 if not specified
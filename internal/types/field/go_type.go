@@ -0,0 +1,46 @@
+package field
+
+// GoType returns the Go type used for the field in generated code.
+// Nullable fields and relations to other models are resolved by the generator.
+func (ft FieldType) GoType() string {
+	switch ft.Kind {
+	case KindInt:
+		return "int"
+	case KindFloat:
+		return "float64"
+	case KindDecimal:
+		return "float64"
+	case KindBigInt:
+		return "int64"
+	case KindString:
+		return "string"
+	case KindText:
+		return "string"
+	case KindChar:
+		return "string"
+	case KindBoolean:
+		return "bool"
+	case KindDateTime:
+		return "time.Time"
+	case KindDate:
+		return "time.Time"
+	case KindTime:
+		return "time.Time"
+	case KindTimestamp:
+		return "time.Time"
+	case KindBinary:
+		return "[]byte"
+	case KindJSON:
+		return "json.RawMessage"
+	case KindUUID:
+		return "string"
+	case KindCUID:
+		return "string"
+	case KindPoint:
+		return "string"
+	case KindCustom:
+		return "string"
+	default:
+		return "string"
+	}
+}
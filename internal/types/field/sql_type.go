@@ -0,0 +1,15 @@
+package field
+
+import "github.com/pixperk/storm/internal/types/dialect"
+
+// SQLType returns the column type of the field for the given dialect
+func (ft FieldType) SQLType(d dialect.Dialect) string {
+	switch d {
+	case dialect.MySQL:
+		return ft.MySQLType()
+	case dialect.SQLite:
+		return ft.SQLiteType()
+	default:
+		return ft.PostgresType()
+	}
+}
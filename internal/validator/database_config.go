@@ -7,6 +7,7 @@ import (
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
 )
 
 // validateDatabaseConfig validates the database configuration
@@ -20,10 +21,7 @@ func validateDatabaseConfig(irData *ir.IR) error {
 		driverName := strings.Trim(irData.DatabaseDriver, "\"'")
 
 		// Validate supported database drivers
-		switch driverName {
-		case "mysql", "postgres", "postgresql", "sqlite", "sqlite3":
-			// Valid drivers, no error
-		default:
+		if dialect.Parse(driverName) == dialect.Unknown {
 			errList = multierror.Append(errList, fmt.Errorf("unsupported database driver: %s", driverName))
 		}
 	}
@@ -0,0 +1,45 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	"github.com/pixperk/storm/internal/types/directive"
+)
+
+// ValidateDialect checks the parts of a schema that depend on the SQL
+// dialect against d, which may differ from the schema's database driver
+// when DDL is generated for another database
+func ValidateDialect(irData *ir.IR, d dialect.Dialect) error {
+	errList := new(multierror.Error)
+	if err := validateIndexes(irData, d); err != nil {
+		errList = multierror.Append(errList, err)
+	}
+	if err := validateDefaultFunctions(irData, d); err != nil {
+		errList = multierror.Append(errList, err)
+	}
+	return errList.ErrorOrNil()
+}
+
+// validateDefaultFunctions checks that the dialect can compute the
+// @default function calls of the schema
+func validateDefaultFunctions(irData *ir.IR, d dialect.Dialect) error {
+	errList := new(multierror.Error)
+	for _, model := range irData.Models {
+		for _, field := range model.Fields {
+			dir, ok := field.Type.Directive(directive.DirDefault)
+			if !ok || len(dir.Positional()) != 1 {
+				continue
+			}
+			value := dir.Positional()[0]
+			// SQLite has no function generating UUIDs
+			if value.Kind == directive.ValueCall && value.Call.Name == "uuid" && d == dialect.SQLite {
+				errList = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+					"model %s: field %s: @default(uuid()) is not supported by %s", model.Name, field.Name, d)))
+			}
+		}
+	}
+	return errList.ErrorOrNil()
+}
@@ -19,7 +19,8 @@ func validateDirectives(field ir.IRField) error {
 			directive.DirNullable, directive.DirHasMany, directive.DirBelongsTo, directive.DirHasOne,
 			directive.DirIndex, directive.DirEnum, directive.DirUpdatedAt, directive.DirCreatedAt,
 			directive.DirLength, directive.DirMin, directive.DirMax, directive.DirPrecision,
-			directive.DirDefaultNow, directive.DirMap, directive.DirRelation:
+			directive.DirDefaultNow, directive.DirMap, directive.DirRelation, directive.DirRenamedFrom,
+			directive.DirDeletedAt:
 			// Valid directive kind
 		default:
 			errList = multierror.Append(errList, fmt.Errorf("unknown directive: %s", dir.Kind.String()))
@@ -2,81 +2,154 @@ package validator
 
 import (
 	"fmt"
-	"strconv"
+	"strings"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/pixperk/storm/internal/types/directive"
 )
 
+var validIndexMethods = map[string]bool{
+	"btree": true,
+	"hash":  true,
+	"gin":   true,
+	"gist":  true,
+	"brin":  true,
+}
+
+// namedArgKeys lists the named arguments each directive accepts; directives
+// missing from this map accept positional arguments only
+var namedArgKeys = map[directive.DirectiveKind]map[string]bool{
+	directive.DirIndex:    {"name": true, "sort": true, "where": true, "type": true, "include": true, "length": true},
+	directive.DirRelation: {"name": true, "fields": true, "references": true, "through": true},
+}
+
+// defaultFunctions lists the functions accepted as @default values
+var defaultFunctions = map[string]bool{
+	"now":  true,
+	"uuid": true,
+}
+
 // validateDirectiveArgs checks if the directive arguments are valid
 func validateDirectiveArgs(dir directive.Directive) error {
 	errList := new(multierror.Error)
+	args := dir.Positional()
+
+	// Named arguments must be known to the directive and given at most once
+	seen := make(map[string]bool)
+	for _, arg := range dir.Named() {
+		if !namedArgKeys[dir.Kind][arg.Name] {
+			errList = multierror.Append(errList, fmt.Errorf("@%s directive does not accept a %s: argument", dir.Kind.String(), arg.Name))
+		} else if seen[arg.Name] {
+			errList = multierror.Append(errList, fmt.Errorf("@%s directive argument %s: given more than once", dir.Kind.String(), arg.Name))
+		}
+		seen[arg.Name] = true
+	}
 
 	switch dir.Kind {
 	case directive.DirLength:
 		// @length requires exactly one integer argument
-		if len(dir.Args) != 1 {
+		if len(args) != 1 {
 			errList = multierror.Append(errList, fmt.Errorf("@length directive requires exactly one integer argument"))
-		} else if _, err := strconv.Atoi(dir.Args[0]); err != nil {
+		} else if args[0].Kind != directive.ValueInt {
 			errList = multierror.Append(errList, fmt.Errorf("@length directive argument must be an integer"))
 		}
 
 	case directive.DirPrecision:
 		// @precision requires exactly two integer arguments
-		if len(dir.Args) != 2 {
+		if len(args) != 2 {
 			errList = multierror.Append(errList, fmt.Errorf("@precision directive requires exactly two integer arguments"))
 		} else {
-			p, err1 := strconv.Atoi(dir.Args[0])
-			s, err2 := strconv.Atoi(dir.Args[1])
+			p, s := args[0], args[1]
 
-			if err1 != nil || err2 != nil {
+			if p.Kind != directive.ValueInt || s.Kind != directive.ValueInt {
 				errList = multierror.Append(errList, fmt.Errorf("@precision directive arguments must be integers"))
-			} else if p <= 0 {
+			} else if p.Int <= 0 {
 				errList = multierror.Append(errList, fmt.Errorf("@precision first argument (precision) must be positive"))
-			} else if s < 0 || s > p {
+			} else if s.Int < 0 || s.Int > p.Int {
 				errList = multierror.Append(errList, fmt.Errorf("@precision second argument (scale) must be between 0 and precision"))
 			}
 		}
 
 	case directive.DirMin, directive.DirMax:
 		// @min and @max require exactly one numeric argument
-		if len(dir.Args) != 1 {
+		if len(args) != 1 {
 			errList = multierror.Append(errList, fmt.Errorf("@%s directive requires exactly one numeric argument", dir.Kind.String()))
-		} else {
-			_, err := strconv.ParseFloat(dir.Args[0], 64)
-			if err != nil {
-				errList = multierror.Append(errList, fmt.Errorf("@%s directive argument must be a number", dir.Kind.String()))
-			}
+		} else if !args[0].IsNumber() {
+			errList = multierror.Append(errList, fmt.Errorf("@%s directive argument must be a number", dir.Kind.String()))
 		}
 
 	case directive.DirDefault:
-		// @default requires exactly one argument
-		if len(dir.Args) != 1 {
+		// @default requires exactly one scalar argument or a known function call
+		if len(args) != 1 {
 			errList = multierror.Append(errList, fmt.Errorf("@default directive requires exactly one argument"))
+		} else if args[0].Kind == directive.ValueArray {
+			errList = multierror.Append(errList, fmt.Errorf("@default directive argument cannot be an array"))
+		} else if args[0].Kind == directive.ValueCall && !defaultFunctions[args[0].Call.Name] {
+			errList = multierror.Append(errList, fmt.Errorf("@default directive does not support function %s()", args[0].Call.Name))
 		}
 	case directive.DirHasMany, directive.DirBelongsTo, directive.DirID, directive.DirAuto,
-		directive.DirUnique, directive.DirIndex, directive.DirUpdatedAt, directive.DirCreatedAt,
-		directive.DirNullable, directive.DirDefaultNow, directive.DirHasOne:
+		directive.DirUnique, directive.DirUpdatedAt, directive.DirCreatedAt,
+		directive.DirNullable, directive.DirDefaultNow, directive.DirHasOne, directive.DirDeletedAt:
 		// These directives don't require arguments
-		if len(dir.Args) > 0 {
+		if len(args) > 0 {
 			errList = multierror.Append(errList, fmt.Errorf("@%s directive does not accept arguments", dir.Kind.String()))
 		}
 
+	case directive.DirIndex:
+		// @index accepts an optional positional name and named options
+		if len(args) > 1 {
+			errList = multierror.Append(errList, fmt.Errorf("@index directive accepts at most one positional argument (name)"))
+		}
+		if sort, ok := dir.Option("sort"); ok && !strings.EqualFold(sort.String(), "asc") && !strings.EqualFold(sort.String(), "desc") {
+			errList = multierror.Append(errList, fmt.Errorf("@index sort must be Asc or Desc, got %s", sort))
+		}
+		if method, ok := dir.Option("type"); ok && !validIndexMethods[strings.ToLower(method.String())] {
+			errList = multierror.Append(errList, fmt.Errorf("@index type must be one of BTree, Hash, Gin, Gist, Brin, got %s", method))
+		}
+		if where, ok := dir.Option("where"); ok && where.Kind != directive.ValueString {
+			errList = multierror.Append(errList, fmt.Errorf("@index where must be a string predicate"))
+		}
+		if length, ok := dir.Option("length"); ok && (length.Kind != directive.ValueInt || length.Int <= 0) {
+			errList = multierror.Append(errList, fmt.Errorf("@index length must be a positive integer"))
+		}
+
 	case directive.DirEnum:
 		// @enum requires at least one argument (enum values)
-		if len(dir.Args) < 1 {
+		if len(args) < 1 {
 			errList = multierror.Append(errList, fmt.Errorf("@enum directive requires at least one argument"))
 		}
+		for _, value := range args {
+			if !value.IsText() {
+				errList = multierror.Append(errList, fmt.Errorf("@enum directive values must be strings or identifiers, got %s", value))
+			}
+		}
 
 	case directive.DirMap:
 		// @map requires exactly one argument (table or column name)
-		if len(dir.Args) != 1 {
+		if len(args) != 1 {
 			errList = multierror.Append(errList, fmt.Errorf("@map directive requires exactly one argument"))
+		} else if !args[0].IsText() {
+			errList = multierror.Append(errList, fmt.Errorf("@map directive argument must be a name"))
+		}
+	case directive.DirRenamedFrom:
+		// @renamedFrom requires exactly one argument (the previous name)
+		if len(args) != 1 {
+			errList = multierror.Append(errList, fmt.Errorf("@renamedFrom directive requires exactly one argument"))
+		} else if !args[0].IsText() {
+			errList = multierror.Append(errList, fmt.Errorf("@renamedFrom directive argument must be a name"))
 		}
 	case directive.DirRelation:
-		// @relation can have 0-2 arguments (name and fields)
-		if len(dir.Args) > 2 {
-			errList = multierror.Append(errList, fmt.Errorf("@relation directive accepts at most two arguments"))
+		// @relation can have 0-2 positional arguments (name and fields)
+		if len(args) > 2 {
+			errList = multierror.Append(errList, fmt.Errorf("@relation directive accepts at most two positional arguments"))
+		}
+		if through, ok := dir.Option("through"); ok && through.Kind != directive.ValueIdent && through.Kind != directive.ValueString {
+			errList = multierror.Append(errList, fmt.Errorf("@relation through: must name a model"))
+		}
+		for _, key := range []string{"fields", "references"} {
+			if value, ok := dir.Option(key); ok && value.Kind != directive.ValueArray {
+				errList = multierror.Append(errList, fmt.Errorf("@relation %s: must be an array of field names", key))
+			}
 		}
 
 	default:
@@ -65,7 +65,20 @@ func validateDirectiveTypeCompatibility(field ir.IRField, dir directive.Directiv
 			errList = multierror.Append(errList, fmt.Errorf("@%s directive can only be used with DateTime or Timestamp types", dir.Kind.String()))
 		}
 
-		// Add more directive compatibility checks
+	case directive.DirDeletedAt:
+		// @deletedAt holds the time a row was soft deleted, NULL while it is not
+		if fieldKind != fld.KindDateTime && fieldKind != fld.KindTimestamp {
+			errList = multierror.Append(errList, fmt.Errorf("@deletedAt directive can only be used with DateTime or Timestamp types"))
+		}
+		if field.IsArray {
+			errList = multierror.Append(errList, fmt.Errorf("@deletedAt directive cannot be used with array fields"))
+		}
+		if field.Type.HasDirective(directive.DirID) || field.Type.HasDirective(directive.DirDefault) ||
+			field.Type.HasDirective(directive.DirDefaultNow) || field.Type.HasDirective(directive.DirCreatedAt) ||
+			field.Type.HasDirective(directive.DirUpdatedAt) {
+			errList = multierror.Append(errList, fmt.Errorf("@deletedAt directive cannot be combined with @id, @default, @defaultNow, @createdAt or @updatedAt"))
+		}
+
 	case directive.DirHasOne:
 		// @hasOne should be used with non-array relation fields
 		if field.IsArray {
@@ -78,6 +91,12 @@ func validateDirectiveTypeCompatibility(field ir.IRField, dir directive.Directiv
 			errList = multierror.Append(errList, fmt.Errorf("@enum directive can only be used with string types"))
 		}
 
+	case directive.DirIndex:
+		// @index length: prefixes only make sense on string columns
+		if _, ok := dir.Option("length"); ok && fieldKind != fld.KindString && fieldKind != fld.KindChar && fieldKind != fld.KindText {
+			errList = multierror.Append(errList, fmt.Errorf("@index length option can only be used with string types"))
+		}
+
 	case directive.DirNullable:
 		// @nullable can be used with any type, but typically not with relation fields
 		// No specific validation needed
@@ -89,6 +108,12 @@ func validateDirectiveTypeCompatibility(field ir.IRField, dir directive.Directiv
 	case directive.DirRelation:
 		// @relation should be used with model relation fields
 		// This would require more complex validation based on model references
+
+	case directive.DirRenamedFrom:
+		// @renamedFrom can be used with any type
+		// No specific validation needed
+
+		// Add more directive compatibility checks
 	}
 
 	return errList.ErrorOrNil()
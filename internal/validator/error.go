@@ -0,0 +1,43 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+)
+
+// Error is a validation error located at the model, field or block it concerns
+type Error struct {
+	Pos ir.Position
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// at locates err at pos
+func at(pos ir.Position, err error) error {
+	return &Error{Pos: pos, Err: err}
+}
+
+// Warning is a validation finding that does not make a schema invalid
+type Warning struct {
+	Pos     ir.Position
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Message
+}
+
+// warnings collects the warnings of a validation
+type warnings []Warning
+
+func (w *warnings) add(pos ir.Position, message string, args ...interface{}) {
+	*w = append(*w, Warning{Pos: pos, Message: fmt.Sprintf(message, args...)})
+}
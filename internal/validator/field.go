@@ -32,10 +32,13 @@ func ValidateField(field ir.IRField, model ir.IRModel, modelNames map[string]boo
 	if err := validateDirectives(field); err != nil {
 		errList = multierror.Append(errList, err)
 	}
+	if err := validateDefault(field); err != nil {
+		errList = multierror.Append(errList, err)
+	}
 	// Validate relation fields
 	relatedModelName := field.Type.String()
-	if modelNames[relatedModelName] && relatedModelName != model.Name {
-		// This is a relation field pointing to another model
+	if modelNames[relatedModelName] {
+		// This is a relation field pointing to another model, or to its own model
 		if !field.IsArray && !(hasDirective(field, directive.DirBelongsTo) || hasDirective(field, directive.DirHasOne)) {
 			errList = multierror.Append(errList, errors.New("relation field must have @belongsTo or @hasOne directive"))
 		}
@@ -91,3 +94,52 @@ func validateFieldType(field ir.IRField) error {
 
 	return errList.ErrorOrNil()
 }
+
+// validateDefault checks that the @default value suits the field type, so
+// the generators never have to coerce it
+func validateDefault(field ir.IRField) error {
+	dir, ok := field.Type.Directive(directive.DirDefault)
+	if !ok || len(dir.Positional()) != 1 {
+		return nil
+	}
+	value := dir.Positional()[0]
+	kind := field.Type.Kind
+
+	var valid bool
+	switch {
+	case value.Kind == directive.ValueArray:
+		// Reported with the other @default arguments
+		return nil
+	case value.Kind == directive.ValueCall:
+		switch value.Call.Name {
+		case "now":
+			valid = kind == fld.KindDateTime || kind == fld.KindDate || kind == fld.KindTime || kind == fld.KindTimestamp
+		case "uuid":
+			valid = kind == fld.KindUUID || kind == fld.KindString || kind == fld.KindText || kind == fld.KindChar
+		default:
+			return nil
+		}
+		if !valid {
+			return fmt.Errorf("@default(%s()) does not match the field type %s", value.Call.Name, kind)
+		}
+		return nil
+	}
+
+	switch kind {
+	case fld.KindBoolean:
+		valid = value.Kind == directive.ValueBool
+	case fld.KindInt, fld.KindBigInt:
+		valid = value.Kind == directive.ValueInt
+	case fld.KindFloat, fld.KindDecimal:
+		valid = value.IsNumber()
+	case fld.KindString, fld.KindText, fld.KindChar, fld.KindUUID, fld.KindCUID,
+		fld.KindDateTime, fld.KindDate, fld.KindTime, fld.KindTimestamp, fld.KindJSON:
+		valid = value.IsText()
+	default:
+		valid = true
+	}
+	if !valid {
+		return fmt.Errorf("@default value %s does not match the field type %s", value.Source(), kind)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pixperk/storm/internal/transform/ir"
+)
+
+// validateGenerators checks the generator blocks of the schema
+func validateGenerators(generators []ir.IRGenerator) error {
+	errList := new(multierror.Error)
+	names := make(map[string]bool)
+
+	for _, gen := range generators {
+		if names[gen.Name] {
+			errList = multierror.Append(errList, at(gen.Pos, fmt.Errorf("duplicate generator name: %s", gen.Name)))
+		}
+		names[gen.Name] = true
+
+		if gen.Provider == "" {
+			errList = multierror.Append(errList, at(gen.Pos, fmt.Errorf("generator %s: provider is required", gen.Name)))
+		}
+
+		seen := make(map[string]bool)
+		for _, opt := range gen.Config {
+			if seen[opt.Name] {
+				errList = multierror.Append(errList, at(gen.Pos, fmt.Errorf("generator %s: option %s is set more than once", gen.Name, opt.Name)))
+			}
+			seen[opt.Name] = true
+		}
+	}
+
+	return errList.ErrorOrNil()
+}
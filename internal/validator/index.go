@@ -0,0 +1,86 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
+	fld "github.com/pixperk/storm/internal/types/field"
+)
+
+// validateIndexes checks that every @index option can be honoured by the target database
+func validateIndexes(irData *ir.IR, d dialect.Dialect) error {
+	errList := new(multierror.Error)
+
+	for _, model := range irData.Models {
+		for _, field := range model.Fields {
+			spec, ok := field.Index()
+			if !ok {
+				continue
+			}
+
+			for _, col := range spec.Include {
+				if _, ok := model.FindField(col); !ok {
+					errList = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+						"model %s: field %s: @index include references unknown field %s", model.Name, field.Name, col)))
+				}
+			}
+
+			for _, option := range unsupportedIndexOptions(spec, d) {
+				errList = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+					"model %s: field %s: @index option %s is not supported by %s", model.Name, field.Name, option, d)))
+			}
+
+			// MySQL indexes TEXT columns by a prefix only, and JSON ones not at all
+			if d == dialect.MySQL {
+				switch {
+				case field.IsArray || field.Type.Kind == fld.KindJSON:
+					errList = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+						"model %s: field %s: %s cannot index a JSON column", model.Name, field.Name, d)))
+				case field.Type.Kind == fld.KindText && spec.Length == 0:
+					errList = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+						"model %s: field %s: %s needs a prefix length to index a TEXT column; add length: to @index", model.Name, field.Name, d)))
+				}
+			}
+		}
+	}
+
+	return errList.ErrorOrNil()
+}
+
+// unsupportedIndexOptions returns the options of spec the dialect cannot express
+func unsupportedIndexOptions(spec *ir.IndexSpec, d dialect.Dialect) []string {
+	var options []string
+	method := strings.ToLower(spec.Method)
+
+	switch d {
+	case dialect.Postgres:
+		if spec.Length > 0 {
+			options = append(options, "length")
+		}
+	case dialect.MySQL:
+		if spec.Where != "" {
+			options = append(options, "where")
+		}
+		if len(spec.Include) > 0 {
+			options = append(options, "include")
+		}
+		if method != "" && method != "btree" && method != "hash" {
+			options = append(options, "type: "+spec.Method)
+		}
+	case dialect.SQLite:
+		if spec.Method != "" {
+			options = append(options, "type")
+		}
+		if len(spec.Include) > 0 {
+			options = append(options, "include")
+		}
+		if spec.Length > 0 {
+			options = append(options, "length")
+		}
+	}
+
+	return options
+}
@@ -22,12 +22,23 @@ func ValidateModel(model ir.IRModel, modelNames map[string]bool) error {
 		errList = multierror.Append(errList, errors.New("model must have at least one field"))
 	}
 
+	for _, dir := range model.Directives {
+		if dir.Kind != directive.DirRenamedFrom {
+			errList = multierror.Append(errList, fmt.Errorf("@%s directive cannot be used on a model", dir.Kind.SourceName()))
+			continue
+		}
+		if err := validateDirectiveArgs(dir); err != nil {
+			errList = multierror.Append(errList, err)
+		}
+	}
+
 	fieldNames := make(map[string]bool)
 	idCount := 0
+	deletedAtCount := 0
 
 	for _, field := range model.Fields {
 		if fieldNames[field.Name] {
-			errList = multierror.Append(errList, fmt.Errorf("duplicate field name: %s", field.Name))
+			errList = multierror.Append(errList, at(field.Pos, fmt.Errorf("duplicate field name: %s", field.Name)))
 		} else {
 			fieldNames[field.Name] = true
 		}
@@ -35,15 +46,21 @@ func ValidateModel(model ir.IRModel, modelNames map[string]bool) error {
 		if hasDirective(field, directive.DirID) {
 			idCount++
 		}
+		if hasDirective(field, directive.DirDeletedAt) {
+			deletedAtCount++
+		}
 
 		if err := ValidateField(field, model, modelNames); err != nil {
-			errList = multierror.Append(errList, fmt.Errorf("field %s: %w", field.Name, err))
+			errList = multierror.Append(errList, at(field.Pos, fmt.Errorf("field %s: %w", field.Name, err)))
 		}
 	}
 
 	if idCount > 1 {
 		errList = multierror.Append(errList, errors.New("model must have at most one @id directive field"))
 	}
+	if deletedAtCount > 1 {
+		errList = multierror.Append(errList, errors.New("model must have at most one @deletedAt directive field"))
+	}
 
 	return errList.ErrorOrNil()
 }
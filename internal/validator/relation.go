@@ -2,6 +2,10 @@ package validator
 
 import (
 	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/pixperk/storm/internal/transform/ir"
@@ -9,12 +13,11 @@ import (
 )
 
 func warn(message string, args ...interface{}) {
-	// This function can be used to log warnings during validation
-	// For now, we just print to stdout, but it can be replaced with a proper logging mechanism
-	fmt.Printf("Warning: "+message+"\n", args...)
+	// Warnings go to stderr, so they never mix with output written to stdout
+	fmt.Fprintf(os.Stderr, "Warning: "+message+"\n", args...)
 }
 
-func validateRelationalConsistency(models []ir.IRModel) error {
+func validateRelationalConsistency(models []ir.IRModel, warns *warnings) error {
 	errList := new(multierror.Error)
 	modelMap := make(map[string]ir.IRModel)
 
@@ -23,114 +26,319 @@ func validateRelationalConsistency(models []ir.IRModel) error {
 		modelMap[model.Name] = model
 	}
 
+	// Relations must pair unambiguously before their fields are checked
+	validateRelationNames(models, modelMap, errList)
+
 	// Validate each model's fields
 	for _, model := range models {
 		for _, field := range model.Fields {
-			validateFieldRelations(model, field, modelMap, errList)
+			validateFieldRelations(model, field, modelMap, errList, warns)
 		}
 	}
 
 	return errList.ErrorOrNil()
 }
 
-func validateFieldRelations(model ir.IRModel, field ir.IRField, modelMap map[string]ir.IRModel, errList *multierror.Error) {
+func validateFieldRelations(model ir.IRModel, field ir.IRField, modelMap map[string]ir.IRModel, errList *multierror.Error, warns *warnings) {
 	switch {
 	case hasDirective(field, directive.DirHasMany):
-		validateHasMany(model, field, modelMap, errList)
+		validateHasMany(model, field, modelMap, errList, warns)
 
 	case hasDirective(field, directive.DirBelongsTo):
-		validateBelongsTo(model, field, modelMap, errList)
+		validateBelongsTo(model, field, modelMap, errList, warns)
 
 	case hasDirective(field, directive.DirHasOne):
-		validateHasOne(model, field, modelMap, errList)
+		validateHasOne(model, field, modelMap, errList, warns)
 	}
 }
 
-func validateHasMany(model ir.IRModel, field ir.IRField, modelMap map[string]ir.IRModel, errList *multierror.Error) {
+func validateHasMany(model ir.IRModel, field ir.IRField, modelMap map[string]ir.IRModel, errList *multierror.Error, warns *warnings) {
 	if !field.IsArray {
 		// This is a real error, not just a warning
-		_ = multierror.Append(errList, fmt.Errorf(
-			"model %s: field %s with @hasMany must be an array", model.Name, field.Name))
+		_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+			"model %s: field %s with @hasMany must be an array", model.Name, field.Name)))
 		return
 	}
 
 	relatedModel, ok := modelMap[field.Type.String()]
 	if !ok {
 		// This is a real error, not just a warning
-		_ = multierror.Append(errList, fmt.Errorf(
-			"model %s: field %s references non-existent model %s", model.Name, field.Name, field.Type.String()))
+		_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+			"model %s: field %s references non-existent model %s", model.Name, field.Name, field.Type.String())))
 		return
 	}
-	if !hasBackReference(relatedModel, model.Name, directive.DirBelongsTo, false) &&
-		!hasBackReference(relatedModel, model.Name, directive.DirHasMany, true) {
+	if through := ir.RelationThrough(field); through != "" {
+		validateThrough(model, field, relatedModel, through, modelMap, errList)
+	}
+
+	paired, ok := ir.PairField(model, relatedModel, field)
+	if !ok {
 		// Issue a warning for unidirectional @hasMany relationships instead of an error
-		warn("model %s: field %s has @hasMany but no corresponding @belongsTo or @hasMany in model %s (unidirectional relation)",
+		warns.add(field.Pos, "model %s: field %s has @hasMany but no corresponding @belongsTo or @hasMany in model %s (unidirectional relation)",
 			model.Name, field.Name, relatedModel.Name)
+		return
+	}
+	if !(hasDirective(paired, directive.DirBelongsTo) && !paired.IsArray) &&
+		!(hasDirective(paired, directive.DirHasMany) && paired.IsArray) {
+		_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+			"model %s: field %s with @hasMany pairs with %s.%s, which must be @belongsTo or @hasMany",
+			model.Name, field.Name, relatedModel.Name, paired.Name)))
 	}
 }
 
-func validateBelongsTo(model ir.IRModel, field ir.IRField, modelMap map[string]ir.IRModel, errList *multierror.Error) {
+func validateBelongsTo(model ir.IRModel, field ir.IRField, modelMap map[string]ir.IRModel, errList *multierror.Error, warns *warnings) {
 	if field.IsArray {
-		_ = multierror.Append(errList, fmt.Errorf(
-			"model %s: field %s with @belongsTo cannot be an array", model.Name, field.Name))
+		_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+			"model %s: field %s with @belongsTo cannot be an array", model.Name, field.Name)))
 		return
 	}
 
 	relatedModel, ok := modelMap[field.Type.String()]
 	if !ok {
-		_ = multierror.Append(errList, fmt.Errorf(
-			"model %s: field %s references non-existent model %s", model.Name, field.Name, field.Type.String()))
+		_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+			"model %s: field %s references non-existent model %s", model.Name, field.Name, field.Type.String())))
+		return
+	}
+
+	if ref := ir.ForeignKeyReference(field); ref != "" {
+		if _, ok := relatedModel.FindField(ref); !ok {
+			_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+				"model %s: field %s: @relation references unknown field %s of model %s", model.Name, field.Name, ref, relatedModel.Name)))
+		}
+	}
+
+	paired, ok := ir.PairField(model, relatedModel, field)
+	if !ok {
+		if !isJoinModelOf(model, relatedModel) {
+			// Issue a warning for unidirectional @belongsTo relationships instead of an error
+			warns.add(field.Pos, "model %s: field %s has @belongsTo but no corresponding @hasMany or @hasOne in model %s (unidirectional relation)",
+				model.Name, field.Name, relatedModel.Name)
+		}
 		return
 	}
 
 	// Check for circular @belongsTo relations (belongsTo in both directions)
-	if hasBelongsToBackReference(relatedModel, model.Name) {
-		_ = multierror.Append(errList, fmt.Errorf(
+	if hasDirective(paired, directive.DirBelongsTo) {
+		_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
 			"circular @belongsTo relation detected: both model %s and model %s have @belongsTo pointing to each other",
-			model.Name, relatedModel.Name))
+			model.Name, relatedModel.Name)))
 		return
 	}
 
-	if !hasBackReference(relatedModel, model.Name, directive.DirHasMany, true) &&
-		!hasBackReference(relatedModel, model.Name, directive.DirHasOne, false) {
-		// Issue a warning for unidirectional @belongsTo relationships instead of an error
-		warn("model %s: field %s has @belongsTo but no corresponding @hasMany or @hasOne in model %s (unidirectional relation)",
-			model.Name, field.Name, relatedModel.Name)
+	if !(hasDirective(paired, directive.DirHasMany) && paired.IsArray) &&
+		!(hasDirective(paired, directive.DirHasOne) && !paired.IsArray) {
+		_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+			"model %s: field %s with @belongsTo pairs with %s.%s, which must be @hasMany or @hasOne",
+			model.Name, field.Name, relatedModel.Name, paired.Name)))
 	}
 }
 
-func validateHasOne(model ir.IRModel, field ir.IRField, modelMap map[string]ir.IRModel, errList *multierror.Error) {
+func validateHasOne(model ir.IRModel, field ir.IRField, modelMap map[string]ir.IRModel, errList *multierror.Error, warns *warnings) {
 	if field.IsArray {
-		_ = multierror.Append(errList, fmt.Errorf(
-			"model %s: field %s with @hasOne cannot be an array", model.Name, field.Name))
+		_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+			"model %s: field %s with @hasOne cannot be an array", model.Name, field.Name)))
 		return
 	}
 
 	relatedModel, ok := modelMap[field.Type.String()]
 	if !ok {
-		_ = multierror.Append(errList, fmt.Errorf(
-			"model %s: field %s references non-existent model %s", model.Name, field.Name, field.Type.String()))
+		_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+			"model %s: field %s references non-existent model %s", model.Name, field.Name, field.Type.String())))
 		return
 	}
-	if !hasBackReference(relatedModel, model.Name, directive.DirBelongsTo, false) {
+
+	paired, ok := ir.PairField(model, relatedModel, field)
+	if !ok {
 		// Issue a warning for unidirectional @hasOne relationships instead of an error
-		warn("model %s: field %s has @hasOne but no corresponding @belongsTo in model %s (unidirectional relation)",
+		warns.add(field.Pos, "model %s: field %s has @hasOne but no corresponding @belongsTo in model %s (unidirectional relation)",
 			model.Name, field.Name, relatedModel.Name)
+		return
+	}
+	if !hasDirective(paired, directive.DirBelongsTo) || paired.IsArray {
+		_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+			"model %s: field %s with @hasOne pairs with %s.%s, which must be @belongsTo",
+			model.Name, field.Name, relatedModel.Name, paired.Name)))
 	}
 }
 
-func hasBackReference(model ir.IRModel, targetModelName string, directiveType directive.DirectiveKind, shouldBeArray bool) bool {
-	for _, field := range model.Fields {
-		if hasDirective(field, directiveType) &&
-			field.IsArray == shouldBeArray &&
-			field.Type.String() == targetModelName {
+// validateThrough checks the explicit join model of a many-to-many relation
+func validateThrough(model ir.IRModel, field ir.IRField, relatedModel ir.IRModel, through string, modelMap map[string]ir.IRModel, errList *multierror.Error) {
+	joinModel, ok := modelMap[through]
+	if !ok {
+		_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+			"model %s: field %s: @relation through references non-existent model %s", model.Name, field.Name, through)))
+		return
+	}
+
+	if paired, ok := ir.PairField(model, relatedModel, field); !ok || !hasDirective(paired, directive.DirHasMany) {
+		_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+			"model %s: field %s: @relation through requires a @hasMany field on both sides of the relation", model.Name, field.Name)))
+	}
+
+	// The join model needs a @belongsTo to each side; a self-relation needs two
+	needed := map[string]int{model.Name: 1, relatedModel.Name: 1}
+	if model.Name == relatedModel.Name {
+		needed[model.Name] = 2
+	}
+	for target, count := range needed {
+		found := 0
+		for _, f := range joinModel.Fields {
+			if hasDirective(f, directive.DirBelongsTo) && f.Type.String() == target {
+				found++
+			}
+		}
+		if found < count {
+			_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+				"model %s: field %s: join model %s must have %d @belongsTo field(s) referencing %s",
+				model.Name, field.Name, through, count, target)))
+		}
+	}
+}
+
+// isJoinModelOf reports whether joinModel is named by a @relation(through:) field of model
+func isJoinModelOf(joinModel ir.IRModel, model ir.IRModel) bool {
+	for _, f := range model.Fields {
+		if ir.RelationThrough(f) == joinModel.Name {
 			return true
 		}
 	}
 	return false
 }
 
-// hasBelongsToBackReference checks if a model has a field with @belongsTo directive pointing to the target model
-func hasBelongsToBackReference(model ir.IRModel, targetModelName string) bool {
-	return hasBackReference(model, targetModelName, directive.DirBelongsTo, false)
+// validateRelationNames checks every pair of related models once for relations
+// that cannot be paired by @relation name
+func validateRelationNames(models []ir.IRModel, modelMap map[string]ir.IRModel, errList *multierror.Error) {
+	checked := make(map[[2]string]bool)
+
+	for _, model := range models {
+		for _, field := range model.Fields {
+			relatedModel, ok := modelMap[field.Type.String()]
+			if !ok || !ir.IsRelationField(field) {
+				continue
+			}
+
+			key := [2]string{model.Name, relatedModel.Name}
+			if key[0] > key[1] {
+				key[0], key[1] = key[1], key[0]
+			}
+			if checked[key] {
+				continue
+			}
+			checked[key] = true
+
+			validateRelationPair(model, relatedModel, errList)
+		}
+	}
+}
+
+// validateRelationPair reports ambiguous, duplicated and mismatched relation names between two models
+func validateRelationPair(model ir.IRModel, relatedModel ir.IRModel, errList *multierror.Error) {
+	forward := relationFieldsTo(model, relatedModel.Name)
+	backward := relationFieldsTo(relatedModel, model.Name)
+
+	if model.Name == relatedModel.Name {
+		// A self-relation keeps both of its sides on the same model
+		if unnamed := unnamedFields(forward); len(unnamed) > 2 {
+			_ = multierror.Append(errList, at(model.Pos, fmt.Errorf(
+				"model %s has more than one self-relation without a name (fields %s); name each with @relation(\"name\")",
+				model.Name, strings.Join(unnamed, ", "))))
+		}
+		byName := namedFields(forward)
+		for _, name := range slices.Sorted(maps.Keys(byName)) {
+			if fields := byName[name]; len(fields) > 2 {
+				_ = multierror.Append(errList, at(model.Pos, fmt.Errorf(
+					"model %s: @relation name %q is used by more than two fields (%s)", model.Name, name, strings.Join(fields, ", "))))
+			}
+		}
+		return
+	}
+
+	unnamedForward, unnamedBackward := unnamedFields(forward), unnamedFields(backward)
+	if len(unnamedForward) > 1 || len(unnamedBackward) > 1 {
+		fields := append(qualify(model.Name, unnamedForward), qualify(relatedModel.Name, unnamedBackward)...)
+		_ = multierror.Append(errList, at(model.Pos, fmt.Errorf(
+			"models %s and %s have more than one relation without a name (fields %s); name each with @relation(\"name\")",
+			model.Name, relatedModel.Name, strings.Join(fields, ", "))))
+	}
+
+	for _, side := range []struct {
+		model  ir.IRModel
+		fields []ir.IRField
+	}{{model, forward}, {relatedModel, backward}} {
+		byName := namedFields(side.fields)
+		for _, name := range slices.Sorted(maps.Keys(byName)) {
+			if fields := byName[name]; len(fields) > 1 {
+				_ = multierror.Append(errList, at(side.model.Pos, fmt.Errorf(
+					"model %s: @relation name %q is used by more than one field (%s)", side.model.Name, name, strings.Join(fields, ", "))))
+			}
+		}
+	}
+
+	// A named field without a counterpart, facing fields that are not paired either,
+	// is most likely a misspelt or missing name rather than a unidirectional relation
+	reportUnpairedNames(model, relatedModel, forward, backward, errList)
+	reportUnpairedNames(relatedModel, model, backward, forward, errList)
+}
+
+// reportUnpairedNames reports named fields of model with no counterpart while relatedModel has unpaired fields
+func reportUnpairedNames(model, relatedModel ir.IRModel, fields, relatedFields []ir.IRField, errList *multierror.Error) {
+	for _, field := range fields {
+		name := ir.RelationName(field)
+		if name == "" {
+			continue
+		}
+		if _, ok := ir.PairField(model, relatedModel, field); ok {
+			continue
+		}
+		var unpaired []string
+		for _, candidate := range relatedFields {
+			if _, ok := ir.PairField(relatedModel, model, candidate); !ok {
+				unpaired = append(unpaired, candidate.Name)
+			}
+		}
+		if len(unpaired) > 0 {
+			_ = multierror.Append(errList, at(field.Pos, fmt.Errorf(
+				"model %s: field %s: @relation(%q) has no counterpart in model %s (unpaired fields %s)",
+				model.Name, field.Name, name, relatedModel.Name, strings.Join(qualify(relatedModel.Name, unpaired), ", "))))
+		}
+	}
+}
+
+// relationFieldsTo returns the relation fields of model pointing at the target model
+func relationFieldsTo(model ir.IRModel, target string) []ir.IRField {
+	var fields []ir.IRField
+	for _, field := range model.Fields {
+		if ir.IsRelationField(field) && field.Type.String() == target {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func unnamedFields(fields []ir.IRField) []string {
+	var names []string
+	for _, field := range fields {
+		if ir.RelationName(field) == "" {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+func namedFields(fields []ir.IRField) map[string][]string {
+	byName := make(map[string][]string)
+	for _, field := range fields {
+		if name := ir.RelationName(field); name != "" {
+			byName[name] = append(byName[name], field.Name)
+		}
+	}
+	return byName
+}
+
+func qualify(model string, fields []string) []string {
+	qualified := make([]string, 0, len(fields))
+	for _, field := range fields {
+		qualified = append(qualified, model+"."+field)
+	}
+	return qualified
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
 	"github.com/pixperk/storm/internal/types/directive"
 )
 
@@ -27,20 +28,35 @@ func hasDirective(field ir.IRField, targetKind directive.DirectiveKind) bool {
 	return false
 }
 
-// ValidateIR validates an entire IR file
+// ValidateIR validates an entire IR file, printing warnings to stderr
 func ValidateIR(irData *ir.IR) error {
+	warns, err := Validate(irData)
+	for _, w := range warns {
+		warn("%s", w)
+	}
+	return err
+}
+
+// Validate validates an entire IR file and returns its warnings alongside the errors
+func Validate(irData *ir.IR) ([]Warning, error) {
 	errList := new(multierror.Error)
+	var warns warnings
 
 	// Validate database configuration
 	if err := validateDatabaseConfig(irData); err != nil {
 		errList = multierror.Append(errList, err)
 	}
 
+	// Validate generator blocks
+	if err := validateGenerators(irData.Generators); err != nil {
+		errList = multierror.Append(errList, err)
+	}
+
 	// Validate models
 	modelNames := make(map[string]bool)
 	for _, model := range irData.Models {
 		if _, exists := modelNames[model.Name]; exists {
-			errList = multierror.Append(errList, fmt.Errorf("duplicate model name: %s", model.Name))
+			errList = multierror.Append(errList, at(model.Pos, fmt.Errorf("duplicate model name: %s", model.Name)))
 		} else {
 			modelNames[model.Name] = true
 		}
@@ -48,14 +64,19 @@ func ValidateIR(irData *ir.IR) error {
 
 	for _, model := range irData.Models {
 		if err := ValidateModel(model, modelNames); err != nil {
-			errList = multierror.Append(errList, fmt.Errorf("model %s: %w", model.Name, err))
+			errList = multierror.Append(errList, at(model.Pos, fmt.Errorf("model %s: %w", model.Name, err)))
 		}
 	}
 
+	// Validate index options and default functions against the target database
+	if err := ValidateDialect(irData, dialect.Parse(irData.DatabaseDriver)); err != nil {
+		errList = multierror.Append(errList, err)
+	}
+
 	// Validate relational consistency
-	if err := validateRelationalConsistency(irData.Models); err != nil {
+	if err := validateRelationalConsistency(irData.Models, &warns); err != nil {
 		errList = multierror.Append(errList, err)
 	}
 
-	return errList.ErrorOrNil()
+	return warns, errList.ErrorOrNil()
 }
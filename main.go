@@ -1,15 +1,86 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/pixperk/storm/internal/catalog"
+	"github.com/pixperk/storm/internal/database"
+	"github.com/pixperk/storm/internal/dbml"
+	"github.com/pixperk/storm/internal/fake"
+	"github.com/pixperk/storm/internal/generator/ddl"
+	"github.com/pixperk/storm/internal/generator/docs"
+	"github.com/pixperk/storm/internal/generator/erd"
+	"github.com/pixperk/storm/internal/generator/external"
+	"github.com/pixperk/storm/internal/generator/golang"
+	"github.com/pixperk/storm/internal/generator/graphql"
+	"github.com/pixperk/storm/internal/generator/jsonschema"
+	"github.com/pixperk/storm/internal/generator/protobuf"
+	"github.com/pixperk/storm/internal/generator/typescript"
+	"github.com/pixperk/storm/internal/migrate"
 	"github.com/pixperk/storm/internal/parser"
+	"github.com/pixperk/storm/internal/seed"
 	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/dialect"
 	"github.com/pixperk/storm/internal/validator"
 )
 
+const defaultSchemaPath = "examples/schema.storm"
+
 func main() {
-	ast, err := parser.ParseDSL("examples/schema.storm")
+	args := os.Args[1:]
+	if len(args) == 0 {
+		args = []string{"print"}
+	}
+
+	switch args[0] {
+	case "print":
+		runPrint(args[1:])
+	case "generate":
+		runGenerate(args[1:])
+	case "ddl":
+		runDDL(args[1:])
+	case "erd":
+		runERD(args[1:])
+	case "dbml":
+		runDBML(args[1:])
+	case "docs":
+		runDocs(args[1:])
+	case "jsonschema":
+		runJSONSchema(args[1:])
+	case "graphql":
+		runGraphQL(args[1:])
+	case "proto":
+		runProto(args[1:])
+	case "ts":
+		runTypeScript(args[1:])
+	case "seed":
+		runSeed(args[1:])
+	case "fake":
+		runFake(args[1:])
+	case "drift":
+		runDrift(args[1:])
+	case "migrate":
+		runMigrate(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", args[0])
+		fmt.Fprintln(os.Stderr, "usage: storm <print|generate|ddl|erd|dbml|docs|jsonschema|graphql|proto|ts|seed|fake|drift|migrate> [flags] [schema.storm]")
+		os.Exit(2)
+	}
+}
+
+// loadSchema parses, transforms and validates the schema at path
+func loadSchema(path string) *ir.IR {
+	ast, err := parser.ParseDSL(path)
 	if err != nil {
 		log.Fatalf("Failed to parse DSL: %v", err)
 	}
@@ -22,5 +93,730 @@ func main() {
 	if err != nil {
 		log.Fatalf("Validation error: %v", err)
 	}
-	ir.PrintIR(irVar)
+	return irVar
+}
+
+// schemaPath returns the schema file named on the command line, or the default one
+func schemaPath(fs *flag.FlagSet) string {
+	if fs.NArg() > 0 {
+		return fs.Arg(0)
+	}
+	return defaultSchemaPath
+}
+
+func runPrint(args []string) {
+	fs := flag.NewFlagSet("print", flag.ExitOnError)
+	format := fs.String("format", "text", "output format: text or json")
+	_ = fs.Parse(args)
+
+	irVar := loadSchema(schemaPath(fs))
+
+	switch *format {
+	case "text":
+		ir.PrintIR(irVar)
+	case "json":
+		if err := ir.EncodeJSON(os.Stdout, irVar); err != nil {
+			log.Fatalf("Failed to encode IR: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown format: %s", *format)
+	}
+}
+
+func runGenerate(args []string) {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	out := fs.String("out", "models", "output directory for the generated Go files")
+	pkg := fs.String("package", "", "package name of the generated files (defaults to the output directory name)")
+	_ = fs.Parse(args)
+
+	irVar := loadSchema(schemaPath(fs))
+
+	if *pkg == "" {
+		*pkg = filepath.Base(*out)
+	}
+	files, err := golang.Generate(irVar, golang.Options{Package: *pkg, Dialect: dialect.Parse(irVar.DatabaseDriver)})
+	if err != nil {
+		log.Fatalf("Failed to generate Go code: %v", err)
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatalf("Failed to create output directory: %v", err)
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(*out, f.Name), f.Content, 0o644); err != nil {
+			log.Fatalf("Failed to write %s: %v", f.Name, err)
+		}
+	}
+
+	// Run the third-party generators declared in the schema
+	for _, gen := range irVar.Generators {
+		files, err := external.Run(context.Background(), gen, irVar)
+		if err != nil {
+			log.Fatalf("Failed to run generator: %v", err)
+		}
+
+		dir := gen.Output
+		if dir == "" {
+			dir = gen.Name
+		}
+		if err := external.Write(dir, files); err != nil {
+			log.Fatalf("Failed to write output of generator %s: %v", gen.Name, err)
+		}
+	}
+}
+
+func runDDL(args []string) {
+	fs := flag.NewFlagSet("ddl", flag.ExitOnError)
+	dialectName := fs.String("dialect", "", "target SQL dialect (defaults to the schema's database driver)")
+	triggers := fs.Bool("updated-at-trigger", false, "also emit PostgreSQL triggers keeping @updatedAt columns current on raw SQL updates")
+	_ = fs.Parse(args)
+
+	irVar := loadSchema(schemaPath(fs))
+
+	driver := irVar.DatabaseDriver
+	if *dialectName != "" {
+		driver = *dialectName
+	}
+	d := dialect.Parse(driver)
+	if d == dialect.Unknown {
+		log.Fatalf("Unsupported dialect: %s", driver)
+	}
+	// loadSchema validated the schema for its own driver only
+	if err := validator.ValidateDialect(irVar, d); err != nil {
+		log.Fatalf("Validation error: %v", err)
+	}
+	stmts := ddl.Generate(irVar, d)
+	if *triggers {
+		stmts = append(stmts, ddl.UpdatedAtTriggers(irVar, d)...)
+	}
+	fmt.Print(ddl.Render(stmts))
+}
+
+func runERD(args []string) {
+	fs := flag.NewFlagSet("erd", flag.ExitOnError)
+	format := fs.String("format", "mermaid", "output format: mermaid, dot, or svg-via-dot-text (the dot text rendered by Graphviz)")
+	models := fs.String("models", "", "comma-separated models to limit the diagram to (defaults to every model)")
+	depth := fs.Int("depth", 0, "relation hops to follow from -models")
+	_ = fs.Parse(args)
+
+	irVar := loadSchema(schemaPath(fs))
+
+	var opts erd.Options
+	if *models != "" {
+		for _, name := range strings.Split(*models, ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := irVar.FindModel(name); !ok {
+				log.Fatalf("Unknown model: %s", name)
+			}
+			opts.Models = append(opts.Models, name)
+		}
+		opts.Depth = *depth
+	}
+
+	switch *format {
+	case "mermaid":
+		fmt.Print(erd.Mermaid(irVar, opts))
+	case "dot":
+		fmt.Print(erd.Dot(irVar, opts))
+	case "svg-via-dot-text":
+		svg, err := erd.SVG(context.Background(), erd.Dot(irVar, opts))
+		if err != nil {
+			log.Fatalf("Failed to render SVG: %v", err)
+		}
+		os.Stdout.Write(svg)
+	default:
+		log.Fatalf("Unknown format: %s", *format)
+	}
+}
+
+func runDBML(args []string) {
+	if len(args) == 0 || (args[0] != "export" && args[0] != "import") {
+		fmt.Fprintln(os.Stderr, "usage: storm dbml export [schema.storm] | storm dbml import [-out schema.storm] [-url database-url] file.dbml")
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("dbml "+args[0], flag.ExitOnError)
+	out := fs.String("out", "", "file receiving the output (defaults to stdout)")
+	url := fs.String("url", "", "database url written by import (defaults to a placeholder to replace)")
+	_ = fs.Parse(args[1:])
+
+	var output string
+	if args[0] == "export" {
+		output = dbml.Export(loadSchema(schemaPath(fs)))
+	} else {
+		if fs.NArg() == 0 {
+			log.Fatal("dbml import needs a DBML file")
+		}
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			log.Fatalf("Failed to open DBML: %v", err)
+		}
+		defer f.Close()
+
+		file, err := dbml.Parse(fs.Arg(0), f)
+		if err != nil {
+			log.Fatalf("Failed to parse DBML: %v", err)
+		}
+		irVar, warnings, err := dbml.Import(file, *url)
+		if err != nil {
+			log.Fatalf("Failed to import DBML: %v", err)
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+		output = ir.Format(irVar)
+	}
+
+	if *out == "" {
+		fmt.Print(output)
+		return
+	}
+	if err := os.WriteFile(*out, []byte(output), 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+}
+
+func runDocs(args []string) {
+	fs := flag.NewFlagSet("docs", flag.ExitOnError)
+	format := fs.String("format", "markdown", "output format: markdown or html")
+	out := fs.String("out", "", "file receiving the documentation (defaults to stdout)")
+	_ = fs.Parse(args)
+
+	irVar := loadSchema(schemaPath(fs))
+
+	var output string
+	switch *format {
+	case "markdown", "md":
+		output = docs.Markdown(irVar)
+	case "html":
+		var err error
+		if output, err = docs.HTML(irVar); err != nil {
+			log.Fatalf("Failed to render documentation: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown format: %s", *format)
+	}
+
+	if *out == "" {
+		fmt.Print(output)
+		return
+	}
+	if err := os.WriteFile(*out, []byte(output), 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+}
+
+func runJSONSchema(args []string) {
+	fs := flag.NewFlagSet("jsonschema", flag.ExitOnError)
+	format := fs.String("format", "jsonschema", "output format: jsonschema (draft 2020-12) or openapi (3.1 components)")
+	excludeRelations := fs.Bool("exclude-relations", false, "leave relation fields out of the schemas")
+	readOnly := fs.Bool("readonly", false, "mark fields set by the database (@id @auto, @createdAt, @updatedAt) readOnly")
+	title := fs.String("title", "", "document title")
+	version := fs.String("version", "", "OpenAPI info.version (defaults to 1.0.0)")
+	_ = fs.Parse(args)
+
+	irVar := loadSchema(schemaPath(fs))
+	opts := jsonschema.Options{ExcludeRelations: *excludeRelations, ReadOnly: *readOnly, Title: *title, Version: *version}
+
+	var output []byte
+	var err error
+	switch *format {
+	case "jsonschema":
+		output, err = jsonschema.JSONSchema(irVar, opts)
+	case "openapi":
+		output, err = jsonschema.OpenAPI(irVar, opts)
+	default:
+		log.Fatalf("Unknown format: %s", *format)
+	}
+	if err != nil {
+		log.Fatalf("Failed to generate schemas: %v", err)
+	}
+	os.Stdout.Write(output)
+}
+
+func runGraphQL(args []string) {
+	fs := flag.NewFlagSet("graphql", flag.ExitOnError)
+	out := fs.String("out", "", "file receiving the SDL (defaults to stdout)")
+	_ = fs.Parse(args)
+
+	output := graphql.Generate(loadSchema(schemaPath(fs)))
+	if *out == "" {
+		fmt.Print(output)
+		return
+	}
+	if err := os.WriteFile(*out, []byte(output), 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+}
+
+func runProto(args []string) {
+	fs := flag.NewFlagSet("proto", flag.ExitOnError)
+	out := fs.String("out", "", "file receiving the .proto (defaults to stdout)")
+	lockPath := fs.String("lock", "storm.proto.lock", "lock file recording field numbers")
+	pkg := fs.String("package", "storm", "protobuf package")
+	goPackage := fs.String("go-package", "", "option go_package of the .proto file")
+	_ = fs.Parse(args)
+
+	irVar := loadSchema(schemaPath(fs))
+
+	lock, err := protobuf.LoadLock(*lockPath)
+	if err != nil {
+		log.Fatalf("Failed to read lock file: %v", err)
+	}
+	output := protobuf.Generate(irVar, lock, protobuf.Options{Package: *pkg, GoPackage: *goPackage})
+
+	if *out == "" {
+		fmt.Print(output)
+	} else if err := os.WriteFile(*out, []byte(output), 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+	if err := lock.Save(*lockPath); err != nil {
+		log.Fatalf("Failed to write lock file: %v", err)
+	}
+}
+
+func runTypeScript(args []string) {
+	fs := flag.NewFlagSet("ts", flag.ExitOnError)
+	out := fs.String("out", "", "file receiving the TypeScript module (defaults to stdout)")
+	zod := fs.Bool("zod", true, "emit Zod schemas next to the interfaces")
+	_ = fs.Parse(args)
+
+	output := typescript.Generate(loadSchema(schemaPath(fs)), typescript.Options{Zod: *zod})
+	if *out == "" {
+		fmt.Print(output)
+		return
+	}
+	if err := os.WriteFile(*out, []byte(output), 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *out, err)
+	}
+}
+
+func runSeed(args []string) {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	file := fs.String("file", "seed.json", "seed file with the rows to insert")
+	url := fs.String("url", "", "database URL (defaults to the schema's database url)")
+	dryRun := fs.Bool("dry-run", false, "validate the seed file and print the insert order without connecting")
+	_ = fs.Parse(args)
+
+	irVar := loadSchema(schemaPath(fs))
+
+	rows, err := seed.Load(*file)
+	if err != nil {
+		log.Fatalf("Failed to read seed file: %v", err)
+	}
+	if err := seed.Validate(irVar, rows); err != nil {
+		log.Fatalf("Invalid seed file: %v", err)
+	}
+	order, err := seed.Order(irVar)
+	if err != nil {
+		log.Fatalf("Failed to order models: %v", err)
+	}
+
+	if *dryRun {
+		for _, model := range order {
+			if n := len(rows[model]); n > 0 {
+				fmt.Printf("%s: %d rows\n", model, n)
+			}
+		}
+		return
+	}
+
+	if *url == "" {
+		*url = irVar.DatabaseURL
+	}
+	d := dialect.Parse(irVar.DatabaseDriver)
+	db, err := database.Open(d, *url)
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	if err := seed.Insert(context.Background(), db, d, irVar, rows); err != nil {
+		log.Fatalf("Failed to seed database: %v", err)
+	}
+	fmt.Println("Database seeded")
+}
+
+func runFake(args []string) {
+	fs := flag.NewFlagSet("fake", flag.ExitOnError)
+	rowsFlag := fs.String("rows", "", "rows to generate per model, as User=10000,Post=50000")
+	seedFlag := fs.Int64("seed", 1, "seed of the random source; the same seed yields the same rows")
+	nullRate := fs.Float64("null-rate", 0.1, "share of nullable values left null")
+	format := fs.String("format", "sql", "output: sql (INSERT statements), csv (one file per model) or db (insert directly)")
+	out := fs.String("out", "", "file receiving the SQL (defaults to stdout), or directory receiving the CSV files")
+	dialectName := fs.String("dialect", "", "target SQL dialect (defaults to the schema's database driver)")
+	url := fs.String("url", "", "database URL for -format db (defaults to the schema's database url)")
+	_ = fs.Parse(args)
+
+	irVar := loadSchema(schemaPath(fs))
+
+	rows, err := fake.ParseRows(*rowsFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(rows) == 0 {
+		log.Fatal("No rows requested; use -rows Model=N,...")
+	}
+
+	driver := irVar.DatabaseDriver
+	if *dialectName != "" {
+		driver = *dialectName
+	}
+	d := dialect.Parse(driver)
+	if d == dialect.Unknown {
+		log.Fatalf("Unsupported dialect: %s", driver)
+	}
+
+	opts := fake.Options{Rows: rows, Seed: *seedFlag, NullRate: *nullRate}
+	var db *sql.DB
+	if *format == "db" {
+		if *url == "" {
+			*url = irVar.DatabaseURL
+		}
+		db, err = database.Open(d, *url)
+		if err != nil {
+			log.Fatalf("Failed to connect: %v", err)
+		}
+		defer db.Close()
+		// Keys and unique values follow the rows already there, so the inserts do not collide with them
+		if opts.KeysAfter, err = fake.KeysAfter(context.Background(), db, d, irVar); err != nil {
+			log.Fatalf("Failed to read existing keys: %v", err)
+		}
+		if opts.RowsBefore, err = fake.RowsBefore(context.Background(), db, d, irVar); err != nil {
+			log.Fatalf("Failed to count existing rows: %v", err)
+		}
+	}
+	tables, err := fake.Generate(irVar, opts)
+	if err != nil {
+		log.Fatalf("Failed to generate data: %v", err)
+	}
+
+	switch *format {
+	case "sql":
+		w := os.Stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				log.Fatalf("Failed to create %s: %v", *out, err)
+			}
+			defer f.Close()
+			w = f
+		}
+		if err := fake.WriteSQL(w, d, tables); err != nil {
+			log.Fatalf("Failed to write SQL: %v", err)
+		}
+	case "csv":
+		dir := *out
+		if dir == "" {
+			dir = "fake"
+		}
+		if err := fake.WriteCSV(dir, d, tables); err != nil {
+			log.Fatalf("Failed to write CSV: %v", err)
+		}
+	case "db":
+		if err := fake.Insert(context.Background(), db, d, tables); err != nil {
+			log.Fatalf("Failed to insert data: %v", err)
+		}
+		for _, t := range tables {
+			fmt.Printf("%s: %d rows\n", t.Model, len(t.Rows))
+		}
+	default:
+		log.Fatalf("Unknown format: %s", *format)
+	}
+}
+
+func runDrift(args []string) {
+	fs := flag.NewFlagSet("drift", flag.ExitOnError)
+	url := fs.String("url", "", "database URL (defaults to the schema's database url)")
+	_ = fs.Parse(args)
+
+	path := schemaPath(fs)
+	irVar := loadSchema(path)
+
+	if *url == "" {
+		*url = irVar.DatabaseURL
+	}
+	d := dialect.Parse(irVar.DatabaseDriver)
+	db, err := database.Open(d, *url)
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	actual, err := catalog.Inspect(context.Background(), db, d)
+	if err != nil {
+		log.Fatalf("Failed to inspect database: %v", err)
+	}
+	actual.Tables = slices.DeleteFunc(actual.Tables, func(t *catalog.Table) bool { return t.Name == migrate.TableName })
+
+	changes := catalog.Diff(actual, catalog.FromIR(irVar, d))
+	if len(changes) == 0 {
+		fmt.Printf("No drift: the database matches %s\n", path)
+		return
+	}
+
+	fmt.Printf("The database has drifted from %s; to match the schema it needs to:\n", path)
+	for _, c := range changes {
+		fmt.Printf("  %s\n", c)
+	}
+	db.Close()
+	os.Exit(1)
+}
+
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: storm migrate <create|up|down|status|squash|verify> [flags] [schema.storm]")
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "create":
+		runMigrateCreate(args[1:])
+	case "up":
+		runMigrateUp(args[1:])
+	case "down":
+		runMigrateDown(args[1:])
+	case "status":
+		runMigrateStatus(args[1:])
+	case "squash":
+		runMigrateSquash(args[1:])
+	case "verify":
+		runMigrateVerify(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command: %s\n", args[0])
+		fmt.Fprintln(os.Stderr, "usage: storm migrate <create|up|down|status|squash|verify> [flags] [schema.storm]")
+		os.Exit(2)
+	}
+}
+
+func runMigrateCreate(args []string) {
+	fs := flag.NewFlagSet("migrate create", flag.ExitOnError)
+	dir := fs.String("dir", "migrations", "migrations directory")
+	name := fs.String("name", "migration", "name of the migration")
+	online := fs.Bool("online", false, "split the migration into expand, backfill and contract phases that avoid long locks (PostgreSQL and MySQL)")
+	_ = fs.Parse(args)
+
+	irVar := loadSchema(schemaPath(fs))
+	d := dialect.Parse(irVar.DatabaseDriver)
+
+	migrations, err := migrate.Load(*dir)
+	if err != nil {
+		log.Fatalf("Failed to read migrations: %v", err)
+	}
+	var created []*migrate.Migration
+	var hazards []migrate.Hazard
+	if *online {
+		created, hazards, err = migrate.CreateOnline(*dir, *name, migrations, irVar, d, time.Now())
+	} else {
+		var m *migrate.Migration
+		m, hazards, err = migrate.Create(*dir, *name, migrations, irVar, d, time.Now())
+		if m != nil {
+			created = append(created, m)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Failed to create migration: %v", err)
+	}
+	if len(created) == 0 {
+		fmt.Println("No schema changes")
+		return
+	}
+
+	for _, m := range created {
+		fmt.Printf("Created %s\n", m.Dir)
+	}
+	if suggestions := created[0].Suggestions; len(suggestions) > 0 {
+		fmt.Println("Possible renames:")
+		for _, s := range suggestions {
+			fmt.Printf("  - %s\n", s)
+		}
+	}
+	if len(hazards) > 0 {
+		fmt.Println("This migration can lose data or fail on existing rows:")
+		for _, h := range hazards {
+			fmt.Printf("  - %s\n", h)
+		}
+		if len(created) > 1 {
+			fmt.Println("Review the up.sql of the phases listing them; storm migrate up refuses each phase unless the data is unaffected or the loss is accepted.")
+		} else {
+			fmt.Printf("Review %s; storm migrate up refuses it unless the data is unaffected or the loss is accepted.\n",
+				filepath.Join(created[0].Dir, migrate.UpFile))
+		}
+	}
+	if len(created) > 1 {
+		fmt.Println("Apply the phases one at a time, deploying code that works with both schemas before the contract phase.")
+	}
+}
+
+func runMigrateUp(args []string) {
+	fs := flag.NewFlagSet("migrate up", flag.ExitOnError)
+	dir := fs.String("dir", "migrations", "migrations directory")
+	url := fs.String("url", "", "database URL (defaults to the schema's database url)")
+	acceptDataLoss := fs.Bool("accept-data-loss", false, "apply migrations that drop or rewrite existing data")
+	limit := fs.Int("n", 0, "apply at most n pending migrations, such as one phase of an online migration (0 applies all)")
+	_ = fs.Parse(args)
+
+	migrations, exec, d, db := openMigrations(*dir, *url, schemaPath(fs))
+	defer db.Close()
+
+	ran, err := migrate.Up(context.Background(), exec, d, migrations, migrate.UpOptions{AcceptDataLoss: *acceptDataLoss, Limit: *limit})
+	for _, m := range ran {
+		fmt.Printf("Applied %s\n", m.Name)
+	}
+	if err != nil {
+		log.Fatalf("Failed to migrate: %v", err)
+	}
+	if len(ran) == 0 {
+		fmt.Println("No pending migrations")
+	}
+}
+
+func runMigrateDown(args []string) {
+	fs := flag.NewFlagSet("migrate down", flag.ExitOnError)
+	dir := fs.String("dir", "migrations", "migrations directory")
+	url := fs.String("url", "", "database URL (defaults to the schema's database url)")
+	_ = fs.Parse(args)
+
+	// storm migrate down [n] [schema.storm]
+	n, path := 1, defaultSchemaPath
+	rest := fs.Args()
+	if len(rest) > 0 {
+		if v, err := strconv.Atoi(rest[0]); err == nil {
+			if v < 1 {
+				log.Fatalf("Invalid number of migrations: %d", v)
+			}
+			n, rest = v, rest[1:]
+		}
+	}
+	if len(rest) > 0 {
+		path = rest[0]
+	}
+
+	migrations, exec, _, db := openMigrations(*dir, *url, path)
+	defer db.Close()
+
+	reverted, err := migrate.Down(context.Background(), exec, migrations, n)
+	for _, a := range reverted {
+		fmt.Printf("Reverted %s\n", a.Name)
+	}
+	if err != nil {
+		log.Fatalf("Failed to revert: %v", err)
+	}
+	if len(reverted) == 0 {
+		fmt.Println("No applied migrations")
+	}
+}
+
+func runMigrateStatus(args []string) {
+	fs := flag.NewFlagSet("migrate status", flag.ExitOnError)
+	dir := fs.String("dir", "migrations", "migrations directory")
+	url := fs.String("url", "", "database URL (defaults to the schema's database url)")
+	_ = fs.Parse(args)
+
+	migrations, exec, _, db := openMigrations(*dir, *url, schemaPath(fs))
+	defer db.Close()
+
+	statuses, err := migrate.Status(context.Background(), exec, migrations)
+	if err != nil {
+		log.Fatalf("Failed to read migration status: %v", err)
+	}
+	if len(statuses) == 0 {
+		fmt.Println("No migrations")
+		return
+	}
+	for _, s := range statuses {
+		if s.AppliedAt.IsZero() {
+			fmt.Printf("%-9s %s\n", s.State, s.Name)
+		} else {
+			fmt.Printf("%-9s %s (applied %s)\n", s.State, s.Name, s.AppliedAt.UTC().Format(time.DateTime))
+		}
+	}
+}
+
+func runMigrateSquash(args []string) {
+	fs := flag.NewFlagSet("migrate squash", flag.ExitOnError)
+	dir := fs.String("dir", "migrations", "migrations directory")
+	name := fs.String("name", "baseline", "name of the baseline migration")
+	to := fs.String("to", "", "squash the migrations up to and including this one (defaults to all)")
+	_ = fs.Parse(args)
+
+	migrations, err := migrate.Load(*dir)
+	if err != nil {
+		log.Fatalf("Failed to read migrations: %v", err)
+	}
+	m, squashed, err := migrate.Squash(*dir, *name, migrations, *to)
+	if err != nil {
+		log.Fatalf("Failed to squash migrations: %v", err)
+	}
+	fmt.Printf("Squashed %d migrations into %s\n", len(squashed), m.Dir)
+	fmt.Println("Databases that applied them record the baseline on their next storm migrate up without running it.")
+}
+
+func runMigrateVerify(args []string) {
+	fs := flag.NewFlagSet("migrate verify", flag.ExitOnError)
+	dir := fs.String("dir", "migrations", "migrations directory")
+	shadowURL := fs.String("shadow-url", "", "URL of an empty throwaway database to replay the migrations into (defaults to in-memory SQLite, required for other dialects)")
+	_ = fs.Parse(args)
+
+	path := schemaPath(fs)
+	irVar := loadSchema(path)
+	d := dialect.Parse(irVar.DatabaseDriver)
+
+	migrations, err := migrate.Load(*dir)
+	if err != nil {
+		log.Fatalf("Failed to read migrations: %v", err)
+	}
+
+	if *shadowURL == "" {
+		if d != dialect.SQLite {
+			log.Fatalf("A %s schema needs a shadow database: pass -shadow-url", d)
+		}
+		*shadowURL = ":memory:"
+	}
+	shadow, err := database.Open(d, *shadowURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to the shadow database: %v", err)
+	}
+	defer shadow.Close()
+	if d == dialect.SQLite {
+		// Every connection to an in-memory SQLite database opens a new one
+		shadow.SetMaxOpenConns(1)
+	}
+
+	changes, err := migrate.Verify(context.Background(), shadow, d, migrations, irVar)
+	if err != nil {
+		log.Fatalf("Failed to verify migrations: %v", err)
+	}
+	if len(changes) == 0 {
+		fmt.Printf("The %d migrations in %s produce %s\n", len(migrations), *dir, path)
+		return
+	}
+
+	fmt.Printf("The migrations in %s do not produce %s; it still needs to:\n", *dir, path)
+	for _, c := range changes {
+		fmt.Printf("  %s\n", c)
+	}
+	shadow.Close()
+	os.Exit(1)
+}
+
+// openMigrations loads the migrations of dir and connects to the database
+// of the schema, or to url when given
+func openMigrations(dir, url, path string) ([]*migrate.Migration, migrate.Executor, dialect.Dialect, *sql.DB) {
+	irVar := loadSchema(path)
+
+	migrations, err := migrate.Load(dir)
+	if err != nil {
+		log.Fatalf("Failed to read migrations: %v", err)
+	}
+
+	if url == "" {
+		url = irVar.DatabaseURL
+	}
+	d := dialect.Parse(irVar.DatabaseDriver)
+	db, err := database.Open(d, url)
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+	return migrations, migrate.NewExecutor(db, d), d, db
 }
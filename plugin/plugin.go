@@ -0,0 +1,116 @@
+// Package plugin implements the protocol between Storm and external code
+// generators, and is the SDK for writing one in Go.
+//
+// A generator block in the schema names the provider executable:
+//
+//	generator rpc {
+//	  provider = "storm-gen-rpc"
+//	  output   = "gen/rpc"
+//	  service  = "billing"
+//	}
+//
+// For each block, `storm generate` runs the provider, writes a Request as JSON
+// to its stdin and reads a Response as JSON from its stdout. The files of the
+// response are written below the output directory, which defaults to a
+// directory named after the generator block. Anything the plugin prints
+// to stderr is passed through to the user. Plugins in other languages only
+// need to speak this JSON; the schema document is the one printed by
+// `storm print -format json`.
+//
+// A Go plugin is a main package calling Run, and reads the schema through the
+// public schema package:
+//
+//	func main() {
+//		plugin.Run(func(gen *schema.Generator, s *schema.Schema) ([]plugin.File, error) {
+//			...
+//		})
+//	}
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pixperk/storm/schema"
+)
+
+// ProtocolVersion is the version of the Request and Response messages
+const ProtocolVersion = 1
+
+// Request is written by Storm to the plugin's stdin
+type Request struct {
+	ProtocolVersion int `json:"protocolVersion"`
+	// Generator is the generator block that invoked the plugin, in the form
+	// it takes in the generators of the schema document
+	Generator json.RawMessage `json:"generator"`
+	// Schema is the schema document, as written by schema.WriteJSON
+	Schema json.RawMessage `json:"schema"`
+}
+
+// Response is read by Storm from the plugin's stdout
+type Response struct {
+	Files []File `json:"files"`
+	Error string `json:"error,omitempty"` // Set when generation failed
+}
+
+// File is a generated file, named relative to the output directory
+type File struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// Func generates files from the schema
+type Func func(gen *schema.Generator, s *schema.Schema) ([]File, error)
+
+// Run serves a single generation request on stdin/stdout and exits
+func Run(fn Func) {
+	if err := Serve(fn, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// Serve reads a Request from r, calls fn and writes the Response to w.
+// Errors from fn are reported in the Response; the returned error is set
+// only when the protocol itself fails.
+func Serve(fn Func, r io.Reader, w io.Writer) error {
+	var req Request
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return fmt.Errorf("decode request: %w", err)
+	}
+	if req.ProtocolVersion != ProtocolVersion {
+		return fmt.Errorf("unsupported protocol version %d (expected %d)", req.ProtocolVersion, ProtocolVersion)
+	}
+	if len(req.Schema) == 0 {
+		return fmt.Errorf("request has no schema")
+	}
+
+	s, err := schema.ReadJSON(bytes.NewReader(req.Schema))
+	if err != nil {
+		return err
+	}
+	var block struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(req.Generator, &block); err != nil {
+		return fmt.Errorf("decode generator: %w", err)
+	}
+	gen, ok := s.Generator(block.Name)
+	if !ok {
+		return fmt.Errorf("schema has no generator %s", block.Name)
+	}
+
+	resp := Response{Files: []File{}}
+	files, err := fn(&gen, s)
+	if err != nil {
+		resp.Error = err.Error()
+	} else if files != nil {
+		resp.Files = files
+	}
+
+	return json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,161 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/validator"
+)
+
+// Severity classifies a diagnostic
+type Severity int
+
+const (
+	Error   Severity = iota // The schema is invalid
+	Warning                 // The schema is valid but likely not what was meant
+)
+
+// String returns "error" or "warning"
+func (s Severity) String() string {
+	if s == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Position locates a model, field or diagnostic in the schema source.
+// Line and Column are 1-based; both are zero when the location is unknown.
+type Position struct {
+	Filename string
+	Line     int
+	Column   int
+}
+
+// String formats the position as file:line:column
+func (p Position) String() string {
+	switch {
+	case p.Line == 0:
+		return p.Filename
+	case p.Filename == "":
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	default:
+		return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+	}
+}
+
+// Diagnostic is a single error or warning about a schema
+type Diagnostic struct {
+	Severity Severity
+	Message  string
+	Pos      Position // Model or field the diagnostic is about, when known
+}
+
+// String formats the diagnostic as "pos: severity: message"
+func (d Diagnostic) String() string {
+	if pos := d.Pos.String(); pos != "" {
+		return fmt.Sprintf("%s: %s: %s", pos, d.Severity, d.Message)
+	}
+	return fmt.Sprintf("%s: %s", d.Severity, d.Message)
+}
+
+// Diagnostics is the list of problems found while loading a schema
+type Diagnostics []Diagnostic
+
+// HasErrors reports whether any diagnostic is an error
+func (d Diagnostics) HasErrors() bool {
+	for _, diag := range d {
+		if diag.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Errors returns the diagnostics of severity Error
+func (d Diagnostics) Errors() Diagnostics {
+	return d.filter(Error)
+}
+
+// Warnings returns the diagnostics of severity Warning
+func (d Diagnostics) Warnings() Diagnostics {
+	return d.filter(Warning)
+}
+
+func (d Diagnostics) filter(severity Severity) Diagnostics {
+	var out Diagnostics
+	for _, diag := range d {
+		if diag.Severity == severity {
+			out = append(out, diag)
+		}
+	}
+	return out
+}
+
+// Err returns the errors as a single error, or nil when there are none
+func (d Diagnostics) Err() error {
+	errs := d.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(errs))
+	for _, diag := range errs {
+		lines = append(lines, diag.String())
+	}
+	return errors.New(strings.Join(lines, "\n"))
+}
+
+// parseDiagnostic converts a parser error, keeping its position when it has one
+func parseDiagnostic(name string, err error) Diagnostic {
+	var perr participle.Error
+	if errors.As(err, &perr) {
+		pos := perr.Position()
+		return Diagnostic{
+			Severity: Error,
+			Message:  perr.Message(),
+			Pos:      Position{Filename: pos.Filename, Line: pos.Line, Column: pos.Column},
+		}
+	}
+	return Diagnostic{Severity: Error, Message: err.Error(), Pos: Position{Filename: name}}
+}
+
+// errorDiagnostics flattens the nested validation errors into one diagnostic
+// each, located at the model, field or block the validator reported them for
+func (s *Schema) errorDiagnostics(err error) Diagnostics {
+	return flatten("", Position{}, err)
+}
+
+// flatten returns a diagnostic per leaf error of a multierror tree. The
+// validator wraps nested lists as "model X: <list>", so each leaf is prefixed
+// with the wrapping text of the errors above it, and is located at the
+// innermost position of the errors wrapping it.
+func flatten(prefix string, pos Position, err error) Diagnostics {
+	if err == nil {
+		return nil
+	}
+
+	if verr, ok := err.(*validator.Error); ok {
+		pos, err = position(verr.Pos), verr.Err
+	}
+
+	var merr *multierror.Error
+	if errors.As(err, &merr) {
+		if merr != err {
+			// Recover the wrapping text, e.g. "model User: "
+			prefix += strings.TrimSuffix(err.Error(), merr.Error())
+		}
+		var diags Diagnostics
+		for _, inner := range merr.Errors {
+			diags = append(diags, flatten(prefix, pos, inner)...)
+		}
+		return diags
+	}
+	return Diagnostics{{Severity: Error, Message: prefix + err.Error(), Pos: pos}}
+}
+
+func position(p ir.Position) Position {
+	return Position{Filename: p.Filename, Line: p.Line, Column: p.Column}
+}
@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"github.com/pixperk/storm/internal/types/directive"
+)
+
+// Directive is a directive attached to a field, e.g. @index(sort: Desc)
+type Directive struct {
+	Name string // Lower-case directive name without the @, e.g. "belongsto"
+	Args []Arg  // Positional and named arguments in source order
+}
+
+// Arg is a directive or call argument, positional when Name is empty
+type Arg struct {
+	Name  string
+	Value Value
+}
+
+// ValueKind identifies the type of an argument value
+type ValueKind string
+
+const (
+	ValueString ValueKind = "string" // "quoted text"
+	ValueIdent  ValueKind = "ident"  // bare identifier, e.g. Desc
+	ValueInt    ValueKind = "int"    // 42
+	ValueFloat  ValueKind = "float"  // 3.14
+	ValueBool   ValueKind = "bool"   // true, false
+	ValueArray  ValueKind = "array"  // [a, b]
+	ValueCall   ValueKind = "call"   // now(), fn(a, key: b)
+)
+
+// Value is a typed argument value; only the fields matching Kind are set
+type Value struct {
+	Kind  ValueKind
+	Text  string  // String literal contents or identifier name
+	Int   int64   // Set for ValueInt
+	Float float64 // Set for ValueFloat
+	Bool  bool    // Set for ValueBool
+	Items []Value // Elements of a ValueArray
+	Call  *Call   // Set for ValueCall
+}
+
+// Call is a function call used as an argument value
+type Call struct {
+	Name string
+	Args []Arg
+}
+
+// Positional returns the arguments written without a name
+func (d Directive) Positional() []Value {
+	var values []Value
+	for _, arg := range d.Args {
+		if arg.Name == "" {
+			values = append(values, arg.Value)
+		}
+	}
+	return values
+}
+
+// Option returns the value of a named argument
+func (d Directive) Option(key string) (Value, bool) {
+	for _, arg := range d.Args {
+		if arg.Name == key {
+			return arg.Value, true
+		}
+	}
+	return Value{}, false
+}
+
+// String formats the value as it is written in the schema, without quotes
+// around strings
+func (v Value) String() string {
+	return v.internal().String()
+}
+
+func (v Value) internal() directive.Value {
+	value := directive.Value{Text: v.Text, Int: v.Int, Float: v.Float, Bool: v.Bool}
+	_ = value.Kind.UnmarshalText([]byte(v.Kind))
+	for _, item := range v.Items {
+		value.Items = append(value.Items, item.internal())
+	}
+	if v.Call != nil {
+		value.Call = &directive.Call{Name: v.Call.Name}
+		for _, arg := range v.Call.Args {
+			value.Call.Args = append(value.Call.Args, directive.Arg{Name: arg.Name, Value: arg.Value.internal()})
+		}
+	}
+	return value
+}
+
+func newDirective(d directive.Directive) Directive {
+	return Directive{Name: d.Kind.String(), Args: newArgs(d.Args)}
+}
+
+func newArgs(args []directive.Arg) []Arg {
+	if len(args) == 0 {
+		return nil
+	}
+	out := make([]Arg, 0, len(args))
+	for _, arg := range args {
+		out = append(out, Arg{Name: arg.Name, Value: newValue(arg.Value)})
+	}
+	return out
+}
+
+func newValue(v directive.Value) Value {
+	value := Value{Kind: ValueKind(v.Kind.String()), Text: v.Text, Int: v.Int, Float: v.Float, Bool: v.Bool}
+	for _, item := range v.Items {
+		value.Items = append(value.Items, newValue(item))
+	}
+	if v.Call != nil {
+		value.Call = &Call{Name: v.Call.Name, Args: newArgs(v.Call.Args)}
+	}
+	return value
+}
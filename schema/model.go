@@ -0,0 +1,180 @@
+package schema
+
+import (
+	"strings"
+
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/types/directive"
+)
+
+// FieldKind is the scalar type of a field, named as in the schema language
+type FieldKind string
+
+const (
+	KindInt       FieldKind = "Int"
+	KindFloat     FieldKind = "Float"
+	KindDecimal   FieldKind = "Decimal"
+	KindBigInt    FieldKind = "BigInt"
+	KindString    FieldKind = "String"
+	KindText      FieldKind = "Text"
+	KindChar      FieldKind = "Char"
+	KindBoolean   FieldKind = "Boolean"
+	KindDateTime  FieldKind = "DateTime"
+	KindDate      FieldKind = "Date"
+	KindTime      FieldKind = "Time"
+	KindTimestamp FieldKind = "Timestamp"
+	KindBinary    FieldKind = "Binary"
+	KindJSON      FieldKind = "JSON"
+	KindUUID      FieldKind = "UUID"
+	KindCUID      FieldKind = "CUID"
+	KindPoint     FieldKind = "Point"
+	KindCustom    FieldKind = "Custom" // A model reference or an unknown type name; see Field.TypeName
+)
+
+// Model is a model of a schema
+type Model struct {
+	schema *Schema
+	model  *ir.IRModel
+}
+
+// Name returns the model name
+func (m *Model) Name() string {
+	return m.model.Name
+}
+
+// Doc returns the text of the /// comments preceding the model
+func (m *Model) Doc() string {
+	return m.model.Doc
+}
+
+// Position returns where the model is declared
+func (m *Model) Position() Position {
+	return position(m.model.Pos)
+}
+
+// Fields returns the fields of the model in declaration order
+func (m *Model) Fields() []*Field {
+	fields := make([]*Field, 0, len(m.model.Fields))
+	for i := range m.model.Fields {
+		fields = append(fields, &Field{model: m, field: &m.model.Fields[i]})
+	}
+	return fields
+}
+
+// Field looks up a field by name
+func (m *Model) Field(name string) (*Field, bool) {
+	for i := range m.model.Fields {
+		if m.model.Fields[i].Name == name {
+			return &Field{model: m, field: &m.model.Fields[i]}, true
+		}
+	}
+	return nil, false
+}
+
+// PrimaryKey returns the field marked with @id
+func (m *Model) PrimaryKey() (*Field, bool) {
+	pk, ok := m.model.PrimaryKey()
+	if !ok {
+		return nil, false
+	}
+	return m.Field(pk.Name)
+}
+
+// Field is a field of a model
+type Field struct {
+	model *Model
+	field *ir.IRField
+}
+
+// Name returns the field name
+func (f *Field) Name() string {
+	return f.field.Name
+}
+
+// Model returns the model declaring the field
+func (f *Field) Model() *Model {
+	return f.model
+}
+
+// Doc returns the text of the /// comments preceding the field
+func (f *Field) Doc() string {
+	return f.field.Doc
+}
+
+// Position returns where the field is declared
+func (f *Field) Position() Position {
+	return position(f.field.Pos)
+}
+
+// Kind returns the scalar type of the field, KindCustom for model references
+func (f *Field) Kind() FieldKind {
+	return FieldKind(f.field.Type.Kind.String())
+}
+
+// TypeName returns the type as written in the schema without the array
+// suffix: the kind name for scalars and the model name for references
+func (f *Field) TypeName() string {
+	return f.field.Type.String()
+}
+
+// IsArray reports whether the field was declared with []
+func (f *Field) IsArray() bool {
+	return f.field.IsArray
+}
+
+// IsNullable reports whether the field was declared with @nullable
+func (f *Field) IsNullable() bool {
+	return f.field.IsNullable()
+}
+
+// IsRelation reports whether the field references another model of the schema
+func (f *Field) IsRelation() bool {
+	return f.model.schema.ir.IsRelation(*f.field)
+}
+
+// RelatedModel returns the model a relation field references
+func (f *Field) RelatedModel() (*Model, bool) {
+	if !f.IsRelation() {
+		return nil, false
+	}
+	return f.model.schema.Model(f.field.Type.ModelName)
+}
+
+// Column returns the database column of the field, honouring @map. Relation
+// fields have no column and return "".
+func (f *Field) Column() string {
+	if f.IsRelation() {
+		return ""
+	}
+	return f.field.ColumnName()
+}
+
+// Directives returns the directives of the field in declaration order
+func (f *Field) Directives() []Directive {
+	directives := make([]Directive, 0, len(f.field.Type.Directives))
+	for _, d := range f.field.Type.Directives {
+		directives = append(directives, newDirective(d))
+	}
+	return directives
+}
+
+// Directive returns the first directive with the given name, e.g. "index".
+// Names are matched case-insensitively, with or without the @, so "belongsTo"
+// finds @belongsTo.
+func (f *Field) Directive(name string) (Directive, bool) {
+	kind, ok := directive.ParseDirectiveKind(strings.ToLower(strings.TrimPrefix(name, "@")))
+	if !ok {
+		return Directive{}, false
+	}
+	d, ok := f.field.Type.Directive(kind)
+	if !ok {
+		return Directive{}, false
+	}
+	return newDirective(d), true
+}
+
+// HasDirective reports whether the field carries the named directive
+func (f *Field) HasDirective(name string) bool {
+	_, ok := f.Directive(name)
+	return ok
+}
@@ -0,0 +1,74 @@
+package schema
+
+import (
+	"github.com/pixperk/storm/internal/transform/ir"
+)
+
+// RelationKind classifies a resolved relation
+type RelationKind string
+
+const (
+	OneToOne   RelationKind = "one-to-one"
+	OneToMany  RelationKind = "one-to-many"
+	ManyToMany RelationKind = "many-to-many"
+)
+
+// Relation is a relation between two models with its declared fields paired
+type Relation struct {
+	Name       string // Name given with @relation, empty when unnamed
+	Kind       RelationKind
+	From       End         // The parent side: @hasMany, @hasOne, or either side of many-to-many
+	To         End         // The child side, holding the foreign key of to-one relations
+	ForeignKey *ForeignKey // Set for one-to-one and one-to-many relations
+	JoinTable  *JoinTable  // Set for many-to-many relations
+}
+
+// End is one side of a relation; Field is empty when that side declares no field
+type End struct {
+	Model string
+	Field string
+}
+
+// ForeignKey is the column implementing a to-one relation
+type ForeignKey struct {
+	Model      string // Model whose table holds the key
+	Column     string // Key column
+	References string // Referenced model
+	Referenced string // Referenced column
+}
+
+// JoinTable links the two sides of a many-to-many relation
+type JoinTable struct {
+	Name    string
+	Through bool // Declared as a model with @relation(through: Model)
+	Source  JoinColumn
+	Target  JoinColumn
+}
+
+// JoinColumn is one side of a join table
+type JoinColumn struct {
+	Name       string // Column in the join table
+	Table      string // Referenced table
+	References string // Referenced primary key column
+}
+
+func newRelation(r ir.Relation) Relation {
+	rel := Relation{
+		Name: r.Name,
+		Kind: RelationKind(r.Kind.String()),
+		From: End{Model: r.From.Model, Field: r.From.Field},
+		To:   End{Model: r.To.Model, Field: r.To.Field},
+	}
+	if fk := r.ForeignKey; fk != nil {
+		rel.ForeignKey = &ForeignKey{Model: fk.Model, Column: fk.Column, References: fk.References, Referenced: fk.Referenced}
+	}
+	if jt := r.JoinTable; jt != nil {
+		rel.JoinTable = &JoinTable{
+			Name:    jt.Name,
+			Through: jt.Through,
+			Source:  JoinColumn{Name: jt.Source.Name, Table: jt.Source.Table, References: jt.Source.References},
+			Target:  JoinColumn{Name: jt.Target.Name, Table: jt.Target.Table, References: jt.Target.References},
+		}
+	}
+	return rel
+}
@@ -0,0 +1,177 @@
+// Package schema is the public API for reading Storm schemas.
+//
+// It parses .storm files, validates them and exposes the result through
+// read-only accessors:
+//
+//	s, diags := schema.Load("schema.storm")
+//	if diags.HasErrors() {
+//		log.Fatal(diags.Err())
+//	}
+//	for _, m := range s.Models() {
+//		for _, f := range m.Fields() {
+//			fmt.Println(m.Name(), f.Name(), f.TypeName(), f.Column())
+//		}
+//	}
+//
+// # Compatibility
+//
+// This package follows semantic versioning together with the storm module.
+// Within a major version, exported identifiers are not removed or renamed,
+// the signatures of exported functions and methods do not change, and the
+// meaning of existing fields and constants stays the same. New functions,
+// methods, struct fields, kinds and constants may be added in minor releases,
+// so code switching over FieldKind, ValueKind or RelationKind should have a
+// default case. Diagnostic messages are meant for humans and may change in any
+// release; match on Severity and Pos rather than on Message text.
+//
+// The packages below internal/ carry no such promise and cannot be imported
+// from other modules.
+package schema
+
+import (
+	"io"
+	"os"
+
+	"github.com/pixperk/storm/internal/parser"
+	"github.com/pixperk/storm/internal/transform/ir"
+	"github.com/pixperk/storm/internal/validator"
+)
+
+// Schema is a parsed Storm schema. It is immutable; accessors return copies.
+type Schema struct {
+	ir *ir.IR
+}
+
+// Database is the database block of a schema
+type Database struct {
+	Driver string
+	URL    string
+}
+
+// Generator is a generator block of a schema, naming an external code generator
+type Generator struct {
+	Name     string
+	Provider string   // Executable run by `storm generate`
+	Output   string   // Output directory, empty for the default
+	Config   []Arg    // The remaining settings of the block, by name
+	Pos      Position // Where the block is declared
+}
+
+// Load parses and validates the schema file at path. The returned schema is
+// nil only when the file cannot be read or parsed; on validation errors it is
+// returned together with the diagnostics describing them.
+func Load(path string) (*Schema, Diagnostics) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, Diagnostics{{Severity: Error, Message: err.Error(), Pos: Position{Filename: path}}}
+	}
+	defer f.Close()
+
+	s, diags := ParseReader(path, f)
+	if s == nil {
+		return nil, diags
+	}
+	return s, append(diags, Validate(s)...)
+}
+
+// ParseReader parses a schema from r without validating it. Name is used as
+// the filename of positions and diagnostics.
+func ParseReader(name string, r io.Reader) (*Schema, Diagnostics) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, Diagnostics{{Severity: Error, Message: err.Error(), Pos: Position{Filename: name}}}
+	}
+	ast, err := parser.Parse(name, string(src))
+	if err != nil {
+		return nil, Diagnostics{parseDiagnostic(name, err)}
+	}
+
+	irData, err := ir.ToIR(ast)
+	if err != nil {
+		return nil, Diagnostics{{Severity: Error, Message: err.Error(), Pos: Position{Filename: name}}}
+	}
+	return &Schema{ir: irData}, nil
+}
+
+// Validate checks a parsed schema and reports its errors and warnings
+func Validate(s *Schema) Diagnostics {
+	warns, err := validator.Validate(s.ir)
+
+	var diags Diagnostics
+	diags = append(diags, s.errorDiagnostics(err)...)
+	for _, w := range warns {
+		diags = append(diags, Diagnostic{Severity: Warning, Message: w.Message, Pos: position(w.Pos)})
+	}
+	return diags
+}
+
+// Database returns the database configuration of the schema
+func (s *Schema) Database() Database {
+	return Database{Driver: s.ir.DatabaseDriver, URL: s.ir.DatabaseURL}
+}
+
+// Models returns the models of the schema in declaration order
+func (s *Schema) Models() []*Model {
+	models := make([]*Model, 0, len(s.ir.Models))
+	for i := range s.ir.Models {
+		models = append(models, &Model{schema: s, model: &s.ir.Models[i]})
+	}
+	return models
+}
+
+// Model looks up a model by name
+func (s *Schema) Model(name string) (*Model, bool) {
+	m, ok := s.ir.FindModel(name)
+	if !ok {
+		return nil, false
+	}
+	return &Model{schema: s, model: m}, true
+}
+
+// Relations returns every relation of the schema, once per pair of fields
+func (s *Schema) Relations() []Relation {
+	resolved := s.ir.Relations()
+	relations := make([]Relation, 0, len(resolved))
+	for _, r := range resolved {
+		relations = append(relations, newRelation(r))
+	}
+	return relations
+}
+
+// WriteJSON writes the versioned JSON form of the schema, as printed by
+// `storm print -format json`
+func (s *Schema) WriteJSON(w io.Writer) error {
+	return ir.EncodeJSON(w, s.ir)
+}
+
+// ReadJSON reads a schema written by WriteJSON. The schema is not validated.
+func ReadJSON(r io.Reader) (*Schema, error) {
+	irData, err := ir.DecodeJSON(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{ir: irData}, nil
+}
+
+// Generators returns the generator blocks of the schema in declaration order
+func (s *Schema) Generators() []Generator {
+	gens := make([]Generator, 0, len(s.ir.Generators))
+	for _, g := range s.ir.Generators {
+		gens = append(gens, newGenerator(g))
+	}
+	return gens
+}
+
+// Generator looks up a generator block by name
+func (s *Schema) Generator(name string) (Generator, bool) {
+	for _, g := range s.ir.Generators {
+		if g.Name == name {
+			return newGenerator(g), true
+		}
+	}
+	return Generator{}, false
+}
+
+func newGenerator(g ir.IRGenerator) Generator {
+	return Generator{Name: g.Name, Provider: g.Provider, Output: g.Output, Config: newArgs(g.Config), Pos: position(g.Pos)}
+}